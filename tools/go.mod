@@ -5,6 +5,7 @@ go 1.25.0
 require (
 	gioui.org v0.8.0
 	gioui.org/x v0.8.1
+	github.com/pkg/sftp v1.13.9
 	golang.org/x/crypto v0.41.0
 	golang.org/x/exp/shiny v0.0.0-20240707233637-46b078467d37
 )
@@ -12,6 +13,7 @@ require (
 require (
 	gioui.org/shader v1.0.8 // indirect
 	github.com/go-text/typesetting v0.2.1 // indirect
+	github.com/kr/fs v0.1.0 // indirect
 	golang.org/x/exp v0.0.0-20240707233637-46b078467d37 // indirect
 	golang.org/x/image v0.18.0 // indirect
 	golang.org/x/sys v0.35.0 // indirect
@@ -0,0 +1,78 @@
+package history
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"tools/jsonstore"
+)
+
+// MaxCommands is the number of commands kept for each host.
+const MaxCommands = 500
+
+// History is the list of commands run on each host, backed by a JSON file.
+// It is safe for concurrent use.
+type History struct {
+	store *jsonstore.File
+	mu    sync.Mutex
+	hosts map[string][]string
+}
+
+type file struct {
+	Hosts map[string][]string `json:"hosts"`
+}
+
+// DefaultPath returns history.json in the user config directory.
+func DefaultPath() string {
+	return jsonstore.DefaultPath("history.json")
+}
+
+// Load reads the history at path, a missing file is an empty history.
+// A file that cannot be parsed is moved to path.bak, saving is refused
+// when it cannot be moved or read.
+func Load(path string) (*History, error) {
+	store, f, err := jsonstore.Open[file](path, "command history")
+	h := &History{store: store, hosts: make(map[string][]string)}
+	if f.Hosts != nil {
+		h.hosts = f.Hosts
+	}
+	return h, err
+}
+
+// Commands returns the commands run on host, oldest first.
+func (h *History) Commands(host string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.hosts[host])
+}
+
+// Add appends cmd to the history of host and saves it. A command already
+// in the history is moved to the end instead of repeated.
+func (h *History) Add(host, cmd string) error {
+	cmd = strings.TrimSpace(cmd)
+	if len(cmd) == 0 {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cmds := slices.DeleteFunc(slices.Clone(h.hosts[host]), func(c string) bool { return c == cmd })
+	cmds = append(cmds, cmd)
+	if len(cmds) > MaxCommands {
+		cmds = cmds[len(cmds)-MaxCommands:]
+	}
+	hosts := make(map[string][]string, len(h.hosts)+1)
+	for k, v := range h.hosts {
+		hosts[k] = v
+	}
+	hosts[host] = cmds
+	if err := h.save(hosts); err != nil {
+		return err
+	}
+	h.hosts = hosts
+	return nil
+}
+
+// save writes hosts to the history file.
+func (h *History) save(hosts map[string][]string) error {
+	return h.store.Save(file{Hosts: hosts})
+}
@@ -29,3 +29,48 @@ var RemoteIcon *widget.Icon = func() *widget.Icon {
 	icon, _ := widget.NewIcon(icons.ActionSettingsRemote)
 	return icon
 }()
+
+var HostsIcon *widget.Icon = func() *widget.Icon {
+	icon, _ := widget.NewIcon(icons.ActionDNS)
+	return icon
+}()
+
+var VaultIcon *widget.Icon = func() *widget.Icon {
+	icon, _ := widget.NewIcon(icons.ActionLock)
+	return icon
+}()
+
+var ConnectionsIcon *widget.Icon = func() *widget.Icon {
+	icon, _ := widget.NewIcon(icons.ActionSettingsEthernet)
+	return icon
+}()
+
+var TerminalIcon *widget.Icon = func() *widget.Icon {
+	icon, _ := widget.NewIcon(icons.HardwareComputer)
+	return icon
+}()
+
+var MultiRunIcon *widget.Icon = func() *widget.Icon {
+	icon, _ := widget.NewIcon(icons.AVPlaylistPlay)
+	return icon
+}()
+
+var FilesIcon *widget.Icon = func() *widget.Icon {
+	icon, _ := widget.NewIcon(icons.FileFolder)
+	return icon
+}()
+
+var TunnelsIcon *widget.Icon = func() *widget.Icon {
+	icon, _ := widget.NewIcon(icons.ActionSwapHoriz)
+	return icon
+}()
+
+var PartitionsIcon *widget.Icon = func() *widget.Icon {
+	icon, _ := widget.NewIcon(icons.DeviceStorage)
+	return icon
+}()
+
+var LvmIcon *widget.Icon = func() *widget.Icon {
+	icon, _ := widget.NewIcon(icons.ActionViewModule)
+	return icon
+}()
@@ -0,0 +1,190 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"tools/jsonstore"
+	"tools/sshclient"
+)
+
+// Host is a saved connection target. Passwords are never stored here.
+type Host struct {
+	Name    string               `json:"name"`
+	Address string               `json:"address"`
+	Port    int                  `json:"port,omitempty"`
+	User    string               `json:"user,omitempty"`
+	Auth    sshclient.AuthMethod `json:"auth,omitempty"`
+	KeyFile string               `json:"key_file,omitempty"`
+	// ProxyJump lists the jump hosts in OpenSSH syntax, entries may also
+	// name other saved hosts so each hop keeps its own auth method.
+	ProxyJump string   `json:"proxy_jump,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+}
+
+// Addr returns the address in "host:port" form, as typed into the
+// connection form.
+func (h Host) Addr() string {
+	if h.Port == 0 {
+		return h.Address
+	}
+	return net.JoinHostPort(strings.Trim(h.Address, "[]"), strconv.Itoa(h.Port))
+}
+
+// Match reports whether the host name, address, tags or groups contain
+// keyword, case insensitively. An empty keyword matches every host.
+func (h Host) Match(keyword string) bool {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	if len(keyword) == 0 {
+		return true
+	}
+	fields := append([]string{h.Name, h.Address, h.User}, h.Tags...)
+	fields = append(fields, h.Groups...)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h Host) validate() error {
+	switch {
+	case len(strings.TrimSpace(h.Name)) == 0:
+		return errors.New("host name is required")
+	case len(strings.TrimSpace(h.Address)) == 0:
+		return errors.New("host address is required")
+	case h.Port < 0 || h.Port > 65535:
+		return fmt.Errorf("invalid port %d", h.Port)
+	}
+	return nil
+}
+
+// Inventory is the list of saved hosts backed by a JSON file, it is safe
+// for concurrent use.
+type Inventory struct {
+	store *jsonstore.File
+	mu    sync.Mutex
+	hosts []Host
+}
+
+type file struct {
+	Hosts []Host `json:"hosts"`
+}
+
+// DefaultPath returns hosts.json in the user config directory.
+func DefaultPath() string {
+	return jsonstore.DefaultPath("hosts.json")
+}
+
+// Load reads the inventory at path, a missing file is an empty inventory.
+// A file that cannot be parsed is moved to path.bak, saving is refused
+// when it cannot be moved or read.
+func Load(path string) (*Inventory, error) {
+	store, f, err := jsonstore.Open[file](path, "host inventory")
+	return &Inventory{store: store, hosts: f.Hosts}, err
+}
+
+// Hosts returns a copy of all hosts sorted by name.
+func (inv *Inventory) Hosts() []Host {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	hosts := slices.Clone(inv.hosts)
+	slices.SortFunc(hosts, func(a, b Host) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return hosts
+}
+
+// Groups returns every group used by at least one host.
+func (inv *Inventory) Groups() []string {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	groups := make([]string, 0)
+	for _, h := range inv.hosts {
+		for _, g := range h.Groups {
+			if !slices.Contains(groups, g) {
+				groups = append(groups, g)
+			}
+		}
+	}
+	slices.Sort(groups)
+	return groups
+}
+
+func (inv *Inventory) Get(name string) (Host, bool) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	i := inv.index(name)
+	if i < 0 {
+		return Host{}, false
+	}
+	return inv.hosts[i], true
+}
+
+func (inv *Inventory) index(name string) int {
+	return slices.IndexFunc(inv.hosts, func(h Host) bool { return h.Name == name })
+}
+
+// Put adds h, or replaces the host named oldName when it is not empty, and
+// saves the inventory.
+func (inv *Inventory) Put(oldName string, h Host) error {
+	if err := h.validate(); err != nil {
+		return err
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if inv.index(h.Name) >= 0 && h.Name != oldName {
+		return fmt.Errorf("host %q already exists", h.Name)
+	}
+	hosts := slices.Clone(inv.hosts)
+	if i := inv.index(oldName); len(oldName) != 0 && i >= 0 {
+		hosts[i] = h
+	} else {
+		hosts = append(hosts, h)
+	}
+	if err := inv.save(hosts); err != nil {
+		return err
+	}
+	inv.hosts = hosts
+	return nil
+}
+
+// Delete removes the host named name and saves the inventory.
+func (inv *Inventory) Delete(name string) error {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	i := inv.index(name)
+	if i < 0 {
+		return fmt.Errorf("host %q does not exist", name)
+	}
+	hosts := slices.Delete(slices.Clone(inv.hosts), i, i+1)
+	if err := inv.save(hosts); err != nil {
+		return err
+	}
+	inv.hosts = hosts
+	return nil
+}
+
+// save writes hosts to the inventory file.
+func (inv *Inventory) save(hosts []Host) error {
+	return inv.store.Save(file{Hosts: hosts})
+}
+
+// SplitList splits a comma separated list typed by the user, dropping
+// blanks and duplicates.
+func SplitList(s string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) != 0 && !slices.Contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
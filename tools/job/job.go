@@ -0,0 +1,116 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of a finished job.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Work is the function run in the background. report can be called at any
+// time to update the progress text shown on the page.
+type Work[T any] func(ctx context.Context, report func(string)) (T, error)
+
+// Runner runs at most one job at a time off the UI goroutine. The page
+// polls it from Layout, the invalidate callback wakes the window up when
+// the job reports progress or finishes.
+type Runner[T any] struct {
+	invalidate func()
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	status  string
+	started time.Time
+	result  *Result[T]
+}
+
+// NewRunner constructs a Runner, invalidate is normally app.Window.Invalidate.
+func NewRunner[T any](invalidate func()) *Runner[T] {
+	return &Runner[T]{invalidate: invalidate}
+}
+
+// Start runs work in a new goroutine. It returns false if a job is already
+// running.
+func (r *Runner[T]) Start(work Work[T]) bool {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.running = true
+	r.cancel = cancel
+	r.status = ""
+	r.started = time.Now()
+	r.result = nil
+	r.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		value, err := work(ctx, r.report)
+		if err != nil && ctx.Err() != nil {
+			err = context.Canceled
+		}
+		r.mu.Lock()
+		r.running = false
+		r.cancel = nil
+		r.result = &Result[T]{Value: value, Err: err}
+		r.mu.Unlock()
+		r.wakeup()
+	}()
+	return true
+}
+
+func (r *Runner[T]) report(status string) {
+	r.mu.Lock()
+	r.status = status
+	r.mu.Unlock()
+	r.wakeup()
+}
+
+func (r *Runner[T]) wakeup() {
+	if r.invalidate != nil {
+		r.invalidate()
+	}
+}
+
+// Cancel cancels the running job, if any.
+func (r *Runner[T]) Cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// Running reports whether a job is in progress.
+func (r *Runner[T]) Running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+// Status returns the last progress text and how long the job has been running.
+func (r *Runner[T]) Status() (string, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status, time.Since(r.started)
+}
+
+// Poll returns the result of the last finished job exactly once.
+func (r *Runner[T]) Poll() (Result[T], bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.result == nil {
+		return Result[T]{}, false
+	}
+	res := *r.result
+	r.result = nil
+	return res, true
+}
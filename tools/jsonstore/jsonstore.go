@@ -0,0 +1,80 @@
+// Package jsonstore keeps the data of a store in a JSON file in the user
+// config directory.
+package jsonstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultPath returns name in the config directory of the tools.
+func DefaultPath(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "my-ui-tools", name)
+}
+
+// File is the JSON file of a store. It refuses to save over a file that
+// exists but could not be read, so its data is never lost.
+type File struct {
+	path string
+	// what 是错误信息里的数据名称，例如 host inventory
+	what string
+	// broken 不为空时文件存在但无法读取，保存会覆盖其中的数据，因此拒绝保存
+	broken error
+}
+
+// Open reads the file at path, what names its data in errors. A missing
+// file yields the zero T. A file that cannot be parsed is moved to
+// path.bak and also yields the zero T with an error, saving is refused when
+// it cannot be moved or read.
+func Open[T any](path, what string) (*File, T, error) {
+	f := &File{path: path, what: what}
+	var v T
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, v, nil
+	}
+	if err != nil {
+		f.broken = fmt.Errorf("read %s failed, %v", what, err)
+		return f, v, f.broken
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		var zero T
+		if renameErr := os.Rename(path, path+".bak"); renameErr != nil {
+			f.broken = fmt.Errorf("unable to unmarshal %s %s, error: %v", what, path, err)
+			return f, zero, f.broken
+		}
+		return f, zero, fmt.Errorf("unable to unmarshal %s %s, moved it to %s.bak, error: %v", what, path, path, err)
+	}
+	return f, v, nil
+}
+
+// Save writes v to a temporary file and renames it over the file so a
+// crash never leaves a truncated file behind.
+func (f *File) Save(v any) error {
+	if f.broken != nil {
+		return fmt.Errorf("save %s failed, %v", f.what, f.broken)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("save %s failed, %v", f.what, err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("save %s failed, %v", f.what, err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("save %s failed, %v", f.what, err)
+	}
+	return nil
+}
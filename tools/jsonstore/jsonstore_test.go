@@ -0,0 +1,68 @@
+package jsonstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type data struct {
+	Names []string `json:"names"`
+}
+
+func TestOpenMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "store.json")
+	f, v, err := Open[data](path, "names")
+	if err != nil || v.Names != nil {
+		t.Fatalf("Open of a missing file = %v, %v, want the zero value", v, err)
+	}
+	if err := f.Save(data{Names: []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	_, v, err = Open[data](path, "names")
+	if err != nil || len(v.Names) != 2 || v.Names[1] != "b" {
+		t.Errorf("Open after Save = %v, %v, want [a b]", v, err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind")
+	}
+}
+
+func TestOpenCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	corrupt := `{"names": ["a",`
+	if err := os.WriteFile(path, []byte(corrupt), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, v, err := Open[data](path, "names")
+	if err == nil {
+		t.Fatal("Open of a corrupt file succeeded")
+	}
+	// 部分解析的数据不能返回
+	if v.Names != nil {
+		t.Errorf("Open of a corrupt file returned %v", v)
+	}
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil || string(backup) != corrupt {
+		t.Fatalf("corrupt file was not moved to .bak: %q, %v", backup, err)
+	}
+	// 移走之后可以正常保存
+	if err := f.Save(data{Names: []string{"c"}}); err != nil {
+		t.Errorf("Save after moving the corrupt file aside = %v", err)
+	}
+}
+
+func TestOpenUnreadable(t *testing.T) {
+	// 目录可以 stat 但不能读取
+	path := t.TempDir()
+	f, _, err := Open[data](path, "names")
+	if err == nil {
+		t.Fatal("Open of an unreadable file succeeded")
+	}
+	if err := f.Save(data{}); err == nil {
+		t.Error("Save over an unreadable file succeeded")
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		t.Errorf("unreadable file was changed: %v, %v", info, err)
+	}
+}
@@ -4,11 +4,27 @@ import (
 	"log"
 	"os"
 
+	"tools/history"
+	"tools/inventory"
 	page "tools/pages"
+	"tools/pages/connections"
+	"tools/pages/credentials"
 	disktable "tools/pages/disk_table"
+	"tools/pages/files"
 	"tools/pages/home"
+	"tools/pages/hosts"
 	listdisks "tools/pages/list_disks.go"
+	"tools/pages/lvm"
+	multirun "tools/pages/multi_run"
+	"tools/pages/partitions"
 	remotessh "tools/pages/remote_ssh"
+	"tools/pages/terminal"
+	"tools/pages/tunnels"
+	"tools/snippet"
+	"tools/sshclient"
+	"tools/sshconfig"
+	"tools/tunnel"
+	"tools/vault"
 
 	"gioui.org/app"
 	"gioui.org/font/gofont"
@@ -34,11 +50,48 @@ func loop(win *app.Window) error {
 	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
 	var ops op.Ops
 
-	router := page.NewRouter()
+	router := page.NewRouter(win)
+	inv, err := inventory.Load(inventory.DefaultPath())
+	if err != nil {
+		log.Printf("load host inventory failed, %v", err)
+	}
+	router.Inventory = inv
+	sshConfig, err := sshconfig.Load(sshconfig.DefaultPath())
+	if err != nil {
+		log.Printf("load ssh config failed, %v", err)
+	}
+	router.SSHConfig = sshConfig
+	cmdHistory, err := history.Load(history.DefaultPath())
+	if err != nil {
+		log.Printf("load command history failed, %v", err)
+	}
+	router.History = cmdHistory
+	snippets, err := snippet.Load(snippet.DefaultPath())
+	if err != nil {
+		log.Printf("load snippets failed, %v", err)
+	}
+	router.Snippets = snippets
+	tunnelStore, err := tunnel.Load(tunnel.DefaultPath())
+	if err != nil {
+		log.Printf("load tunnels failed, %v", err)
+	}
+	router.Tunnels = tunnelStore
+	router.Vault = vault.New(vault.DefaultPath(), vault.DefaultLockTimeout, router.Invalidate)
+	router.Pool = sshclient.NewPool(sshclient.DefaultIdleTimeout)
+	defer router.Pool.Close()
 	router.Register("home", home.New(&router))
 	router.Register("remote", remotessh.New(&router))
 	router.Register("disks", listdisks.New(&router))
 	router.Register("table", disktable.New(&router))
+	router.Register("hosts", hosts.New(&router))
+	router.Register("vault", credentials.New(&router))
+	router.Register("connections", connections.New(&router))
+	router.Register("terminal", terminal.New(&router))
+	router.Register("multirun", multirun.New(&router))
+	router.Register("files", files.New(&router))
+	router.Register("tunnels", tunnels.New(&router))
+	router.Register("partitions", partitions.New(&router))
+	router.Register("lvm", lvm.New(&router))
 
 	for {
 		switch e := win.Event().(type) {
@@ -0,0 +1,339 @@
+package ansiview
+
+import (
+	"image"
+	"image/color"
+	"regexp"
+	"strings"
+	"tools/vt"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/styledtext"
+)
+
+var (
+	matchColor   = color.NRGBA{R: 255, G: 200, B: 0, A: 100}
+	currentColor = color.NRGBA{R: 255, G: 120, B: 0, A: 150}
+)
+
+// match is a find result, start and end are byte offsets in the plain
+// text of the line.
+type match struct {
+	line       int
+	start, end int
+}
+
+// View shows program output with its ANSI colours, bold, italic and
+// underline in a scrollable list of lines. Each line is laid out as styled
+// text spans; styledtext is used instead of richtext since its span
+// callback can draw the underlines and find highlights. Backgrounds are
+// not drawn.
+type View struct {
+	// Limit is the number of bytes kept, older lines are dropped. Zero
+	// keeps everything.
+	Limit int
+	lines [][]vt.Span
+	// texts 是每行去掉转义序列后的文本
+	texts  []string
+	size   int
+	open   bool
+	styler vt.Styler
+	list   widget.List
+	// follow 为 true 时列表停在末尾，新输出会滚动显示
+	follow bool
+
+	filter *regexp.Regexp
+	find   *regexp.Regexp
+	// shown 是通过过滤的行号，matches 是这些行中的查找结果，
+	// 输出或条件变化后标记 dirty 并在使用前重新计算
+	dirty     bool
+	shown     []int
+	matches   []match
+	lineMatch map[int][]int
+	// next 是当前查找结果的序号加一，0 表示还没有定位到结果
+	next int
+}
+
+// SetFollow sets whether appended output scrolls the view to the end.
+// Turning it on scrolls to the end right away.
+func (v *View) SetFollow(follow bool) {
+	if follow && !v.follow {
+		v.list.Position.BeforeEnd = false
+	}
+	v.follow = follow
+	v.list.ScrollToEnd = follow
+}
+
+// Append adds output, scrolling to the end while following. Text after the
+// last newline is continued by the next call.
+func (v *View) Append(text string) {
+	if len(text) == 0 {
+		return
+	}
+	for _, span := range v.styler.Spans(text) {
+		for {
+			part, rest, newline := strings.Cut(span.Text, "\n")
+			if !v.open {
+				v.lines = append(v.lines, nil)
+				v.texts = append(v.texts, "")
+				v.open = true
+			}
+			if len(part) != 0 {
+				last := len(v.lines) - 1
+				v.lines[last] = append(v.lines[last], vt.Span{Text: part, Style: span.Style})
+				v.texts[last] += part
+				v.size += len(part)
+			}
+			if !newline {
+				break
+			}
+			v.open = false
+			v.size++
+			if len(rest) == 0 {
+				break
+			}
+			span.Text = rest
+		}
+	}
+	v.trim()
+	v.dirty = true
+	if v.follow {
+		v.list.Position.BeforeEnd = false
+	}
+}
+
+// trim drops the oldest lines until the output fits in Limit.
+func (v *View) trim() {
+	if v.Limit <= 0 || v.size <= v.Limit {
+		return
+	}
+	drop := 0
+	for drop < len(v.lines)-1 && v.size > v.Limit*3/4 {
+		v.size -= len(v.texts[drop]) + 1
+		drop++
+	}
+	v.lines = append([][]vt.Span(nil), v.lines[drop:]...)
+	v.texts = append([]string(nil), v.texts[drop:]...)
+}
+
+// Reset clears the output and the current style, the filter and find
+// pattern are kept.
+func (v *View) Reset() {
+	v.lines, v.texts, v.size, v.open = nil, nil, 0, false
+	v.styler = vt.Styler{}
+	v.list.Position = layout.Position{}
+	v.next = 0
+	v.dirty = true
+}
+
+// Len returns the number of lines.
+func (v *View) Len() int {
+	return len(v.lines)
+}
+
+// SetFilter shows only the lines matching re, nil shows every line.
+func (v *View) SetFilter(re *regexp.Regexp) {
+	v.filter = re
+	v.next = 0
+	v.dirty = true
+}
+
+// SetFind highlights the matches of re in the shown lines, nil clears the
+// highlights.
+func (v *View) SetFind(re *regexp.Regexp) {
+	v.find = re
+	v.next = 0
+	v.dirty = true
+}
+
+// update recomputes the shown lines and the matches after a change.
+func (v *View) update() {
+	if !v.dirty {
+		return
+	}
+	v.dirty = false
+	v.shown = v.shown[:0]
+	for i, text := range v.texts {
+		if v.filter == nil || v.filter.MatchString(text) {
+			v.shown = append(v.shown, i)
+		}
+	}
+	v.matches = v.matches[:0]
+	v.lineMatch = make(map[int][]int)
+	if v.find == nil {
+		return
+	}
+	for _, i := range v.shown {
+		for _, loc := range v.find.FindAllStringIndex(v.texts[i], -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			v.lineMatch[i] = append(v.lineMatch[i], len(v.matches))
+			v.matches = append(v.matches, match{line: i, start: loc[0], end: loc[1]})
+		}
+	}
+	// 新的输出不改变当前查找位置
+	if v.next > len(v.matches) {
+		v.next = 0
+	}
+}
+
+// Matches returns the index of the current match, -1 before moving to one,
+// and the number of matches.
+func (v *View) Matches() (current, total int) {
+	v.update()
+	return v.next - 1, len(v.matches)
+}
+
+// NextMatch moves to the next or previous match, wrapping around, and
+// scrolls its line into view.
+func (v *View) NextMatch(forward bool) {
+	v.update()
+	if len(v.matches) == 0 {
+		return
+	}
+	current := v.next - 1
+	switch {
+	case current < 0 && forward:
+		current = 0
+	case current < 0:
+		current = len(v.matches) - 1
+	case forward:
+		current = (current + 1) % len(v.matches)
+	default:
+		current = (current + len(v.matches) - 1) % len(v.matches)
+	}
+	v.next = current + 1
+	line := v.matches[current].line
+	for pos, i := range v.shown {
+		if i == line {
+			// 留出几行上下文
+			v.list.Position = layout.Position{First: max(pos-3, 0), BeforeEnd: true}
+			break
+		}
+	}
+}
+
+// Text returns the shown lines without escape sequences.
+func (v *View) Text() string {
+	v.update()
+	var b strings.Builder
+	for _, i := range v.shown {
+		b.WriteString(v.texts[i])
+		if i < len(v.texts)-1 || !v.open {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func (v *View) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	v.update()
+	v.list.Axis = layout.Vertical
+	return material.List(th, &v.list).Layout(gtx, len(v.shown), func(gtx layout.Context, pos int) layout.Dimensions {
+		i := v.shown[pos]
+		return v.layoutLine(gtx, th, v.split(i))
+	})
+}
+
+// segment is part of a span, mark is nil or the find highlight colour.
+type segment struct {
+	span vt.Span
+	mark *color.NRGBA
+}
+
+// split cuts the spans of line i at the boundaries of its matches.
+func (v *View) split(i int) []segment {
+	line := v.lines[i]
+	segments := make([]segment, 0, len(line))
+	ids := v.lineMatch[i]
+	if len(ids) == 0 {
+		for _, span := range line {
+			segments = append(segments, segment{span: span})
+		}
+		return segments
+	}
+	offset := 0
+	for _, span := range line {
+		spanStart, spanEnd := offset, offset+len(span.Text)
+		offset = spanEnd
+		pos := spanStart
+		for _, id := range ids {
+			m := v.matches[id]
+			start, end := max(m.start, pos), min(m.end, spanEnd)
+			if start >= end {
+				continue
+			}
+			if start > pos {
+				segments = append(segments, segment{span: vt.Span{Text: span.Text[pos-spanStart : start-spanStart], Style: span.Style}})
+			}
+			mark := &matchColor
+			if id == v.next-1 {
+				mark = &currentColor
+			}
+			segments = append(segments, segment{span: vt.Span{Text: span.Text[start-spanStart : end-spanStart], Style: span.Style}, mark: mark})
+			pos = end
+		}
+		if pos < spanEnd {
+			segments = append(segments, segment{span: vt.Span{Text: span.Text[pos-spanStart:], Style: span.Style}})
+		}
+	}
+	return segments
+}
+
+func (v *View) layoutLine(gtx layout.Context, th *material.Theme, line []segment) layout.Dimensions {
+	if len(line) == 0 {
+		// 空行也占一行高度
+		line = []segment{{span: vt.Span{Text: " "}}}
+	}
+	styles := make([]styledtext.SpanStyle, len(line))
+	for i, seg := range line {
+		c := seg.span.Style
+		// 不绘制背景色，反色时保持前景色
+		c.Attr &^= vt.Reverse
+		fg, _ := c.Colors(th.Fg, th.Bg)
+		fnt := font.Font{Typeface: th.Face}
+		if c.Attr&vt.Bold != 0 {
+			fnt.Weight = font.Bold
+		}
+		if c.Attr&vt.Italic != 0 {
+			fnt.Style = font.Italic
+		}
+		styles[i] = styledtext.SpanStyle{
+			Font:    fnt,
+			Size:    th.TextSize,
+			Color:   fg,
+			Content: seg.span.Text,
+		}
+	}
+	txt := styledtext.Text(th.Shaper, styles...)
+	txt.WrapPolicy = styledtext.WrapGraphemes
+	return txt.Layout(gtx, func(gtx layout.Context, i int, dims layout.Dimensions) {
+		seg := line[i]
+		// 查找结果用半透明颜色覆盖
+		if seg.mark != nil {
+			paint.FillShape(gtx.Ops, *seg.mark, clip.Rect{Max: dims.Size}.Op())
+		}
+		attr := seg.span.Style.Attr
+		if attr&(vt.Underline|vt.Strike) == 0 {
+			return
+		}
+		// 下划线画在基线下方，删除线在字符中部
+		thick := max(1, gtx.Dp(1))
+		baseline := dims.Size.Y - dims.Baseline
+		if attr&vt.Underline != 0 {
+			rect := image.Rect(0, baseline+thick, dims.Size.X, baseline+2*thick)
+			paint.FillShape(gtx.Ops, styles[i].Color, clip.Rect(rect).Op())
+		}
+		if attr&vt.Strike != 0 {
+			mid := baseline * 2 / 3
+			rect := image.Rect(0, mid, dims.Size.X, mid+thick)
+			paint.FillShape(gtx.Ops, styles[i].Color, clip.Rect(rect).Op())
+		}
+	})
+}
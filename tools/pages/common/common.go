@@ -0,0 +1,56 @@
+// Package common holds the layout helpers shared by the pages.
+package common
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// Status is what Progress shows, job.Runner implements it.
+type Status interface {
+	Status() (string, time.Duration)
+}
+
+// Progress draws a spinner with the status of a running job, how long it
+// has been running and a cancel button.
+func Progress(gtx layout.Context, th *material.Theme, job Status, cancel *widget.Clickable) layout.Dimensions {
+	status, elapsed := job.Status()
+	return layout.Flex{
+		Axis:      layout.Horizontal,
+		Alignment: layout.Middle,
+	}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min = image.Pt(gtx.Dp(24), gtx.Dp(24))
+			return material.Loader(th).Layout(gtx)
+		}),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(material.Body1(th, fmt.Sprintf("%s (%s)", status, elapsed.Round(time.Second))).Layout),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(unit.Dp(80))
+			gtx.Constraints.Max.X = gtx.Dp(unit.Dp(80))
+			return material.Button(th, cancel, "cancel").Layout(gtx)
+		}),
+	)
+}
+
+// FormatSize prints a byte count with a binary unit, e.g. 1.5G.
+func FormatSize(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	v := float64(n)
+	i := -1
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f%c", v, units[i])
+}
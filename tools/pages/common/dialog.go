@@ -0,0 +1,132 @@
+package common
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+var (
+	// DangerColor marks buttons that destroy data.
+	DangerColor = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+	MonoFont    = font.Font{Typeface: "Go Mono"}
+)
+
+// Dialog is the modal dialog of a page. It shows a message with a confirm
+// button, or a warning with the commands about to run and buttons to run
+// or cancel them.
+type Dialog struct {
+	msg string
+	// preview 是等待确认的命令，确认后调用 confirmed
+	preview   []string
+	confirmed func()
+	visible   bool
+	okButton  widget.Clickable
+	noButton  widget.Clickable
+}
+
+// Fail shows msg.
+func (d *Dialog) Fail(msg string) {
+	d.msg = msg
+	d.preview = nil
+	d.confirmed = nil
+	d.visible = true
+}
+
+// Confirm shows warning with cmds and calls confirmed once the user
+// confirms them.
+func (d *Dialog) Confirm(warning string, cmds []string, confirmed func()) {
+	d.msg = warning
+	d.preview = cmds
+	d.confirmed = confirmed
+	d.visible = true
+}
+
+func (d *Dialog) Visible() bool {
+	return d.visible
+}
+
+// Layout draws the dialog over the page when it is visible.
+func (d *Dialog) Layout(gtx layout.Context, th *material.Theme) {
+	if !d.visible {
+		return
+	}
+	// 全屏
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	full.Pop()
+
+	// 窗口大小和位置（居中），预览命令时加宽加高
+	boxW := min(gtx.Constraints.Max.X-80, 420)
+	boxH := 150
+	if len(d.preview) != 0 {
+		boxW = min(gtx.Constraints.Max.X-80, gtx.Dp(720))
+		boxH = min(gtx.Constraints.Max.Y-80, gtx.Dp(unit.Dp(150+24*len(d.preview))))
+	}
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	// 窗口背景（白色矩形）
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	// 将坐标系偏移道对话框左上角，然后在内部做正常布局
+	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		children := []layout.FlexChild{
+			layout.Rigid(material.Body1(th, d.msg).Layout),
+		}
+		// 将要执行的命令
+		for _, cmd := range d.preview {
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				lbl := material.Body2(th, cmd)
+				lbl.Font = MonoFont
+				return layout.Inset{Top: unit.Dp(4)}.Layout(gtx, lbl.Layout)
+			}))
+		}
+		children = append(children,
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if d.okButton.Clicked(gtx) {
+					d.visible = false
+					if confirmed := d.confirmed; confirmed != nil {
+						d.confirmed = nil
+						confirmed()
+					}
+				}
+				if d.noButton.Clicked(gtx) {
+					d.visible = false
+					d.confirmed = nil
+				}
+				if d.confirmed == nil {
+					return material.Button(th, &d.okButton, "confirm").Layout(gtx)
+				}
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						btn := material.Button(th, &d.okButton, "run these commands")
+						btn.Background = DangerColor
+						return btn.Layout(gtx)
+					}),
+					layout.Rigid(layout.Spacer{Width: unit.Dp(10)}.Layout),
+					layout.Rigid(material.Button(th, &d.noButton, "cancel").Layout),
+				)
+			}),
+		)
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+	offset.Pop()
+}
@@ -0,0 +1,143 @@
+package connections
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"time"
+	"tools/icon"
+	page "tools/pages"
+	"tools/pages/connform"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+// Page shows the pooled ssh connections and sets their idle timeout.
+type Page struct {
+	idleInput      widget.Editor
+	applyButton    widget.Clickable
+	closeAllButton widget.Clickable
+	closeButtons   map[string]*widget.Clickable
+	connList       widget.List
+	*page.Router
+}
+
+func New(router *page.Router) *Page {
+	page := &Page{
+		Router:       router,
+		closeButtons: make(map[string]*widget.Clickable),
+	}
+	page.idleInput.SingleLine = true
+	page.idleInput.Filter = "0123456789"
+	page.idleInput.SetText(strconv.Itoa(int(router.Pool.IdleTimeout() / time.Minute)))
+	page.connList.Axis = layout.Vertical
+	return page
+}
+
+var _ page.Page = &Page{}
+
+func (p *Page) Actions() []component.AppBarAction {
+	return []component.AppBarAction{}
+}
+
+func (p *Page) Overflow() []component.OverflowAction {
+	return []component.OverflowAction{}
+}
+
+func (p *Page) NavItem() component.NavItem {
+	return component.NavItem{
+		Name: "Connections",
+		Icon: icon.ConnectionsIcon,
+	}
+}
+
+func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	if p.applyButton.Clicked(gtx) {
+		minutes, _ := strconv.Atoi(p.idleInput.Text())
+		p.Router.Pool.SetIdleTimeout(time.Duration(minutes) * time.Minute)
+		p.idleInput.SetText(strconv.Itoa(int(p.Router.Pool.IdleTimeout() / time.Minute)))
+	}
+	conns := p.Router.Pool.Conns()
+	if p.closeAllButton.Clicked(gtx) {
+		for _, c := range conns {
+			p.Router.Pool.Drop(c.Key)
+		}
+	}
+	for _, c := range conns {
+		btn, ok := p.closeButtons[c.Key]
+		if !ok {
+			btn = new(widget.Clickable)
+			p.closeButtons[c.Key] = btn
+		}
+		if btn.Clicked(gtx) {
+			p.Router.Pool.Drop(c.Key)
+		}
+	}
+	// 空闲时间每秒刷新一次
+	gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(time.Second)})
+
+	return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				lbl := material.H6(th, "SSH connections")
+				lbl.Font.Weight = font.Bold
+				return layout.Inset{Bottom: unit.Dp(10)}.Layout(gtx, lbl.Layout)
+			}),
+			// 空闲超时设置
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(material.Body1(th, "close idle connections after").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return connform.Input(gtx, th, &p.idleInput, "minutes", 80)
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Body1(th, "minutes").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Button(th, &p.applyButton, "apply").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Button(th, &p.closeAllButton, "close all").Layout),
+				)
+			}),
+			layout.Rigid(layout.Spacer{Height: 10}.Layout),
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				if len(conns) == 0 {
+					return material.Body2(th, "No open connections.").Layout(gtx)
+				}
+				return material.List(th, &p.connList).Layout(gtx, len(conns), func(gtx layout.Context, i int) layout.Dimensions {
+					c := conns[i]
+					state := fmt.Sprintf("in use (%d)", c.InUse)
+					if c.InUse == 0 {
+						state = fmt.Sprintf("idle for %v", gtx.Now.Sub(c.LastUsed).Truncate(time.Second))
+					}
+					stateColor := color.NRGBA{R: 30, G: 140, B: 60, A: 255}
+					if !c.Alive {
+						state = "disconnected"
+						stateColor = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+					}
+					return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								gtx.Constraints.Min.X = gtx.Dp(320)
+								return material.Body1(th, c.Key).Layout(gtx)
+							}),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								gtx.Constraints.Min.X = gtx.Dp(200)
+								lbl := material.Body2(th, state)
+								lbl.Color = stateColor
+								return lbl.Layout(gtx)
+							}),
+							layout.Rigid(material.Button(th, p.closeButtons[c.Key], "close").Layout),
+						)
+					})
+				})
+			}),
+		)
+	})
+}
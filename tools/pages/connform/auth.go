@@ -0,0 +1,93 @@
+package connform
+
+import (
+	"os"
+	"tools/sshclient"
+
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// authFields are the inputs of one authentication method, used for the
+// target host and for the jump host.
+type authFields struct {
+	method   widget.Enum
+	password widget.Editor
+	keyFile  widget.Editor
+}
+
+func (a *authFields) init() {
+	a.password.SingleLine = true
+	a.password.Mask = '*'
+	a.keyFile.SingleLine = true
+	a.method.Value = string(sshclient.AuthPassword)
+}
+
+func (a *authFields) current() sshclient.AuthMethod {
+	return sshclient.AuthMethod(a.method.Value)
+}
+
+// set fills the inputs from a saved method, the password is cleared.
+func (a *authFields) set(method sshclient.AuthMethod, keyFile string) {
+	a.method.Value = string(sshclient.AuthPassword)
+	if len(method) != 0 {
+		a.method.Value = string(method)
+	}
+	a.keyFile.SetText(keyFile)
+	// 密码属于之前的主机，留着会发给新主机并跳过密码库
+	a.password.SetText("")
+}
+
+// missing returns the required credential that is empty, keyFile is a
+// fallback coming from ~/.ssh/config. With a vault the password may be
+// left empty to use the saved one.
+func (a *authFields) missing(prefix, keyFile string, vaulted bool) string {
+	switch {
+	case a.current() == sshclient.AuthPassword && a.password.Text() == "" && !vaulted:
+		return prefix + "password"
+	case a.current() == sshclient.AuthPublicKey && a.keyFile.Text() == "" && keyFile == "":
+		return prefix + "private key file"
+	case a.current() == sshclient.AuthAgent && os.Getenv("SSH_AUTH_SOCK") == "":
+		return "ssh agent (SSH_AUTH_SOCK)"
+	}
+	return ""
+}
+
+// apply copies the selected method and credentials into cfg.
+func (a *authFields) apply(cfg *sshclient.Config) {
+	cfg.Auth = a.current()
+	cfg.Password = a.password.Text()
+	cfg.KeyFile = a.keyFile.Text()
+}
+
+// layoutSecret draws the password or key file input for the selected method.
+func (a *authFields) layoutSecret(gtx layout.Context, th *material.Theme, vaulted bool) layout.Dimensions {
+	switch a.current() {
+	case sshclient.AuthPublicKey:
+		return Input(gtx, th, &a.keyFile, "private key file, e.g. ~/.ssh/id_ed25519", 200)
+	case sshclient.AuthAgent:
+		gtx.Constraints.Min.X = gtx.Dp(200)
+		return material.Body2(th, "keys from ssh-agent").Layout(gtx)
+	case sshclient.AuthKeyboardInteractive:
+		return Input(gtx, th, &a.password, "password (optional)", 200)
+	}
+	if vaulted {
+		return Input(gtx, th, &a.password, "password (empty: vault)", 200)
+	}
+	return Input(gtx, th, &a.password, "password", 200)
+}
+
+// layoutMethods draws the radio buttons selecting the method.
+func (a *authFields) layoutMethods(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	children := make([]layout.FlexChild, 0, len(sshclient.AuthMethods))
+	for _, m := range sshclient.AuthMethods {
+		children = append(children, layout.Rigid(
+			material.RadioButton(th, &a.method, string(m), string(m)).Layout,
+		))
+	}
+	return layout.Flex{
+		Axis:      layout.Horizontal,
+		Alignment: layout.Middle,
+	}.Layout(gtx, children...)
+}
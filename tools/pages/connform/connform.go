@@ -0,0 +1,498 @@
+package connform
+
+import (
+	"fmt"
+	"image/color"
+	"net"
+	"os"
+	"strings"
+	"tools/inventory"
+	page "tools/pages"
+	"tools/sshclient"
+	"tools/sshconfig"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// Form holds the connection inputs shared by the remote pages: address,
+// user name, the authentication method with its credentials and an
+// optional jump host.
+type Form struct {
+	remoteIpInput widget.Editor
+	usernameInput widget.Editor
+	auth          authFields
+	useJump       widget.Bool
+	jumpInput     widget.Editor
+	jumpUserInput widget.Editor
+	jumpAuth      authFields
+	pickerButton  widget.Clickable
+	filterInput   widget.Editor
+	hostList      widget.List
+	hostButtons   []widget.Clickable
+	showPicker    bool
+	*page.Router
+}
+
+func New(router *page.Router) *Form {
+	f := &Form{
+		Router: router,
+	}
+	f.remoteIpInput.SingleLine = true
+	f.usernameInput.SingleLine = true
+	f.auth.init()
+	f.jumpInput.SingleLine = true
+	f.jumpUserInput.SingleLine = true
+	f.jumpAuth.init()
+	f.filterInput.SingleLine = true
+	f.hostList.Axis = layout.Vertical
+	return f
+}
+
+// Fill copies a saved host into the inputs. The passwords are cleared
+// since the inventory does not store them, the vault supplies them instead.
+func (f *Form) Fill(h inventory.Host) {
+	f.remoteIpInput.SetText(h.Addr())
+	f.usernameInput.SetText(h.User)
+	f.auth.set(h.Auth, h.KeyFile)
+	f.useJump.Value = len(h.ProxyJump) != 0
+	f.jumpInput.SetText(h.ProxyJump)
+	f.jumpAuth.password.SetText("")
+}
+
+// resolve looks the typed address up in ~/.ssh/config the way the ssh
+// command does. Addresses with an explicit port are used as they are.
+func (f *Form) resolve() (sshconfig.Host, bool) {
+	return f.resolveAlias(f.remoteIpInput.Text())
+}
+
+func (f *Form) resolveAlias(host string) (sshconfig.Host, bool) {
+	host = strings.TrimSpace(host)
+	if f.Router.SSHConfig == nil || len(host) == 0 {
+		return sshconfig.Host{}, false
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return sshconfig.Host{}, false
+	}
+	return f.Router.SSHConfig.Resolve(host), true
+}
+
+// identityFile returns the first IdentityFile of h that exists.
+func identityFile(h sshconfig.Host) string {
+	for _, file := range h.IdentityFiles {
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// Missing returns the name of the first required input that is empty, the
+// password is only required for password authentication. Values provided
+// by ~/.ssh/config count as filled in.
+func (f *Form) Missing() string {
+	resolved, _ := f.resolve()
+	switch {
+	case f.remoteIpInput.Text() == "":
+		return "ip address"
+	case f.usernameInput.Text() == "" && resolved.User == "":
+		return "login user name"
+	}
+	if item := f.auth.missing("login user ", identityFile(resolved), f.vaulted()); len(item) != 0 {
+		return item
+	}
+	if !f.useJump.Value {
+		return ""
+	}
+	if f.jumpInput.Text() == "" {
+		return "jump host"
+	}
+	return f.jumpAuth.missing("jump host ", "", f.vaulted())
+}
+
+// Config returns the ssh client config for the current inputs. Editors are
+// only safe to read on the UI goroutine, so call it before starting a job.
+func (f *Form) Config() (sshclient.Config, error) {
+	cfg := sshclient.Config{
+		Host:          f.remoteIpInput.Text(),
+		User:          f.usernameInput.Text(),
+		Prompt:        f.Router.Prompts.Ask,
+		HostKeyPrompt: f.Router.HostKeys.Confirm,
+		Secrets:       f.Router.Secrets(),
+	}
+	f.auth.apply(&cfg)
+
+	// 与 ssh 命令一致，输入的用户名、私钥和跳板机优先于配置文件
+	jumpSpec := ""
+	if resolved, ok := f.resolve(); ok {
+		cfg.Host = resolved.Addr()
+		if len(cfg.User) == 0 {
+			cfg.User = resolved.User
+		}
+		if len(cfg.KeyFile) == 0 {
+			cfg.KeyFile = identityFile(resolved)
+		}
+		jumpSpec = resolved.ProxyJump
+	}
+	if f.useJump.Value {
+		jumpSpec = f.jumpInput.Text()
+	}
+	jumps, err := f.jumpChain(jumpSpec, map[string]bool{})
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Jumps = jumps
+	return cfg, nil
+}
+
+// Target returns user@address for the inputs after resolving ssh config
+// aliases, it identifies the host in the command history.
+func (f *Form) Target() string {
+	host, user := f.remoteIpInput.Text(), f.usernameInput.Text()
+	if resolved, ok := f.resolve(); ok {
+		host = resolved.Addr()
+		if len(user) == 0 {
+			user = resolved.User
+		}
+	}
+	return user + "@" + host
+}
+
+// HostConfig returns the ssh client config for a saved host or alias
+// without going through the inputs, passwords come from the vault.
+func (f *Form) HostConfig(h inventory.Host) (sshclient.Config, error) {
+	cfg := sshclient.Config{
+		Host:          h.Addr(),
+		User:          h.User,
+		Auth:          h.Auth,
+		KeyFile:       h.KeyFile,
+		Prompt:        f.Router.Prompts.Ask,
+		HostKeyPrompt: f.Router.HostKeys.Confirm,
+		Secrets:       f.Router.Secrets(),
+	}
+	if len(cfg.Auth) == 0 {
+		cfg.Auth = sshclient.AuthPassword
+	}
+	jumpSpec := h.ProxyJump
+	if resolved, ok := f.resolveAlias(cfg.Host); ok {
+		cfg.Host = resolved.Addr()
+		if len(cfg.User) == 0 {
+			cfg.User = resolved.User
+		}
+		if len(cfg.KeyFile) == 0 {
+			cfg.KeyFile = identityFile(resolved)
+		}
+		if len(jumpSpec) == 0 {
+			jumpSpec = resolved.ProxyJump
+		}
+	}
+	jumps, err := f.jumpChain(jumpSpec, map[string]bool{})
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %v", h.Name, err)
+	}
+	cfg.Jumps = jumps
+	return cfg, nil
+}
+
+// jumpChain expands a ProxyJump list into hop configs. Entries can be saved
+// host names, ~/.ssh/config aliases or [user@]host[:port], the jumps of
+// saved hosts and aliases are followed as well.
+func (f *Form) jumpChain(spec string, seen map[string]bool) ([]sshclient.Config, error) {
+	entries, err := sshclient.ParseProxyJump(spec)
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]sshclient.Config, 0, len(entries))
+	for _, e := range entries {
+		if seen[e.Host] {
+			return nil, fmt.Errorf("jump host %s refers to itself", e.Host)
+		}
+		seen[e.Host] = true
+
+		hop := e
+		via := ""
+		if h, ok := f.savedHost(e.Host); ok {
+			hop.Host = h.Addr()
+			hop.Auth = h.Auth
+			hop.KeyFile = h.KeyFile
+			if len(hop.User) == 0 {
+				hop.User = h.User
+			}
+			via = h.ProxyJump
+		} else {
+			// 未保存的跳板机使用表单中的跳板机认证方式
+			f.jumpAuth.apply(&hop)
+			if len(hop.User) == 0 {
+				hop.User = f.jumpUserInput.Text()
+			}
+			if resolved, ok := f.resolveAlias(e.Host); ok {
+				hop.Host = resolved.Addr()
+				if len(hop.User) == 0 {
+					hop.User = resolved.User
+				}
+				if file := identityFile(resolved); len(file) != 0 && len(hop.KeyFile) == 0 {
+					hop.KeyFile = file
+				}
+				via = resolved.ProxyJump
+			}
+		}
+		before, err := f.jumpChain(via, seen)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, before...)
+		chain = append(chain, hop)
+	}
+	return chain, nil
+}
+
+// vaulted reports whether saved passwords can stand in for empty inputs.
+func (f *Form) vaulted() bool {
+	return f.Router.Vault != nil && f.Router.Vault.Exists()
+}
+
+func (f *Form) savedHost(name string) (inventory.Host, bool) {
+	if f.Router.Inventory == nil {
+		return inventory.Host{}, false
+	}
+	return f.Router.Inventory.Get(name)
+}
+
+// resolvedHint describes what an ssh config alias resolves to, it is empty
+// when the address is not an alias.
+func (f *Form) resolvedHint() string {
+	resolved, ok := f.resolve()
+	if !ok || !f.Router.SSHConfig.Has(resolved.Alias) {
+		return ""
+	}
+	target := resolved.Addr()
+	if len(resolved.User) != 0 {
+		target = resolved.User + "@" + target
+	}
+	if len(resolved.ProxyJump) != 0 && !f.useJump.Value {
+		target += " via " + resolved.ProxyJump
+	}
+	return fmt.Sprintf("%s → %s (~/.ssh/config)", resolved.Alias, target)
+}
+
+func (f *Form) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{
+			Axis:      layout.Vertical,
+			Alignment: layout.Middle,
+		}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{
+					Axis:      layout.Horizontal,
+					Alignment: layout.Middle,
+					Spacing:   layout.SpaceSides,
+				}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return Input(gtx, th, &f.remoteIpInput, "remote ip address", 280)
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					// 用户名输入框
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return Input(gtx, th, &f.usernameInput, "user name", 200)
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					// 密码或私钥文件输入框，随认证方式切换
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return f.auth.layoutSecret(gtx, th, f.vaulted())
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					// 已保存的主机
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if f.pickerButton.Clicked(gtx) {
+							f.showPicker = !f.showPicker
+						}
+						return material.Button(th, &f.pickerButton, "hosts").Layout(gtx)
+					}),
+				)
+			}),
+			layout.Rigid(layout.Spacer{Height: 5}.Layout),
+			// 认证方式
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{
+					Axis:      layout.Horizontal,
+					Alignment: layout.Middle,
+				}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return f.auth.layoutMethods(gtx, th)
+					}),
+					layout.Rigid(layout.Spacer{Width: 20}.Layout),
+					layout.Rigid(material.CheckBox(th, &f.useJump, "via jump host").Layout),
+				)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				hint := f.resolvedHint()
+				if len(hint) == 0 {
+					return layout.Dimensions{}
+				}
+				return material.Caption(th, hint).Layout(gtx)
+			}),
+			// 跳板机
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !f.useJump.Value {
+					return layout.Dimensions{}
+				}
+				return f.layoutJump(gtx, th)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !f.showPicker {
+					return layout.Dimensions{}
+				}
+				return f.layoutPicker(gtx, th)
+			}),
+		)
+	})
+}
+
+// layoutJump draws the jump host inputs, the credentials are used for jump
+// hosts that are not saved in the inventory.
+func (f *Form) layoutJump(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	return layout.Inset{Top: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{
+			Axis:      layout.Vertical,
+			Alignment: layout.Middle,
+		}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{
+					Axis:      layout.Horizontal,
+					Alignment: layout.Middle,
+				}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return Input(gtx, th, &f.jumpInput, "jump host: saved host, alias or user@host:port", 280)
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return Input(gtx, th, &f.jumpUserInput, "jump user name", 200)
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return f.jumpAuth.layoutSecret(gtx, th, f.vaulted())
+					}),
+				)
+			}),
+			layout.Rigid(layout.Spacer{Height: 5}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return f.jumpAuth.layoutMethods(gtx, th)
+			}),
+		)
+	})
+}
+
+// Targets returns the saved hosts followed by the ~/.ssh/config aliases.
+func (f *Form) Targets() []inventory.Host {
+	hosts := make([]inventory.Host, 0)
+	if f.Router.Inventory != nil {
+		hosts = append(hosts, f.Router.Inventory.Hosts()...)
+	}
+	if f.Router.SSHConfig != nil {
+		for _, alias := range f.Router.SSHConfig.Aliases() {
+			resolved := f.Router.SSHConfig.Resolve(alias)
+			h := inventory.Host{
+				Name:    alias,
+				Address: alias,
+				User:    resolved.User,
+				Groups:  []string{"ssh config"},
+			}
+			if file := identityFile(resolved); len(file) != 0 {
+				h.Auth = sshclient.AuthPublicKey
+				h.KeyFile = file
+			}
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+// layoutPicker lists saved hosts and ssh config aliases matching the
+// filter, clicking one fills the connection inputs.
+func (f *Form) layoutPicker(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	hosts := make([]inventory.Host, 0)
+	for _, h := range f.Targets() {
+		if h.Match(f.filterInput.Text()) {
+			hosts = append(hosts, h)
+		}
+	}
+	if len(f.hostButtons) < len(hosts) {
+		f.hostButtons = append(f.hostButtons, make([]widget.Clickable, len(hosts)-len(f.hostButtons))...)
+	}
+	for i, h := range hosts {
+		if f.hostButtons[i].Clicked(gtx) {
+			f.Fill(h)
+			f.showPicker = false
+		}
+	}
+
+	gtx.Constraints.Max.X = min(gtx.Constraints.Max.X, gtx.Dp(720))
+	gtx.Constraints.Max.Y = min(gtx.Constraints.Max.Y, gtx.Dp(240))
+	return layout.Inset{Top: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return Input(gtx, th, &f.filterInput, "filter by name, address, tag or group", 400)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if len(hosts) == 0 {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.Body2(th, "no saved hosts, add them on the Hosts page").Layout)
+				}
+				return material.List(th, &f.hostList).Layout(gtx, len(hosts), func(gtx layout.Context, i int) layout.Dimensions {
+					return material.Clickable(gtx, &f.hostButtons[i], func(gtx layout.Context) layout.Dimensions {
+						return layout.UniformInset(unit.Dp(5)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return HostLabel(gtx, th, hosts[i])
+						})
+					})
+				})
+			}),
+		)
+	})
+}
+
+// HostLabel draws a one line summary of a saved host.
+func HostLabel(gtx layout.Context, th *material.Theme, h inventory.Host) layout.Dimensions {
+	target := h.Addr()
+	if len(h.User) != 0 {
+		target = h.User + "@" + target
+	}
+	extra := make([]string, 0, 2)
+	if len(h.Groups) != 0 {
+		extra = append(extra, fmt.Sprintf("[%s]", strings.Join(h.Groups, ", ")))
+	}
+	for _, t := range h.Tags {
+		extra = append(extra, "#"+t)
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			name := material.Body1(th, h.Name)
+			name.Font.Weight = font.Bold
+			return name.Layout(gtx)
+		}),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(material.Body2(th, target).Layout),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(material.Caption(th, strings.Join(extra, " ")).Layout),
+	)
+}
+
+// Input draws a bordered single editor of the given width, the style used
+// by all connection inputs.
+func Input(gtx layout.Context, th *material.Theme, editor *widget.Editor, hint string, width unit.Dp) layout.Dimensions {
+	return widget.Border{
+		Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
+		Width: unit.Dp(1),
+	}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Inset{
+			Top:    unit.Dp(5),
+			Bottom: unit.Dp(5),
+			Left:   unit.Dp(5),
+		}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(width)
+			gtx.Constraints.Max.X = gtx.Dp(width)
+			return material.Editor(th, editor, hint).Layout(gtx)
+		})
+	})
+}
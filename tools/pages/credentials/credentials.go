@@ -0,0 +1,468 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"tools/icon"
+	page "tools/pages"
+	"tools/pages/connform"
+	"tools/sshclient"
+	"tools/vault"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+const (
+	kindPassword   = "password"
+	kindPassphrase = "passphrase"
+)
+
+// Page unlocks the credential vault and edits the saved passwords and key
+// passphrases.
+type Page struct {
+	passphraseInput widget.Editor
+	confirmInput    widget.Editor
+	unlockButton    widget.Clickable
+	lockButton      widget.Clickable
+	kind            widget.Enum
+	userInput       widget.Editor
+	hostInput       widget.Editor
+	keyFileInput    widget.Editor
+	secretInput     widget.Editor
+	newButton       widget.Clickable
+	saveButton      widget.Clickable
+	deleteButton    widget.Clickable
+	modalButton     widget.Clickable
+	cancelButton    widget.Clickable
+	entryList       widget.List
+	entryButtons    []widget.Clickable
+	showDialog      bool
+	confirmDelete   bool
+	confirmMsg      string
+	// editing 是正在编辑的条目名，新建时为空
+	editing string
+	// unlocked 记录上一帧的状态，自动锁定后清空表单
+	unlocked bool
+	*page.Router
+}
+
+func New(router *page.Router) *Page {
+	page := &Page{
+		Router: router,
+	}
+	for _, e := range []*widget.Editor{
+		&page.passphraseInput, &page.confirmInput, &page.userInput,
+		&page.hostInput, &page.keyFileInput, &page.secretInput,
+	} {
+		e.SingleLine = true
+	}
+	page.passphraseInput.Mask = '*'
+	page.passphraseInput.Submit = true
+	page.confirmInput.Mask = '*'
+	page.confirmInput.Submit = true
+	page.secretInput.Mask = '*'
+	page.kind.Value = kindPassword
+	page.entryList.Axis = layout.Vertical
+	return page
+}
+
+var _ page.Page = &Page{}
+
+func (p *Page) Actions() []component.AppBarAction {
+	return []component.AppBarAction{}
+}
+
+func (p *Page) Overflow() []component.OverflowAction {
+	return []component.OverflowAction{}
+}
+
+func (p *Page) NavItem() component.NavItem {
+	return component.NavItem{
+		Name: "Vault",
+		Icon: icon.VaultIcon,
+	}
+}
+
+func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	unlocked := !p.Router.Vault.Locked()
+	if p.unlocked && !unlocked {
+		p.edit("", vault.Credential{})
+	}
+	p.unlocked = unlocked
+
+	mainPage := layout.Flex{
+		Axis: layout.Horizontal,
+	}
+	mainPage.Layout(gtx,
+		// 解锁和条目列表
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(480)
+			gtx.Constraints.Max.X = gtx.Dp(480)
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutVault(gtx, th)
+			})
+		}),
+		// 编辑表单
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if !p.unlocked {
+				return layout.Dimensions{}
+			}
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutForm(gtx, th)
+			})
+		}),
+	)
+
+	// 弹出对话框
+	if p.showDialog {
+		p.drawConfirmDialog(gtx, th)
+	}
+
+	return mainPage.Layout(gtx)
+}
+
+func (p *Page) layoutVault(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	exists := p.Router.Vault.Exists()
+	submitted := false
+	for _, e := range []*widget.Editor{&p.passphraseInput, &p.confirmInput} {
+		for {
+			ev, ok := e.Update(gtx)
+			if !ok {
+				break
+			}
+			if _, ok := ev.(widget.SubmitEvent); ok {
+				submitted = true
+			}
+		}
+	}
+	if p.unlockButton.Clicked(gtx) || (submitted && !p.unlocked) {
+		p.unlock(exists)
+	}
+	if p.lockButton.Clicked(gtx) {
+		p.Router.Vault.Lock()
+	}
+
+	status := "Locked, enter the master passphrase to manage saved credentials."
+	switch {
+	case !exists:
+		status = "No vault yet, choose a master passphrase to create one."
+	case p.unlocked:
+		status = fmt.Sprintf("Unlocked, locks after %v without use.", vault.DefaultLockTimeout)
+	}
+
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			lbl := material.H6(th, "Credential vault")
+			lbl.Font.Weight = font.Bold
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, lbl.Layout)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(10)}.Layout(gtx, material.Body2(th, status).Layout)
+		}),
+	}
+	if p.unlocked {
+		children = append(children,
+			layout.Rigid(material.Button(th, &p.lockButton, "lock").Layout),
+			layout.Rigid(layout.Spacer{Height: 10}.Layout),
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutEntries(gtx, th)
+			}),
+		)
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	}
+
+	children = append(children,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return connform.Input(gtx, th, &p.passphraseInput, "master passphrase", 440)
+		}),
+		layout.Rigid(layout.Spacer{Height: 5}.Layout),
+	)
+	label := "unlock"
+	if !exists {
+		label = "create"
+		children = append(children,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return connform.Input(gtx, th, &p.confirmInput, "repeat master passphrase", 440)
+			}),
+			layout.Rigid(layout.Spacer{Height: 5}.Layout),
+		)
+	}
+	children = append(children, layout.Rigid(material.Button(th, &p.unlockButton, label).Layout))
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+func (p *Page) layoutEntries(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	names, err := p.Router.Vault.Names()
+	if err != nil {
+		return layout.Dimensions{}
+	}
+	if len(p.entryButtons) < len(names) {
+		p.entryButtons = append(p.entryButtons, make([]widget.Clickable, len(names)-len(p.entryButtons))...)
+	}
+	for i, name := range names {
+		if p.entryButtons[i].Clicked(gtx) {
+			c, err := p.Router.Vault.Get(name)
+			if err != nil {
+				p.confirmMsg = err.Error()
+				p.showDialog = true
+				continue
+			}
+			p.edit(name, c)
+		}
+	}
+	if len(names) == 0 {
+		return material.Body2(th, "No saved credentials.").Layout(gtx)
+	}
+	return material.List(th, &p.entryList).Layout(gtx, len(names), func(gtx layout.Context, i int) layout.Dimensions {
+		return material.Clickable(gtx, &p.entryButtons[i], func(gtx layout.Context) layout.Dimensions {
+			// 高亮正在编辑的条目
+			if names[i] == p.editing {
+				rect := clip.Rect{Max: image.Pt(gtx.Constraints.Max.X, gtx.Dp(30))}.Push(gtx.Ops)
+				paint.Fill(gtx.Ops, color.NRGBA{R: 230, G: 236, B: 250, A: 255})
+				rect.Pop()
+			}
+			return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.Body1(th, names[i]).Layout)
+		})
+	})
+}
+
+func (p *Page) layoutForm(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	if p.newButton.Clicked(gtx) {
+		p.edit("", vault.Credential{})
+	}
+	if p.saveButton.Clicked(gtx) {
+		p.save()
+	}
+	if p.deleteButton.Clicked(gtx) && len(p.editing) != 0 {
+		p.confirmMsg = "delete credential " + p.editing + "?"
+		p.confirmDelete = true
+		p.showDialog = true
+	}
+
+	title := "New credential"
+	if len(p.editing) != 0 {
+		title = "Edit " + p.editing
+	}
+	field := func(label string, editor *widget.Editor, hint string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						gtx.Constraints.Min.X = gtx.Dp(100)
+						return material.Body1(th, label).Layout(gtx)
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return connform.Input(gtx, th, editor, hint, 360)
+					}),
+				)
+			})
+		})
+	}
+
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			lbl := material.H6(th, title)
+			lbl.Font.Weight = font.Bold
+			return layout.Inset{Bottom: unit.Dp(10)}.Layout(gtx, lbl.Layout)
+		}),
+		// 条目类型
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						gtx.Constraints.Min.X = gtx.Dp(100)
+						return material.Body1(th, "Kind").Layout(gtx)
+					}),
+					layout.Rigid(material.RadioButton(th, &p.kind, kindPassword, "login password").Layout),
+					layout.Rigid(material.RadioButton(th, &p.kind, kindPassphrase, "key passphrase").Layout),
+				)
+			})
+		}),
+	}
+	if p.kind.Value == kindPassphrase {
+		children = append(children,
+			field("Key file", &p.keyFileInput, "private key file, e.g. ~/.ssh/id_ed25519"),
+			field("Passphrase", &p.secretInput, "key passphrase"),
+		)
+	} else {
+		children = append(children,
+			field("User", &p.userInput, "login user name"),
+			field("Host", &p.hostInput, "ip address or host name, port defaults to 22"),
+			field("Password", &p.secretInput, "login password"),
+		)
+	}
+	children = append(children,
+		layout.Rigid(layout.Spacer{Height: 10}.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			children := []layout.FlexChild{
+				layout.Rigid(material.Button(th, &p.saveButton, "save").Layout),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(material.Button(th, &p.newButton, "new").Layout),
+			}
+			if len(p.editing) != 0 {
+				children = append(children,
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						btn := material.Button(th, &p.deleteButton, "delete")
+						btn.Background = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+						return btn.Layout(gtx)
+					}),
+				)
+			}
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+		}),
+	)
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+func (p *Page) unlock(exists bool) {
+	passphrase := p.passphraseInput.Text()
+	if !exists && passphrase != p.confirmInput.Text() {
+		p.confirmMsg = "the passphrases do not match"
+		p.showDialog = true
+		return
+	}
+	err := p.Router.Vault.Unlock(passphrase)
+	p.passphraseInput.SetText("")
+	p.confirmInput.SetText("")
+	if err != nil {
+		p.confirmMsg = err.Error()
+		p.showDialog = true
+	}
+}
+
+// edit loads an entry into the form, an empty name starts a new entry.
+func (p *Page) edit(name string, c vault.Credential) {
+	p.editing = name
+	p.userInput.SetText("")
+	p.hostInput.SetText("")
+	p.keyFileInput.SetText("")
+	p.secretInput.SetText(c.Password)
+	p.kind.Value = kindPassword
+	if keyFile, ok := strings.CutPrefix(name, vault.PassphraseKey("")); ok {
+		p.kind.Value = kindPassphrase
+		p.keyFileInput.SetText(keyFile)
+		return
+	}
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		p.userInput.SetText(name[:i])
+		p.hostInput.SetText(name[i+1:])
+	}
+}
+
+// name builds the entry name from the inputs, addresses and key files are
+// normalised the way the ssh client looks them up.
+func (p *Page) name() (string, error) {
+	if p.kind.Value == kindPassphrase {
+		keyFile := strings.TrimSpace(p.keyFileInput.Text())
+		if len(keyFile) == 0 {
+			return "", errors.New("private key file is required")
+		}
+		return vault.PassphraseKey(sshclient.ExpandHome(keyFile)), nil
+	}
+	user := strings.TrimSpace(p.userInput.Text())
+	if len(user) == 0 {
+		return "", errors.New("login user name is required")
+	}
+	addr, err := sshclient.NormalizeAddr(strings.TrimSpace(p.hostInput.Text()))
+	if err != nil {
+		return "", err
+	}
+	return vault.PasswordKey(user, addr), nil
+}
+
+func (p *Page) save() {
+	name, err := p.name()
+	if err == nil && len(p.secretInput.Text()) == 0 {
+		err = errors.New("the secret is empty")
+	}
+	if err == nil {
+		err = p.Router.Vault.Put(name, vault.Credential{Password: p.secretInput.Text()})
+	}
+	// 条目名变化时删除旧条目
+	if err == nil && len(p.editing) != 0 && p.editing != name {
+		err = p.Router.Vault.Delete(p.editing)
+	}
+	if err != nil {
+		p.confirmMsg = err.Error()
+		p.showDialog = true
+		return
+	}
+	p.editing = name
+}
+
+func (p *Page) delete() {
+	if err := p.Router.Vault.Delete(p.editing); err != nil {
+		p.confirmMsg = err.Error()
+		p.showDialog = true
+		return
+	}
+	p.edit("", vault.Credential{})
+}
+
+func (p *Page) drawConfirmDialog(gtx layout.Context, th *material.Theme) {
+	// 全屏
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	full.Pop()
+
+	// 窗口大小和位置（居中）
+	boxW := min(gtx.Constraints.Max.X-80, 420)
+	boxH := 150
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	// 窗口背景（白色矩形）
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	// 将坐标系偏移道对话框左上角，然后在内部做正常布局
+	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(material.Body1(th, p.confirmMsg).Layout),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if p.modalButton.Clicked(gtx) {
+					p.showDialog = false
+					if p.confirmDelete {
+						p.confirmDelete = false
+						p.delete()
+					}
+				}
+				if p.cancelButton.Clicked(gtx) {
+					p.showDialog = false
+					p.confirmDelete = false
+				}
+				children := []layout.FlexChild{
+					layout.Rigid(material.Button(th, &p.modalButton, "confirm").Layout),
+				}
+				if p.confirmDelete {
+					children = append(children,
+						layout.Rigid(layout.Spacer{Width: unit.Dp(10)}.Layout),
+						layout.Rigid(material.Button(th, &p.cancelButton, "cancel").Layout),
+					)
+				}
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+			}),
+		)
+	})
+	offset.Pop()
+}
@@ -1,18 +1,23 @@
 package disktable
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"strconv"
-	"strings"
 	"tools/icon"
+	"tools/job"
 	page "tools/pages"
+	"tools/pages/common"
+	"tools/pages/connform"
+	"tools/pages/fstools"
+	"tools/sshclient"
 	"tools/utils"
 
 	"gioui.org/font"
 	"gioui.org/layout"
-	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/text"
@@ -20,29 +25,49 @@ import (
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 	"gioui.org/x/component"
-	"golang.org/x/crypto/ssh"
 )
 
 type Page struct {
-	remoteIpInput widget.Editor
-	usernameInput widget.Editor
-	passwordInput widget.Editor
-	execButton    widget.Clickable
-	modalButton   widget.Clickable
-	showDialog    bool
-	confirmMsg    string
-	resultEditor  widget.Editor
-	devices       []utils.BlockDevice
+	connForm     *connform.Form
+	execButton   widget.Clickable
+	cancelButton widget.Clickable
+	dialog       common.Dialog
+	resultEditor widget.Editor
+	devices      []utils.BlockDevice
+	runner       *job.Runner[*scan]
+	// health 是每块磁盘的 SMART 数据，detail 是正在查看详情的磁盘
+	health     map[string]*diskHealth
+	healthBtns map[string]*widget.Clickable
+	detail     string
+	backButton widget.Clickable
+	detailList widget.List
+	// rows 是展开后可见的行，collapsed 记录折叠的设备
+	rows           []treeRow
+	collapsed      map[string]bool
+	toggles        map[string]*widget.Clickable
+	expandButton   widget.Clickable
+	collapseButton widget.Clickable
+	grid           component.GridState
+	// selected 是选中的分区行的 key，fs 是它的文件系统操作
+	selected     string
+	selects      map[string]*widget.Clickable
+	fs           *fstools.Tools
+	sudoPassword string
 	*page.Router
 }
 
 func New(router *page.Router) *Page {
 	page := &Page{
-		Router: router,
+		Router:     router,
+		runner:     job.NewRunner[*scan](router.Invalidate),
+		collapsed:  make(map[string]bool),
+		toggles:    make(map[string]*widget.Clickable),
+		healthBtns: make(map[string]*widget.Clickable),
+		selects:    make(map[string]*widget.Clickable),
+		fs:         fstools.New(),
 	}
-	page.remoteIpInput.SingleLine = true
-	page.usernameInput.SingleLine = true
-	page.passwordInput.SingleLine = true
+	page.detailList.Axis = layout.Vertical
+	page.connForm = connform.New(router)
 	page.resultEditor.ReadOnly = true
 	page.resultEditor.WrapPolicy = text.WrapGraphemes
 	return page
@@ -50,7 +75,47 @@ func New(router *page.Router) *Page {
 
 var _ page.Page = &Page{}
 
-var headingText = []string{"No", "Name", "Type", "Size", "Serial", "Vendor", "Model"}
+// column is a column of the device tree.
+type column struct {
+	name  string
+	width int
+}
+
+var columns = []column{
+	{"No", 60},
+	{"Name", 280},
+	{"Status", 150},
+	{"Health", 100},
+	{"Type", 110},
+	{"Size", 90},
+	{"FSType", 100},
+	{"MountPoint", 220},
+	{"UUID", 320},
+	{"FSSize", 90},
+	{"FSUsed", 90},
+	{"FSAvail", 90},
+	{"Serial", 200},
+	{"Vendor", 120},
+	{"Model", 220},
+}
+
+var (
+	rootColor    = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+	mountedColor = color.NRGBA{R: 30, G: 110, B: 200, A: 255}
+	diskRowColor = color.NRGBA{R: 240, G: 240, B: 240, A: 255}
+	// selectedColor 标记选中的分区行
+	selectedColor = color.NRGBA{R: 63, G: 81, B: 181, A: 40}
+)
+
+// treeRow is a visible row of the device tree.
+type treeRow struct {
+	dev *utils.BlockDevice
+	// key 是从磁盘到设备的路径，同一个设备（如 RAID）可能出现在多个父设备下
+	key   string
+	depth int
+	// no 是磁盘的序号，子设备为 0
+	no int
+}
 
 func (p *Page) Actions() []component.AppBarAction {
 	return []component.AppBarAction{}
@@ -68,202 +133,257 @@ func (p *Page) NavItem() component.NavItem {
 }
 
 func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	// 后台任务结束后在 UI 协程中处理结果
+	if res, ok := p.runner.Poll(); ok {
+		p.handleResult(res)
+	}
+	p.update(gtx)
+
 	mainPage := layout.Flex{
 		Axis:      layout.Vertical,
 		Alignment: layout.Middle,
 	}
 	mainPage.Layout(gtx,
-		layout.Rigid(
-			func(gtx layout.Context) layout.Dimensions {
-				return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-					return layout.Flex{
-						Axis:      layout.Horizontal,
-						Alignment: layout.Middle,
-						Spacing:   layout.SpaceSides,
-					}.Layout(gtx,
-						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							return widget.Border{
-								Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
-								Width: unit.Dp(1),
-							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								return layout.Inset{
-									Top:    unit.Dp(5),
-									Bottom: unit.Dp(5),
-									Left:   unit.Dp(5),
-								}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-									gtx.Constraints.Min.X = gtx.Dp(280)
-									gtx.Constraints.Max.X = gtx.Dp(280)
-									return material.Editor(th, &p.remoteIpInput, "remote ip address").Layout(gtx)
-								})
-							})
-						}),
-						layout.Rigid(layout.Spacer{Width: 10}.Layout),
-						// 用户名输入框
-						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							return widget.Border{
-								Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
-								Width: unit.Dp(1),
-							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								return layout.Inset{
-									Top:    unit.Dp(5),
-									Bottom: unit.Dp(5),
-									Left:   unit.Dp(5),
-								}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-									gtx.Constraints.Min.X = gtx.Dp(200)
-									gtx.Constraints.Max.X = gtx.Dp(200)
-									return material.Editor(th, &p.usernameInput, "user name").Layout(gtx)
-								})
-							})
-						}),
-						layout.Rigid(layout.Spacer{Width: 10}.Layout),
-						// 密码输入框
-						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							return widget.Border{
-								Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
-								Width: unit.Dp(1),
-							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								return layout.Inset{
-									Top:    unit.Dp(5),
-									Bottom: unit.Dp(5),
-									Left:   unit.Dp(5),
-								}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-									gtx.Constraints.Min.X = gtx.Dp(200)
-									gtx.Constraints.Max.X = gtx.Dp(200)
-									p.passwordInput.Mask = '*'
-									return material.Editor(th, &p.passwordInput, "password").Layout(gtx)
-								})
-							})
-						}),
-					)
-				})
-			},
-		),
+		// 连接参数
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.connForm.Layout(gtx, th)
+		}),
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			// 点击按钮逻辑
-			if p.execButton.Clicked(gtx) {
-				p.checkInput()
-				if !p.showDialog {
-					p.executeCmd()
-				}
+			if p.execButton.Clicked(gtx) && !p.runner.Running() {
+				p.executeCmd(nil)
 			}
-			return Button(gtx, 80, th, &p.execButton, "execute")
+			if p.cancelButton.Clicked(gtx) {
+				p.runner.Cancel()
+			}
+			if p.runner.Running() {
+				return common.Progress(gtx, th, p.runner, &p.cancelButton)
+			}
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return Button(gtx, 80, th, &p.execButton, "execute")
+				}),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return Button(gtx, 110, th, &p.expandButton, "expand all")
+				}),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return Button(gtx, 110, th, &p.collapseButton, "collapse all")
+				}),
+			)
+		}),
+		// 选中分区的文件系统操作
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			dev := p.selectedDevice()
+			if dev == nil || len(p.detail) != 0 {
+				return layout.Dimensions{}
+			}
+			return layout.Inset{Left: unit.Dp(8), Right: unit.Dp(8), Top: unit.Dp(8)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.fs.Layout(gtx, th, dev)
+			})
 		}),
 		// 结果显示区域（占满剩余空间）
-		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+		layout.Flexed(2, func(gtx layout.Context) layout.Dimensions {
 			in := layout.UniformInset(unit.Dp(8))
 			return in.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-				return p.tableLayout(gtx, th, 1)
+				if h, ok := p.health[p.detail]; ok {
+					return p.layoutDetail(gtx, th, h)
+				}
+				return p.tableLayout(gtx, th)
+			})
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if len(p.devices) == 0 || len(p.detail) != 0 {
+				return layout.Dimensions{}
+			}
+			return layout.Inset{Left: unit.Dp(8), Right: unit.Dp(8)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.fs.LayoutFstab(gtx, th, p.devices)
 			})
 		}),
 	)
 
 	// 弹出对话框
-	if p.showDialog {
-		p.drawConfirmDialog(gtx, th)
-	}
+	p.dialog.Layout(gtx, th)
 
 	return mainPage.Layout(gtx)
 }
 
-func (p *Page) checkInput() {
-	itemName := ""
-	switch {
-	case p.remoteIpInput.Text() == "":
-		itemName = "ip address"
-	case p.usernameInput.Text() == "":
-		itemName = "login user name"
-	case p.passwordInput.Text() == "":
-		itemName = "login user password"
+// executeCmd lists the devices and reads their SMART data, after running
+// op when it is not nil.
+func (p *Page) executeCmd(op *fstools.Operation) {
+	if itemName := p.connForm.Missing(); len(itemName) != 0 {
+		p.dialog.Fail(fmt.Sprintf("%s is required", itemName))
+		return
 	}
-	if len(itemName) != 0 {
-		p.confirmMsg = fmt.Sprintf("%s is required", itemName)
-		p.showDialog = true
+	// 编辑框只能在 UI 协程中读取，先取出参数再交给后台任务
+	cfg, err := p.connForm.Config()
+	if err != nil {
+		p.dialog.Fail(err.Error())
+		return
 	}
-}
+	target := p.connForm.Target()
+	password := p.sudoPassword
+	if len(password) == 0 {
+		password = cfg.Password
+	}
+	p.runner.Start(func(ctx context.Context, report func(string)) (*scan, error) {
+		report(fmt.Sprintf("connecting to %s", cfg.Host))
+		client, release, err := p.Router.Pool.Get(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 
-func (p *Page) drawConfirmDialog(gtx layout.Context, th *material.Theme) {
-	// 全屏
-	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
-	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
-	full.Pop()
-
-	// 窗口大小和位置（居中）
-	boxW := min(gtx.Constraints.Max.X-80, 420)
-	boxH := 150
-	cx := gtx.Constraints.Max.X / 2
-	cy := gtx.Constraints.Max.Y / 2
-	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
-
-	// 窗口背景（白色矩形）
-	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
-	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
-	box.Pop()
-
-	// 将坐标系偏移道对话框左上角，然后在内部做正常布局
-	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
-	inner := gtx
-	inner.Constraints.Min = image.Pt(boxW, 0)
-	inner.Constraints.Max = image.Pt(boxW, boxH)
-
-	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
-		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-			layout.Rigid(material.Body1(th, p.confirmMsg).Layout),
-			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
-			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-				btn := material.Button(th, &p.modalButton, "confirm")
-				// 注意：Clicked() 无参
-				if p.modalButton.Clicked(gtx) {
-					p.showDialog = false
-				}
-				return btn.Layout(gtx)
-			}),
-		)
+		sudo := &sshclient.Sudo{Password: password}
+		var applyErr error
+		if op != nil {
+			applyErr = fstools.Apply(ctx, p.Router, client, sudo, target, op, report)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+
+		// 执行失败时也重新读取，显示设备当前的状态
+		report("listing block devices")
+		output, err := client.Run(ctx, utils.Lsblk)
+		if err != nil {
+			return nil, errors.Join(applyErr, err)
+		}
+		devices, err := utils.GetBlockDevices(output)
+		if err != nil {
+			return nil, errors.Join(applyErr, err)
+		}
+		fstab := fstools.ReadFstab(ctx, client)
+		health, err := p.readHealth(ctx, client, devices, target, sudo, report)
+		if err != nil {
+			return nil, errors.Join(applyErr, err)
+		}
+		return &scan{devices: devices, health: health, fstab: fstab, password: sudo.Password}, applyErr
 	})
-	offset.Pop()
 }
 
-func (p *Page) executeCmd() {
-
-	config := &ssh.ClientConfig{
-		User: p.usernameInput.Text(),
-		Auth: []ssh.AuthMethod{
-			ssh.Password(p.passwordInput.Text()),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+func (p *Page) handleResult(res job.Result[*scan]) {
+	if errors.Is(res.Err, context.Canceled) {
+		return
 	}
-	host := p.remoteIpInput.Text()
-	if len(strings.Split(host, ":")) == 1 {
-		host += ":22"
+	if s := res.Value; s != nil {
+		p.devices = s.devices
+		p.health = s.health
+		p.fs.SetFstab(s.fstab)
+		p.sudoPassword = s.password
+		p.healthBtns = make(map[string]*widget.Clickable)
+		if _, ok := p.health[p.detail]; !ok {
+			p.detail = ""
+		}
+		p.flatten()
 	}
-	conn, err := ssh.Dial("tcp", host, config)
-	if err != nil {
-		p.confirmMsg = fmt.Sprintf("dail %s failed, %v", host, err)
-		p.showDialog = true
-		return
+	if res.Err != nil {
+		p.dialog.Fail(res.Err.Error())
 	}
-	defer conn.Close()
+}
 
-	session, err := conn.NewSession()
-	if err != nil {
-		p.confirmMsg = fmt.Sprintf("create session failed, %v", err)
-		p.showDialog = true
-		return
+// selectedDevice returns the selected partition, nil when none is
+// selected or it is gone.
+func (p *Page) selectedDevice() *utils.BlockDevice {
+	for _, r := range p.rows {
+		if r.key == p.selected && len(p.selected) != 0 {
+			return r.dev
+		}
 	}
-	defer session.Close()
+	return nil
+}
 
-	output, err := session.CombinedOutput(utils.Lsblk)
-	if err != nil {
-		p.confirmMsg = fmt.Sprintf("execute command failed, %v", err)
-		p.showDialog = true
-		return
+// update handles the expand and collapse buttons of the rows.
+func (p *Page) update(gtx layout.Context) {
+	changed := false
+	for key, btn := range p.toggles {
+		if btn.Clicked(gtx) {
+			p.collapsed[key] = !p.collapsed[key]
+			changed = true
+		}
 	}
-	blocks, err := utils.GetBlockDevices(output)
-	if err != nil {
-		p.confirmMsg = err.Error()
-		p.showDialog = true
+	if p.expandButton.Clicked(gtx) {
+		clear(p.collapsed)
+		changed = true
+	}
+	if p.collapseButton.Clicked(gtx) {
+		for _, d := range p.devices {
+			if len(d.Children) != 0 {
+				p.collapsed[d.Name] = true
+			}
+		}
+		changed = true
+	}
+	if changed {
+		p.flatten()
+	}
+	for name, btn := range p.healthBtns {
+		if btn.Clicked(gtx) {
+			p.detail = name
+		}
+	}
+	if p.backButton.Clicked(gtx) {
+		p.detail = ""
+	}
+	for key, btn := range p.selects {
+		if btn.Clicked(gtx) {
+			p.selected = key
+			p.fs.Select(p.selectedDevice())
+		}
+	}
+	if p.runner.Running() || len(p.devices) == 0 {
 		return
 	}
-	p.devices = blocks
+	warning, op, err := p.fs.Update(gtx, p.selectedDevice())
+	switch {
+	case err != nil:
+		p.dialog.Fail(err.Error())
+	case op != nil:
+		p.dialog.Confirm(warning, op.Cmds, func() { p.executeCmd(op) })
+	}
+}
+
+// flatten lists the rows of the devices that are not collapsed, disks
+// start expanded.
+func (p *Page) flatten() {
+	p.rows = p.rows[:0]
+	var walk func(dev *utils.BlockDevice, key string, depth, no int)
+	walk = func(dev *utils.BlockDevice, key string, depth, no int) {
+		p.rows = append(p.rows, treeRow{dev: dev, key: key, depth: depth, no: no})
+		if p.collapsed[key] {
+			return
+		}
+		for i := range dev.Children {
+			child := &dev.Children[i]
+			walk(child, key+"/"+child.Name, depth+1, 0)
+		}
+	}
+	for i := range p.devices {
+		walk(&p.devices[i], p.devices[i].Name, 0, i+1)
+	}
+	// 只保留可见行的按钮，分区行可以选中
+	toggles := make(map[string]*widget.Clickable, len(p.rows))
+	selects := make(map[string]*widget.Clickable)
+	for _, r := range p.rows {
+		if r.dev.Type == "part" {
+			btn, ok := p.selects[r.key]
+			if !ok {
+				btn = new(widget.Clickable)
+			}
+			selects[r.key] = btn
+		}
+		if len(r.dev.Children) == 0 {
+			continue
+		}
+		btn, ok := p.toggles[r.key]
+		if !ok {
+			btn = new(widget.Clickable)
+		}
+		toggles[r.key] = btn
+	}
+	p.toggles = toggles
+	p.selects = selects
 }
 
 func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.Clickable, txt string) layout.Dimensions {
@@ -272,7 +392,7 @@ func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.C
 	return material.Button(th, wid, txt).Layout(gtx)
 }
 
-func (p *Page) tableLayout(gtx layout.Context, th *material.Theme, rows int) layout.Dimensions {
+func (p *Page) tableLayout(gtx layout.Context, th *material.Theme) layout.Dimensions {
 
 	border := widget.Border{
 		Color: color.NRGBA{A: 255},
@@ -298,19 +418,12 @@ func (p *Page) tableLayout(gtx layout.Context, th *material.Theme, rows int) lay
 
 	gtx.Constraints = orig
 
-	tbl := component.Table(th, &component.GridState{}) // GridState 管理状态
-	return tbl.Layout(gtx, len(p.devices), len(headingText),
+	tbl := component.Table(th, &p.grid) // GridState 管理状态
+	return tbl.Layout(gtx, len(p.rows), len(columns),
 		func(axis layout.Axis, index, constraint int) int {
 			switch axis {
 			case layout.Horizontal:
-				switch index {
-				case 0:
-					return 100
-				case 4:
-					return 300
-				default:
-					return 200
-				}
+				return gtx.Dp(unit.Dp(columns[index].width))
 			default:
 				return dims.Size.Y
 			}
@@ -318,42 +431,156 @@ func (p *Page) tableLayout(gtx layout.Context, th *material.Theme, rows int) lay
 		func(gtx layout.Context, col int) layout.Dimensions { // 表头函数
 			return border.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 				return inset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-					headingLabel.Text = headingText[col]
+					headingLabel.Text = columns[col].name
 					return headingLabel.Layout(gtx)
 				})
 			})
 		},
 		func(gtx layout.Context, row, col int) layout.Dimensions { // 单元格函数
+			r := p.rows[row]
+			dev := r.dev
+			// 磁盘行加背景色，便于区分不同的磁盘
+			switch {
+			case r.key == p.selected:
+				paint.FillShape(gtx.Ops, selectedColor, clip.Rect{Max: gtx.Constraints.Max}.Op())
+			case r.depth == 0:
+				paint.FillShape(gtx.Ops, diskRowColor, clip.Rect{Max: gtx.Constraints.Max}.Op())
+			}
 			return inset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				dataLabel.Alignment = text.Middle
+				dataLabel.Text = ""
 				switch col {
 				case 0:
-					dataLabel.Text = strconv.Itoa(row + 1)
-					dataLabel.Alignment = text.Middle
+					if r.no != 0 {
+						dataLabel.Text = strconv.Itoa(r.no)
+					}
 				case 1:
-					dataLabel.Text = p.devices[row].Name
-					dataLabel.Alignment = text.Middle
+					return p.layoutName(gtx, th, r)
 				case 2:
-					if p.devices[row].Rota {
-						dataLabel.Text = "HDD"
-					} else {
-						dataLabel.Text = "SSD"
-					}
-					dataLabel.Alignment = text.Middle
+					return layoutStatus(gtx, th, dev)
 				case 3:
-					dataLabel.Text = strconv.Itoa(int(p.devices[row].Size / 1024 / 1024 / 1024))
-					dataLabel.Alignment = text.End
+					if r.depth != 0 {
+						return layout.Dimensions{}
+					}
+					return p.layoutHealth(gtx, th, dev.Name)
 				case 4:
-					dataLabel.Text = p.devices[row].Serial
-					dataLabel.Alignment = text.Middle
+					dataLabel.Text = dev.Type
+					if dev.Type == "disk" {
+						if dev.Rota {
+							dataLabel.Text = "disk (HDD)"
+						} else {
+							dataLabel.Text = "disk (SSD)"
+						}
+					}
 				case 5:
-					dataLabel.Text = p.devices[row].Vendor
-					dataLabel.Alignment = text.Middle
+					dataLabel.Text = common.FormatSize(dev.Size)
+					dataLabel.Alignment = text.End
 				case 6:
-					dataLabel.Text = p.devices[row].Model
-					dataLabel.Alignment = text.Middle
+					dataLabel.Text = dev.FSType
+				case 7:
+					dataLabel.Text = dev.MountPoint
+					dataLabel.Alignment = text.Start
+				case 8:
+					dataLabel.Text = dev.UUID
+				case 9:
+					dataLabel.Text = fsSize(dev.FSSize)
+					dataLabel.Alignment = text.End
+				case 10:
+					dataLabel.Text = fsSize(dev.FSUsed)
+					dataLabel.Alignment = text.End
+				case 11:
+					dataLabel.Text = fsSize(dev.FSAvail)
+					dataLabel.Alignment = text.End
+				case 12:
+					dataLabel.Text = dev.Serial
+				case 13:
+					dataLabel.Text = dev.Vendor
+				case 14:
+					dataLabel.Text = dev.Model
 				}
 				return dataLabel.Layout(gtx)
 			})
 		},
 	)
 }
+
+// layoutName draws the device name indented by its depth, devices with
+// children get a button to expand or collapse them. Clicking the name of a
+// partition selects it for the filesystem operations.
+func (p *Page) layoutName(gtx layout.Context, th *material.Theme, r treeRow) layout.Dimensions {
+	lbl := material.Body1(th, r.dev.Name)
+	lbl.MaxLines = 1
+	if r.depth == 0 {
+		lbl.Font.Weight = font.Bold
+	}
+	return layout.Inset{Left: unit.Dp(16 * r.depth)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min.X = gtx.Dp(18)
+				btn, ok := p.toggles[r.key]
+				if !ok {
+					return layout.Dimensions{Size: image.Pt(gtx.Constraints.Min.X, 0)}
+				}
+				arrow := "▼"
+				if p.collapsed[r.key] {
+					arrow = "▶"
+				}
+				return material.Clickable(gtx, btn, material.Body2(th, arrow).Layout)
+			}),
+			layout.Rigid(layout.Spacer{Width: 4}.Layout),
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				btn, ok := p.selects[r.key]
+				if !ok {
+					return lbl.Layout(gtx)
+				}
+				return material.Clickable(gtx, btn, lbl.Layout)
+			}),
+		)
+	})
+}
+
+// layoutStatus draws the root and mounted badges of a device.
+func layoutStatus(gtx layout.Context, th *material.Theme, dev *utils.BlockDevice) layout.Dimensions {
+	var children []layout.FlexChild
+	if dev.IsRootDisk() {
+		children = append(children,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return badge(gtx, th, "root", rootColor)
+			}),
+			layout.Rigid(layout.Spacer{Width: 4}.Layout),
+		)
+	}
+	if mounted, _ := dev.IsMounted(); mounted {
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return badge(gtx, th, "mounted", mountedColor)
+		}))
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+}
+
+// badge draws txt in white on a rounded rectangle of color c.
+func badge(gtx layout.Context, th *material.Theme, txt string, c color.NRGBA) layout.Dimensions {
+	gtx.Constraints.Min = image.Point{}
+	return layout.Background{}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		rr := gtx.Dp(4)
+		paint.FillShape(gtx.Ops, c, clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, rr).Op(gtx.Ops))
+		return layout.Dimensions{Size: gtx.Constraints.Min}
+	}, func(gtx layout.Context) layout.Dimensions {
+		return layout.Inset{Left: unit.Dp(6), Right: unit.Dp(6), Top: unit.Dp(1), Bottom: unit.Dp(1)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			lbl := material.Caption(th, txt)
+			lbl.Color = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			lbl.MaxLines = 1
+			return lbl.Layout(gtx)
+		})
+	})
+}
+
+// fsSize formats a filesystem size reported by lsblk, which is empty when
+// the filesystem is not mounted.
+func fsSize(s string) string {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return s
+	}
+	return common.FormatSize(n)
+}
@@ -0,0 +1,262 @@
+package disktable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"tools/pages/common"
+	"tools/sshclient"
+	"tools/utils"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+var healthColors = map[utils.Health]color.NRGBA{
+	utils.HealthUnknown: {R: 140, G: 140, B: 140, A: 255},
+	utils.HealthPass:    {R: 30, G: 140, B: 60, A: 255},
+	utils.HealthWarn:    {R: 220, G: 140, B: 0, A: 255},
+	utils.HealthFail:    {R: 200, G: 40, B: 40, A: 255},
+}
+
+// scan is the result of a refresh.
+type scan struct {
+	devices []utils.BlockDevice
+	health  map[string]*diskHealth
+	fstab   []utils.FstabEntry
+	// password 是 sudo 接受的密码，下次执行时先用它
+	password string
+}
+
+// diskHealth is the SMART data of a disk, err is why it could not be read.
+type diskHealth struct {
+	device  string
+	info    *utils.SmartInfo
+	err     error
+	health  utils.Health
+	reasons []string
+}
+
+// readHealth runs smartctl for every disk. smartctl needs root, the
+// password in sudo is tried first and the user is asked when sudo wants
+// another one. A disk whose data cannot be read is reported as unknown.
+func (p *Page) readHealth(ctx context.Context, client *sshclient.Client, devices []utils.BlockDevice, target string, sudo *sshclient.Sudo, report func(string)) (map[string]*diskHealth, error) {
+	// sudoErr 不为空时用户取消了输入密码，其余磁盘不再询问
+	var sudoErr error
+	health := make(map[string]*diskHealth)
+	for _, d := range devices {
+		if d.Type != "disk" {
+			continue
+		}
+		report(fmt.Sprintf("reading SMART data of %s", d.Name))
+		h := &diskHealth{device: d.Name}
+		health[d.Name] = h
+		if sudoErr != nil {
+			h.err = sudoErr
+			continue
+		}
+		h.err = sudo.Retry(ctx, target, p.Prompts.Ask, func(s sshclient.Sudo) error {
+			var err error
+			h.info, err = smartctl(ctx, client, d.Name, s)
+			return err
+		})
+		if errors.Is(h.err, sshclient.ErrSudoPassword) || errors.Is(h.err, sshclient.ErrSudoWrongPassword) {
+			sudoErr = h.err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if h.info != nil {
+			h.health, h.reasons = h.info.Health()
+		} else {
+			h.reasons = []string{h.err.Error()}
+		}
+	}
+	return health, nil
+}
+
+func smartctl(ctx context.Context, client *sshclient.Client, device string, sudo sshclient.Sudo) (*utils.SmartInfo, error) {
+	res, err := client.ExecSudo(ctx, utils.Smartctl+" "+sshclient.Quote(device), sudo)
+	if err != nil {
+		return nil, err
+	}
+	// smartctl 没有安装时没有 JSON 输出
+	if len(strings.TrimSpace(string(res.Stdout))) == 0 {
+		msg := strings.TrimSpace(string(res.Stderr))
+		if len(msg) == 0 {
+			msg = res.Status()
+		}
+		return nil, fmt.Errorf("smartctl failed, %s", msg)
+	}
+	return utils.ParseSmart(res.Stdout)
+}
+
+// layoutHealth draws the health badge of a disk, clicking it opens the
+// details.
+func (p *Page) layoutHealth(gtx layout.Context, th *material.Theme, name string) layout.Dimensions {
+	h, ok := p.health[name]
+	if !ok {
+		return layout.Dimensions{}
+	}
+	btn, ok := p.healthBtns[name]
+	if !ok {
+		btn = new(widget.Clickable)
+		p.healthBtns[name] = btn
+	}
+	return material.Clickable(gtx, btn, func(gtx layout.Context) layout.Dimensions {
+		return badge(gtx, th, h.health.String(), healthColors[h.health])
+	})
+}
+
+// layoutDetail shows the SMART data of one disk.
+func (p *Page) layoutDetail(gtx layout.Context, th *material.Theme, h *diskHealth) layout.Dimensions {
+	var rows []layout.Widget
+	text := func(s string) layout.Widget {
+		return material.Body2(th, s).Layout
+	}
+	title := func(s string) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Top: unit.Dp(10), Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				lbl := material.Body1(th, s)
+				lbl.Font.Weight = font.Bold
+				return lbl.Layout(gtx)
+			})
+		}
+	}
+	field := func(name, value string) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			return fields(gtx, []int{220, 0}, text(name), text(value))
+		}
+	}
+
+	rows = append(rows, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return Button(gtx, 80, th, &p.backButton, "back")
+			}),
+			layout.Rigid(layout.Spacer{Width: 10}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return badge(gtx, th, h.health.String(), healthColors[h.health])
+			}),
+			layout.Rigid(layout.Spacer{Width: 10}.Layout),
+			layout.Rigid(material.H6(th, h.device).Layout),
+		)
+	})
+	for _, r := range h.reasons {
+		lbl := material.Body1(th, r)
+		lbl.Color = healthColors[h.health]
+		rows = append(rows, lbl.Layout)
+	}
+
+	if info := h.info; info != nil {
+		passed := "not reported"
+		if info.Passed != nil {
+			passed = "FAILED"
+			if *info.Passed {
+				passed = "PASSED"
+			}
+		}
+		// 温度为 0 表示 smartctl 没有报告
+		temperature := "not reported"
+		if info.Temperature != 0 {
+			temperature = fmt.Sprintf("%d°C", info.Temperature)
+		}
+		rows = append(rows,
+			title("Device"),
+			field("protocol", info.Protocol),
+			field("model", info.ModelName),
+			field("serial number", info.SerialNumber),
+			field("firmware", info.Firmware),
+			field("self-assessment", passed),
+			field("temperature", temperature),
+			field("power-on hours", strconv.FormatInt(info.PowerOnHours, 10)),
+		)
+		if nvme := info.NVMe; nvme != nil {
+			rows = append(rows,
+				title("NVMe health"),
+				field("critical warning", fmt.Sprintf("0x%02x", nvme.CriticalWarning)),
+				field("available spare", fmt.Sprintf("%d%% (threshold %d%%)", nvme.AvailableSpare, nvme.AvailableSpareThreshold)),
+				field("percentage used", fmt.Sprintf("%d%%", nvme.PercentageUsed)),
+				field("media errors", strconv.FormatInt(nvme.MediaErrors, 10)),
+				field("error log entries", strconv.FormatInt(nvme.ErrorLogEntries, 10)),
+				field("unsafe shutdowns", strconv.FormatInt(nvme.UnsafeShutdowns, 10)),
+				// 一个数据单元是 1000 个 512 字节的扇区
+				field("data read", common.FormatSize(nvme.DataUnitsRead*512000)),
+				field("data written", common.FormatSize(nvme.DataUnitsWritten*512000)),
+			)
+		}
+		if ata := info.ATA; ata != nil {
+			rows = append(rows,
+				title("ATA attributes"),
+				field("reallocated sectors", strconv.FormatInt(ata.ReallocatedSectors, 10)),
+				field("pending sectors", strconv.FormatInt(ata.PendingSectors, 10)),
+				field("offline uncorrectable", strconv.FormatInt(ata.OfflineUncorrectable, 10)),
+			)
+			widths := []int{50, 260, 70, 70, 70, 100, 0}
+			rows = append(rows, func(gtx layout.Context) layout.Dimensions {
+				return layout.Inset{Top: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					heads := []string{"ID", "Name", "Value", "Worst", "Thresh", "Failed", "Raw"}
+					ws := make([]layout.Widget, len(heads))
+					for i, s := range heads {
+						lbl := material.Body2(th, s)
+						lbl.Font.Weight = font.Bold
+						ws[i] = lbl.Layout
+					}
+					return fields(gtx, widths, ws...)
+				})
+			})
+			for _, a := range ata.Attributes {
+				failed := a.WhenFailed
+				if len(failed) == 0 {
+					failed = "-"
+				}
+				rows = append(rows, func(gtx layout.Context) layout.Dimensions {
+					return fields(gtx, widths,
+						text(strconv.Itoa(a.ID)),
+						text(a.Name),
+						text(strconv.Itoa(a.Value)),
+						text(strconv.Itoa(a.Worst)),
+						text(strconv.Itoa(a.Thresh)),
+						text(failed),
+						text(a.RawString),
+					)
+				})
+			}
+		}
+		if len(info.Messages) != 0 {
+			rows = append(rows, title("smartctl messages"))
+			for _, m := range info.Messages {
+				rows = append(rows, text(m))
+			}
+		}
+	}
+
+	return material.List(th, &p.detailList).Layout(gtx, len(rows), func(gtx layout.Context, i int) layout.Dimensions {
+		return layout.Inset{Bottom: unit.Dp(2)}.Layout(gtx, rows[i])
+	})
+}
+
+// fields lays out widgets in a row with the given widths in dp, a zero
+// width takes the remaining space.
+func fields(gtx layout.Context, widths []int, widgets ...layout.Widget) layout.Dimensions {
+	children := make([]layout.FlexChild, 0, len(widgets))
+	for i, w := range widgets {
+		width := unit.Dp(widths[i])
+		if width == 0 {
+			children = append(children, layout.Flexed(1, w))
+			continue
+		}
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(width)
+			gtx.Constraints.Max.X = gtx.Dp(width)
+			return w(gtx)
+		}))
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+}
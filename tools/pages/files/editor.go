@@ -0,0 +1,280 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+	"tools/remotefs"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+var (
+	monoFont    = font.Font{Typeface: "Go Mono"}
+	gutterColor = color.NRGBA{R: 140, G: 140, B: 140, A: 255}
+)
+
+// textEditor edits a remote text file. The editor is laid out at its full
+// height inside a list, so the list does the scrolling and the line
+// numbers can be drawn next to the positions the editor reports for the
+// start of each line.
+type textEditor struct {
+	file     *remotefs.File
+	input    widget.Editor
+	list     widget.List
+	modified bool
+	// starts 是每行第一个字符的位置，内容变化后重新计算
+	starts []int
+	// caret 是上一帧的光标位置，光标移动时滚动列表使其可见
+	caret   int
+	regions []widget.Region
+
+	saveButton   widget.Clickable
+	reloadButton widget.Clickable
+	closeButton  widget.Clickable
+	backup       widget.Bool
+	sudo         widget.Bool
+}
+
+func newTextEditor(file *remotefs.File) *textEditor {
+	e := &textEditor{file: file}
+	e.input.WrapPolicy = text.WrapGraphemes
+	e.input.SetText(file.Content)
+	e.list.Axis = layout.Vertical
+	e.backup.Value = true
+	e.changed()
+	return e
+}
+
+// setFile replaces the file after it was saved, the edited text is kept.
+func (e *textEditor) setFile(file *remotefs.File) {
+	e.file = file
+	e.changed()
+}
+
+func (e *textEditor) changed() {
+	content := e.input.Text()
+	e.modified = content != e.file.Content
+	e.starts = e.starts[:0]
+	e.starts = append(e.starts, 0)
+	runes := 0
+	for _, r := range content {
+		runes++
+		if r == '\n' {
+			e.starts = append(e.starts, runes)
+		}
+	}
+}
+
+// openFile reads a remote file into the editor.
+func (p *Page) openFile(file string) {
+	rfs := p.session.fs
+	p.runner.Start(func(ctx context.Context, report func(string)) (*listing, error) {
+		report(fmt.Sprintf("opening %s", file))
+		f, err := rfs.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return &listing{opened: f}, nil
+	})
+}
+
+// save writes the editor back, force skips the conflict check.
+func (p *Page) save(force bool) {
+	e := p.editor
+	file, content := e.file, e.input.Text()
+	opts := remotefs.WriteOptions{Backup: e.backup.Value, Force: force}
+	if e.sudo.Value {
+		opts.Sudo, opts.SudoTarget, opts.Prompt = p.session.sudo, p.session.target, p.Prompts.Ask
+	}
+	rfs := p.session.fs
+	p.runner.Start(func(ctx context.Context, report func(string)) (*listing, error) {
+		report(fmt.Sprintf("saving %s", file.Path))
+		saved, err := rfs.WriteFile(ctx, file, content, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &listing{saved: saved}, nil
+	})
+}
+
+// discard runs action, asking first when the editor has unsaved changes.
+func (p *Page) discard(action func()) {
+	if p.editor == nil || !p.editor.modified {
+		action()
+		return
+	}
+	p.confirmMsg = fmt.Sprintf("discard the changes to %s?", p.editor.file.Path)
+	p.confirmed = action
+	p.showDialog = true
+}
+
+// updateEditor handles the editor buttons and tracks changes to the text.
+func (p *Page) updateEditor(gtx layout.Context) {
+	e := p.editor
+	for {
+		ev, ok := e.input.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := ev.(widget.ChangeEvent); ok {
+			e.changed()
+		}
+	}
+	if e.saveButton.Clicked(gtx) {
+		p.save(false)
+	}
+	if e.reloadButton.Clicked(gtx) {
+		p.discard(func() { p.openFile(e.file.Path) })
+	}
+	if e.closeButton.Clicked(gtx) {
+		p.discard(func() {
+			p.editor = nil
+			p.open(p.dir)
+		})
+	}
+}
+
+func (p *Page) layoutEditor(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	e := p.editor
+	title := e.file.Path
+	if e.modified {
+		title += " (modified)"
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(material.Button(th, &e.saveButton, "save").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Button(th, &e.reloadButton, "reload").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Button(th, &e.closeButton, "close").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.CheckBox(th, &e.backup, "keep .bak backup").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.CheckBox(th, &e.sudo, "save with sudo").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Body2(th, title).Layout),
+				)
+			})
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return widget.Border{
+				Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
+				Width: unit.Dp(1),
+			}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.UniformInset(unit.Dp(5)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return e.layoutText(gtx, th)
+				})
+			})
+		}),
+	)
+}
+
+func (e *textEditor) layoutText(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	viewH := gtx.Constraints.Max.Y
+	dims := material.List(th, &e.list).Layout(gtx, 1, func(gtx layout.Context, _ int) layout.Dimensions {
+		return e.layoutLines(gtx, th, viewH)
+	})
+	e.followCaret(gtx, viewH)
+	return dims
+}
+
+// layoutLines draws the editor and the numbers of the lines in view, viewH
+// is the height of the list.
+func (e *textEditor) layoutLines(gtx layout.Context, th *material.Theme, viewH int) layout.Dimensions {
+	// 行号宽度按最大行号的位数计算
+	digits := len(strconv.Itoa(len(e.starts)))
+	number := func(n string) material.LabelStyle {
+		lbl := material.Label(th, th.TextSize, n)
+		lbl.Font = monoFont
+		lbl.Color = gutterColor
+		lbl.MaxLines = 1
+		return lbl
+	}
+	measure := op.Record(gtx.Ops)
+	ngtx := gtx
+	ngtx.Constraints.Min = image.Point{}
+	gutterW := number(strings.Repeat("0", digits)).Layout(ngtx).Size.X + gtx.Dp(12)
+	measure.Stop()
+
+	// 先布局编辑框，才能得到每行的位置
+	macro := op.Record(gtx.Ops)
+	egtx := gtx
+	egtx.Constraints.Max.X = max(gtx.Constraints.Max.X-gutterW, 0)
+	egtx.Constraints.Min.X = egtx.Constraints.Max.X
+	ed := material.Editor(th, &e.input, "")
+	ed.Font = monoFont
+	dims := ed.Layout(egtx)
+	call := macro.Stop()
+
+	top := e.list.Position.Offset
+	bottom := top + viewH
+	// 找到第一个可见的行
+	first := sort.Search(len(e.starts), func(i int) bool {
+		r := e.lineRegion(i)
+		return r != nil && r.Bounds.Max.Y > top
+	})
+	for i := first; i < len(e.starts); i++ {
+		r := e.lineRegion(i)
+		if r == nil || r.Bounds.Min.Y > bottom {
+			break
+		}
+		off := op.Offset(image.Pt(0, r.Bounds.Min.Y)).Push(gtx.Ops)
+		ngtx.Constraints.Min.X = gutterW - gtx.Dp(8)
+		ngtx.Constraints.Max.X = ngtx.Constraints.Min.X
+		layout.E.Layout(ngtx, number(strconv.Itoa(i+1)).Layout)
+		off.Pop()
+	}
+
+	off := op.Offset(image.Pt(gutterW, 0)).Push(gtx.Ops)
+	call.Add(gtx.Ops)
+	off.Pop()
+	return layout.Dimensions{Size: image.Pt(gtx.Constraints.Max.X, dims.Size.Y), Baseline: dims.Baseline}
+}
+
+// lineRegion returns where line i starts in the editor.
+func (e *textEditor) lineRegion(i int) *widget.Region {
+	e.regions = e.input.Regions(e.starts[i], e.starts[i], e.regions)
+	if len(e.regions) == 0 {
+		return nil
+	}
+	return &e.regions[0]
+}
+
+// followCaret scrolls the list when the caret moved out of view, the
+// editor cannot scroll itself since it has all the height it wants.
+func (e *textEditor) followCaret(gtx layout.Context, viewH int) {
+	_, caret := e.input.Selection()
+	if caret == e.caret {
+		return
+	}
+	e.caret = caret
+	e.regions = e.input.Regions(caret, caret, e.regions)
+	if len(e.regions) == 0 {
+		return
+	}
+	r := e.regions[0].Bounds
+	pos := &e.list.Position
+	switch {
+	case r.Min.Y < pos.Offset:
+		pos.Offset = r.Min.Y
+	case r.Max.Y > pos.Offset+viewH:
+		pos.Offset = r.Max.Y - viewH
+	default:
+		return
+	}
+	pos.First = 0
+	gtx.Execute(op.InvalidateCmd{})
+}
@@ -0,0 +1,846 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"tools/icon"
+	"tools/job"
+	page "tools/pages"
+	"tools/pages/common"
+	"tools/pages/connform"
+	"tools/remotefs"
+	"tools/sshclient"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+var selectedColor = color.NRGBA{R: 63, G: 81, B: 181, A: 40}
+
+// session is an open sftp client on a pooled connection.
+type session struct {
+	fs      *remotefs.FS
+	release func()
+	target  string
+	// sudo 保存 sudo 接受的密码，开始时用登录密码
+	sudo *sshclient.Sudo
+}
+
+func (s *session) close() {
+	s.fs.Close()
+	s.release()
+}
+
+// listing is the result of every background job: the directory shown
+// after the operation, the new session when connecting and the file
+// opened or saved in the editor.
+type listing struct {
+	session *session
+	dir     string
+	entries []remotefs.Entry
+	opened  *remotefs.File
+	saved   *remotefs.File
+}
+
+// column is a sortable column of the file list.
+type column struct {
+	name  string
+	width unit.Dp
+	less  func(a, b remotefs.Entry) bool
+}
+
+var columns = []column{
+	{"name", 0, func(a, b remotefs.Entry) bool { return a.Name < b.Name }},
+	{"size", 100, func(a, b remotefs.Entry) bool { return a.Size < b.Size }},
+	{"mode", 110, func(a, b remotefs.Entry) bool { return a.Mode.Perm() < b.Mode.Perm() }},
+	{"owner", 150, func(a, b remotefs.Entry) bool { return a.Owner < b.Owner }},
+	{"modified", 150, func(a, b remotefs.Entry) bool { return a.ModTime.Before(b.ModTime) }},
+}
+
+// Page browses the files of a remote host over sftp.
+type Page struct {
+	connForm         *connform.Form
+	connectButton    widget.Clickable
+	disconnectButton widget.Clickable
+	cancelButton     widget.Clickable
+	modalButton      widget.Clickable
+	noButton         widget.Clickable
+	showDialog       bool
+	confirmMsg       string
+	// confirmed 不为空时对话框询问是否继续，确认后执行
+	confirmed func()
+	// overwrite 在目标已存在时重新执行上一个改名或上传并覆盖目标
+	overwrite func()
+	// 目录导航
+	pathInput     widget.Editor
+	upButton      widget.Clickable
+	refreshButton widget.Clickable
+	// 文件操作，nameInput 和 modeInput 在选中文件时自动填写
+	nameInput      widget.Editor
+	mkdirButton    widget.Clickable
+	renameButton   widget.Clickable
+	modeInput      widget.Editor
+	chmodButton    widget.Clickable
+	deleteButton   widget.Clickable
+	editButton     widget.Clickable
+	localInput     widget.Editor
+	uploadButton   widget.Clickable
+	downloadButton widget.Clickable
+	// 文件列表，sortBy 是排序的列，directories 总是排在前面
+	headers  []widget.Clickable
+	sortBy   int
+	desc     bool
+	rows     []widget.Clickable
+	list     widget.List
+	dir      string
+	entries  []remotefs.Entry
+	selected string
+	session  *session
+	// editor 不为空时显示文件编辑器代替文件列表
+	editor *textEditor
+	runner *job.Runner[*listing]
+	*page.Router
+}
+
+func New(router *page.Router) *Page {
+	p := &Page{
+		Router:  router,
+		runner:  job.NewRunner[*listing](router.Invalidate),
+		headers: make([]widget.Clickable, len(columns)),
+	}
+	p.connForm = connform.New(router)
+	p.pathInput.SingleLine = true
+	p.pathInput.Submit = true
+	p.nameInput.SingleLine = true
+	p.modeInput.SingleLine = true
+	p.modeInput.Filter = "01234567"
+	p.localInput.SingleLine = true
+	p.list.Axis = layout.Vertical
+	return p
+}
+
+var _ page.Page = &Page{}
+
+func (p *Page) Actions() []component.AppBarAction {
+	return []component.AppBarAction{}
+}
+
+func (p *Page) Overflow() []component.OverflowAction {
+	return []component.OverflowAction{}
+}
+
+func (p *Page) NavItem() component.NavItem {
+	return component.NavItem{
+		Name: "Files",
+		Icon: icon.FilesIcon,
+	}
+}
+
+func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	// 后台任务结束后在 UI 协程中处理结果
+	if res, ok := p.runner.Poll(); ok {
+		p.handleResult(res)
+	}
+	p.update(gtx)
+
+	mainPage := layout.Flex{
+		Axis: layout.Vertical,
+	}
+	mainPage.Layout(gtx,
+		// 连接参数
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if p.session != nil {
+				return layout.Dimensions{}
+			}
+			return p.connForm.Layout(gtx, th)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10), Top: unit.Dp(5), Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutButtons(gtx, th)
+			})
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if p.session == nil || p.editor != nil {
+				return layout.Dimensions{}
+			}
+			return layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutTools(gtx, th)
+			})
+		}),
+		// 文件列表或编辑器（占满剩余空间）
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if p.session == nil {
+				return layout.Dimensions{}
+			}
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				if p.editor != nil {
+					return p.layoutEditor(gtx, th)
+				}
+				return p.layoutFiles(gtx, th)
+			})
+		}),
+	)
+
+	// 弹出对话框
+	if p.showDialog {
+		p.drawConfirmDialog(gtx, th)
+	}
+
+	return mainPage.Layout(gtx)
+}
+
+// update handles the clicks and the path input before the widgets are
+// laid out.
+func (p *Page) update(gtx layout.Context) {
+	if p.connectButton.Clicked(gtx) && !p.runner.Running() && p.session == nil {
+		p.connect()
+	}
+	if p.cancelButton.Clicked(gtx) {
+		p.runner.Cancel()
+	}
+	if p.disconnectButton.Clicked(gtx) && !p.runner.Running() && p.session != nil {
+		p.discard(p.disconnect)
+	}
+	if p.session == nil || p.runner.Running() {
+		return
+	}
+	if p.editor != nil {
+		p.updateEditor(gtx)
+		return
+	}
+
+	for {
+		ev, ok := p.pathInput.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := ev.(widget.SubmitEvent); ok {
+			p.open(strings.TrimSpace(p.pathInput.Text()))
+		}
+	}
+	if p.upButton.Clicked(gtx) {
+		p.open(path.Dir(p.dir))
+	}
+	if p.refreshButton.Clicked(gtx) {
+		p.open(p.dir)
+	}
+	for i := range p.headers {
+		if p.headers[i].Clicked(gtx) {
+			// 再次点击同一列时反向排序
+			p.desc = p.sortBy == i && !p.desc
+			p.sortBy = i
+			p.sort()
+		}
+	}
+	for i := range p.rows {
+		for {
+			click, ok := p.rows[i].Update(gtx)
+			if !ok {
+				break
+			}
+			p.selectEntry(p.entries[i])
+			// 双击打开目录或编辑文件
+			switch {
+			case click.NumClicks < 2:
+			case p.entries[i].IsDir():
+				p.open(path.Join(p.dir, p.entries[i].Name))
+			default:
+				p.openFile(path.Join(p.dir, p.entries[i].Name))
+			}
+		}
+	}
+
+	if p.mkdirButton.Clicked(gtx) {
+		p.mkdir()
+	}
+	if p.renameButton.Clicked(gtx) {
+		p.rename()
+	}
+	if p.chmodButton.Clicked(gtx) {
+		p.chmod()
+	}
+	if p.deleteButton.Clicked(gtx) {
+		if e, ok := p.current(); ok {
+			p.confirmMsg = fmt.Sprintf("delete %s?", path.Join(p.dir, e.Name))
+			if e.IsDir() {
+				p.confirmMsg = fmt.Sprintf("delete %s and everything in it?", path.Join(p.dir, e.Name))
+			}
+			p.confirmed = p.delete
+			p.showDialog = true
+		}
+	}
+	if p.editButton.Clicked(gtx) {
+		if e, ok := p.current(); ok && !e.IsDir() {
+			p.openFile(path.Join(p.dir, e.Name))
+		} else {
+			p.fail("select a file to edit")
+		}
+	}
+	if p.uploadButton.Clicked(gtx) {
+		p.upload()
+	}
+	if p.downloadButton.Clicked(gtx) {
+		p.download()
+	}
+}
+
+func (p *Page) disconnect() {
+	p.session.close()
+	p.session, p.editor = nil, nil
+	p.dir, p.entries, p.selected = "", nil, ""
+}
+
+func (p *Page) fail(msg string) {
+	p.confirmMsg = msg
+	p.showDialog = true
+}
+
+func (p *Page) connect() {
+	if itemName := p.connForm.Missing(); len(itemName) != 0 {
+		p.fail(fmt.Sprintf("%s is required", itemName))
+		return
+	}
+	// 编辑框只能在 UI 协程中读取，先取出参数再交给后台任务
+	cfg, err := p.connForm.Config()
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	target := p.connForm.Target()
+	p.runner.Start(func(ctx context.Context, report func(string)) (*listing, error) {
+		report(fmt.Sprintf("connecting to %s", cfg.Host))
+		client, release, err := p.Router.Pool.Get(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		report("starting sftp")
+		rfs, err := remotefs.Open(ctx, client)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		s := &session{fs: rfs, release: release, target: target, sudo: &sshclient.Sudo{Password: cfg.Password}}
+		l := &listing{session: s}
+		if l.dir, err = s.fs.Home(); err != nil {
+			return l, err
+		}
+		report(fmt.Sprintf("listing %s", l.dir))
+		l.entries, err = s.fs.List(l.dir)
+		return l, err
+	})
+}
+
+// do runs op in the background and lists dir afterwards, also when op
+// failed so the list shows what was done.
+func (p *Page) do(dir string, op func(ctx context.Context, fs *remotefs.FS, report func(string)) error) {
+	rfs := p.session.fs
+	p.runner.Start(func(ctx context.Context, report func(string)) (*listing, error) {
+		opErr := op(ctx, rfs, report)
+		l := &listing{dir: dir}
+		var err error
+		if l.entries, err = rfs.List(dir); err != nil {
+			l = nil
+		}
+		if opErr != nil {
+			return l, opErr
+		}
+		return l, err
+	})
+}
+
+// open changes to dir, symbolic links are resolved first.
+func (p *Page) open(dir string) {
+	if len(dir) == 0 {
+		dir = "."
+	}
+	if !path.IsAbs(dir) {
+		dir = path.Join(p.dir, dir)
+	}
+	rfs := p.session.fs
+	p.runner.Start(func(ctx context.Context, report func(string)) (*listing, error) {
+		report(fmt.Sprintf("listing %s", dir))
+		resolved, err := rfs.Clean(dir)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := rfs.List(resolved)
+		if err != nil {
+			return nil, err
+		}
+		return &listing{dir: resolved, entries: entries}, nil
+	})
+}
+
+func (p *Page) handleResult(res job.Result[*listing]) {
+	switch l := res.Value; {
+	case l == nil:
+	case l.session != nil && res.Err != nil:
+		// 连接后列目录失败，关闭会话
+		l.session.close()
+	default:
+		if l.session != nil {
+			p.session = l.session
+		}
+		if len(l.dir) != 0 {
+			p.setListing(l)
+		}
+		if l.opened != nil {
+			p.editor = newTextEditor(l.opened)
+		}
+		if l.saved != nil && p.editor != nil {
+			p.editor.setFile(l.saved)
+		}
+	}
+	switch {
+	case res.Err == nil || errors.Is(res.Err, context.Canceled):
+	case errors.Is(res.Err, remotefs.ErrConflict):
+		// 文件已被修改，确认后覆盖
+		p.confirmMsg = res.Err.Error() + ", overwrite it?"
+		p.confirmed = func() { p.save(true) }
+		p.showDialog = true
+	case errors.Is(res.Err, remotefs.ErrExist) && p.overwrite != nil:
+		// 目标已存在，确认后覆盖
+		p.confirmMsg = res.Err.Error() + ", overwrite it?"
+		p.confirmed = p.overwrite
+		p.showDialog = true
+	case errors.Is(res.Err, remotefs.ErrPermission) && p.editor != nil:
+		p.editor.sudo.Value = true
+		p.fail(res.Err.Error())
+	default:
+		p.fail(res.Err.Error())
+	}
+}
+
+func (p *Page) setListing(l *listing) {
+	if l.dir != p.dir {
+		p.selected = ""
+		p.list.Position = layout.Position{}
+	}
+	p.dir, p.entries = l.dir, l.entries
+	p.rows = make([]widget.Clickable, len(p.entries))
+	p.pathInput.SetText(p.dir)
+	p.sort()
+	if _, ok := p.current(); !ok {
+		p.selected = ""
+	}
+}
+
+func (p *Page) sort() {
+	less := columns[p.sortBy].less
+	sort.SliceStable(p.entries, func(i, j int) bool {
+		a, b := p.entries[i], p.entries[j]
+		if a.IsDir() != b.IsDir() {
+			return a.IsDir()
+		}
+		if p.desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
+
+func (p *Page) selectEntry(e remotefs.Entry) {
+	p.selected = e.Name
+	p.nameInput.SetText(e.Name)
+	p.modeInput.SetText(fmt.Sprintf("%o", e.Mode.Perm()))
+}
+
+// current returns the selected entry.
+func (p *Page) current() (remotefs.Entry, bool) {
+	for _, e := range p.entries {
+		if len(p.selected) != 0 && e.Name == p.selected {
+			return e, true
+		}
+	}
+	return remotefs.Entry{}, false
+}
+
+// target turns the name input into a path, relative names are in the
+// current directory.
+func (p *Page) target() (string, bool) {
+	name := strings.TrimSpace(p.nameInput.Text())
+	if len(name) == 0 {
+		p.fail("name is required")
+		return "", false
+	}
+	if path.IsAbs(name) {
+		return path.Clean(name), true
+	}
+	return path.Join(p.dir, name), true
+}
+
+func (p *Page) mkdir() {
+	dir, ok := p.target()
+	if !ok {
+		return
+	}
+	p.do(p.dir, func(ctx context.Context, rfs *remotefs.FS, report func(string)) error {
+		report(fmt.Sprintf("creating %s", dir))
+		return rfs.Mkdir(dir)
+	})
+}
+
+func (p *Page) rename() {
+	e, ok := p.current()
+	if !ok {
+		p.fail("select a file to rename")
+		return
+	}
+	newPath, ok := p.target()
+	if !ok {
+		return
+	}
+	oldPath := path.Join(p.dir, e.Name)
+	if newPath == oldPath {
+		return
+	}
+	p.renameTo(oldPath, newPath, false)
+}
+
+func (p *Page) renameTo(oldPath, newPath string, overwrite bool) {
+	p.selected = path.Base(newPath)
+	p.overwrite = func() { p.renameTo(oldPath, newPath, true) }
+	p.do(p.dir, func(ctx context.Context, rfs *remotefs.FS, report func(string)) error {
+		report(fmt.Sprintf("renaming %s", oldPath))
+		return rfs.Rename(oldPath, newPath, overwrite)
+	})
+}
+
+func (p *Page) chmod() {
+	e, ok := p.current()
+	if !ok {
+		p.fail("select a file to change its mode")
+		return
+	}
+	mode, err := strconv.ParseUint(p.modeInput.Text(), 8, 32)
+	if err != nil || mode > 0o7777 {
+		p.fail(fmt.Sprintf("invalid mode %q, use octal like 644", p.modeInput.Text()))
+		return
+	}
+	file := path.Join(p.dir, e.Name)
+	p.do(p.dir, func(ctx context.Context, rfs *remotefs.FS, report func(string)) error {
+		report(fmt.Sprintf("changing mode of %s", file))
+		return rfs.Chmod(file, fs.FileMode(mode))
+	})
+}
+
+func (p *Page) delete() {
+	e, ok := p.current()
+	if !ok {
+		return
+	}
+	file := path.Join(p.dir, e.Name)
+	p.do(p.dir, func(ctx context.Context, rfs *remotefs.FS, report func(string)) error {
+		report(fmt.Sprintf("deleting %s", file))
+		return rfs.Remove(file)
+	})
+}
+
+// upload copies the local file into the current directory.
+func (p *Page) upload() {
+	local := strings.TrimSpace(p.localInput.Text())
+	if len(local) == 0 {
+		p.fail("local file is required")
+		return
+	}
+	p.uploadTo(local, path.Join(p.dir, filepath.Base(local)), false)
+}
+
+func (p *Page) uploadTo(local, remote string, overwrite bool) {
+	p.selected = path.Base(remote)
+	p.overwrite = func() { p.uploadTo(local, remote, true) }
+	p.do(p.dir, func(ctx context.Context, rfs *remotefs.FS, report func(string)) error {
+		report(fmt.Sprintf("uploading %s", local))
+		return rfs.Upload(ctx, local, remote, overwrite, progress(report, "uploading", filepath.Base(local)))
+	})
+}
+
+// download copies the selected file to the local path, into it when it is
+// a directory.
+func (p *Page) download() {
+	e, ok := p.current()
+	if !ok || e.IsDir() {
+		p.fail("select a file to download")
+		return
+	}
+	local := strings.TrimSpace(p.localInput.Text())
+	if len(local) == 0 {
+		p.fail("local path is required")
+		return
+	}
+	remote := path.Join(p.dir, e.Name)
+	p.do(p.dir, func(ctx context.Context, rfs *remotefs.FS, report func(string)) error {
+		dst := local
+		if info, err := os.Stat(local); err == nil && info.IsDir() {
+			dst = filepath.Join(local, e.Name)
+		}
+		report(fmt.Sprintf("downloading %s", remote))
+		return rfs.Download(ctx, remote, dst, progress(report, "downloading", e.Name))
+	})
+}
+
+// progress reports a transfer each time its percentage changes.
+func progress(report func(string), verb, name string) remotefs.Progress {
+	last := -1
+	return func(done, total int64) {
+		percent := 100
+		if total > 0 {
+			percent = int(done * 100 / total)
+		}
+		if percent == last {
+			return
+		}
+		last = percent
+		report(fmt.Sprintf("%s %s %d%% (%s of %s)", verb, name, percent, common.FormatSize(done), common.FormatSize(total)))
+	}
+}
+
+func (p *Page) layoutButtons(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	if p.runner.Running() {
+		return common.Progress(gtx, th, p.runner, &p.cancelButton)
+	}
+	if p.session == nil {
+		return Button(gtx, 100, th, &p.connectButton, "connect")
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return Button(gtx, 100, th, &p.disconnectButton, "disconnect")
+		}),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(material.Body2(th, p.session.target).Layout),
+	)
+}
+
+// layoutTools draws the path and the inputs of the file operations.
+func (p *Page) layoutTools(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	row := func(gtx layout.Context, children ...layout.FlexChild) layout.Dimensions {
+		return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+		})
+	}
+	gap := layout.Rigid(layout.Spacer{Width: 10}.Layout)
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		// 当前目录
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return row(gtx,
+				layout.Rigid(material.Button(th, &p.upButton, "up").Layout),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.pathInput, "path, enter to open", 560)
+				}),
+				gap,
+				layout.Rigid(material.Button(th, &p.refreshButton, "refresh").Layout),
+			)
+		}),
+		// 文件操作
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return row(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.nameInput, "name", 240)
+				}),
+				gap,
+				layout.Rigid(material.Button(th, &p.mkdirButton, "mkdir").Layout),
+				gap,
+				layout.Rigid(material.Button(th, &p.renameButton, "rename").Layout),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.modeInput, "mode", 60)
+				}),
+				gap,
+				layout.Rigid(material.Button(th, &p.chmodButton, "chmod").Layout),
+				gap,
+				layout.Rigid(material.Button(th, &p.editButton, "edit").Layout),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					btn := material.Button(th, &p.deleteButton, "delete")
+					btn.Background = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+					return btn.Layout(gtx)
+				}),
+			)
+		}),
+		// 上传下载
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return row(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.localInput, "local file to upload, or directory to download to", 420)
+				}),
+				gap,
+				layout.Rigid(material.Button(th, &p.uploadButton, "upload").Layout),
+				gap,
+				layout.Rigid(material.Button(th, &p.downloadButton, "download").Layout),
+			)
+		}),
+	)
+}
+
+// cells lays out a table row with the column widths, the name column takes
+// the remaining space.
+func cells(gtx layout.Context, widgets ...layout.Widget) layout.Dimensions {
+	children := make([]layout.FlexChild, 0, len(widgets))
+	for i, w := range widgets {
+		width := columns[i].width
+		if width == 0 {
+			children = append(children, layout.Flexed(1, w))
+			continue
+		}
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(width)
+			gtx.Constraints.Max.X = gtx.Dp(width)
+			return w(gtx)
+		}))
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+}
+
+func (p *Page) layoutFiles(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		// 表头，点击排序
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			header := make([]layout.Widget, len(columns))
+			for i, c := range columns {
+				name := c.name
+				if i == p.sortBy {
+					name += " ▲"
+					if p.desc {
+						name = c.name + " ▼"
+					}
+				}
+				header[i] = func(gtx layout.Context) layout.Dimensions {
+					return material.Clickable(gtx, &p.headers[i], func(gtx layout.Context) layout.Dimensions {
+						lbl := material.Body2(th, name)
+						lbl.Font.Weight = font.Bold
+						return lbl.Layout(gtx)
+					})
+				}
+			}
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return cells(gtx, header...)
+			})
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if len(p.entries) == 0 {
+				return material.Body2(th, "empty directory").Layout(gtx)
+			}
+			return material.List(th, &p.list).Layout(gtx, len(p.entries), func(gtx layout.Context, i int) layout.Dimensions {
+				return p.layoutEntry(gtx, th, i)
+			})
+		}),
+	)
+}
+
+func (p *Page) layoutEntry(gtx layout.Context, th *material.Theme, i int) layout.Dimensions {
+	e := p.entries[i]
+	name := e.Name
+	size := common.FormatSize(e.Size)
+	if e.IsDir() {
+		name += "/"
+		size = "-"
+	}
+	owner := e.Owner
+	if len(e.Group) != 0 {
+		owner += ":" + e.Group
+	}
+	return material.Clickable(gtx, &p.rows[i], func(gtx layout.Context) layout.Dimensions {
+		// 选中的文件加背景色
+		return layout.Background{}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			if e.Name == p.selected {
+				paint.FillShape(gtx.Ops, selectedColor, clip.Rect{Max: gtx.Constraints.Min}.Op())
+			}
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}, func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				lbl := material.Body1(th, name)
+				lbl.MaxLines = 1
+				if e.IsDir() {
+					lbl.Font.Weight = font.Bold
+				}
+				return cells(gtx,
+					lbl.Layout,
+					material.Body2(th, size).Layout,
+					material.Body2(th, e.Mode.String()).Layout,
+					material.Body2(th, owner).Layout,
+					material.Body2(th, e.ModTime.Format("2006-01-02 15:04")).Layout,
+				)
+			})
+		})
+	})
+}
+
+func (p *Page) drawConfirmDialog(gtx layout.Context, th *material.Theme) {
+	// 全屏
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	full.Pop()
+
+	// 窗口大小和位置（居中）
+	boxW := min(gtx.Constraints.Max.X-80, 420)
+	boxH := 150
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	// 窗口背景（白色矩形）
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	// 将坐标系偏移道对话框左上角，然后在内部做正常布局
+	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(material.Body1(th, p.confirmMsg).Layout),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if p.modalButton.Clicked(gtx) {
+					p.showDialog = false
+					if confirmed := p.confirmed; confirmed != nil {
+						p.confirmed = nil
+						confirmed()
+					}
+				}
+				if p.noButton.Clicked(gtx) {
+					p.showDialog = false
+					p.confirmed = nil
+				}
+				children := []layout.FlexChild{
+					layout.Rigid(material.Button(th, &p.modalButton, "confirm").Layout),
+				}
+				if p.confirmed != nil {
+					children = append(children,
+						layout.Rigid(layout.Spacer{Width: unit.Dp(10)}.Layout),
+						layout.Rigid(material.Button(th, &p.noButton, "cancel").Layout),
+					)
+				}
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+			}),
+		)
+	})
+	offset.Pop()
+}
+
+func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.Clickable, txt string) layout.Dimensions {
+	gtx.Constraints.Min.X = gtx.Dp(width)
+	gtx.Constraints.Max.X = gtx.Dp(width)
+	return material.Button(th, wid, txt).Layout(gtx)
+}
@@ -0,0 +1,371 @@
+// Package fstools creates filesystems on partitions, mounts them and
+// manages their fstab entries for the pages that show partitions.
+package fstools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"path"
+	"strings"
+	page "tools/pages"
+	"tools/pages/common"
+	"tools/pages/connform"
+	"tools/sshclient"
+	"tools/utils"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+var (
+	okColor   = color.NRGBA{R: 30, G: 140, B: 60, A: 255}
+	warnColor = color.NRGBA{R: 220, G: 140, B: 0, A: 255}
+	grayColor = color.NRGBA{R: 140, G: 140, B: 140, A: 255}
+)
+
+// Operation is a change confirmed by the user. The device is checked again
+// right before Cmds run, Check returns why it must not be changed. An
+// operation without Device is not checked.
+type Operation struct {
+	Device string
+	Check  func(dev *utils.BlockDevice) string
+	Cmds   []string
+}
+
+// Apply checks the device of op again, it may have been mounted since it
+// was listed, and runs the commands as root.
+func Apply(ctx context.Context, r *page.Router, client *sshclient.Client, sudo *sshclient.Sudo, target string, op *Operation, report func(string)) error {
+	if len(op.Device) != 0 {
+		report(fmt.Sprintf("checking %s", op.Device))
+		output, err := client.Run(ctx, utils.Lsblk+" "+sshclient.Quote(op.Device))
+		if err != nil {
+			return err
+		}
+		devices, err := utils.GetBlockDevices(output)
+		if err != nil {
+			return err
+		}
+		if len(devices) != 1 {
+			return fmt.Errorf("%s not found", op.Device)
+		}
+		if reason := op.Check(&devices[0]); len(reason) != 0 {
+			return fmt.Errorf("refusing to change %s, %s", op.Device, reason)
+		}
+	}
+	for _, cmd := range op.Cmds {
+		report(fmt.Sprintf("running %s", cmd))
+		if _, err := r.SudoRun(ctx, client, sudo, target, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFstab reads the fstab of the host, a missing fstab is empty.
+func ReadFstab(ctx context.Context, client *sshclient.Client) []utils.FstabEntry {
+	if res, err := client.Exec(ctx, utils.ReadFstab); err == nil && !res.Failed() {
+		return utils.ParseFstab(res.Stdout)
+	}
+	return nil
+}
+
+// Tools is the form that creates, mounts and unmounts the filesystem of a
+// partition and adds it to the fstab, with the fstab entries below it.
+type Tools struct {
+	fstype widget.Enum
+	// options 是每种文件系统的可选参数，与 utils.FSTypes 对应
+	options        [][]widget.Bool
+	labelInput     widget.Editor
+	extraInput     widget.Editor
+	formatButton   widget.Clickable
+	dirInput       widget.Editor
+	mountOptsInput widget.Editor
+	mountButton    widget.Clickable
+	umountButton   widget.Clickable
+	fstabButton    widget.Clickable
+
+	fstab         []utils.FstabEntry
+	removeButtons []widget.Clickable
+	fstabList     widget.List
+}
+
+func New() *Tools {
+	t := &Tools{}
+	t.fstype.Value = utils.FSTypes[0].Name
+	t.options = make([][]widget.Bool, len(utils.FSTypes))
+	for i, fs := range utils.FSTypes {
+		t.options[i] = make([]widget.Bool, len(fs.Options))
+	}
+	t.labelInput.SingleLine = true
+	t.extraInput.SingleLine = true
+	t.dirInput.SingleLine = true
+	t.mountOptsInput.SingleLine = true
+	t.fstabList.Axis = layout.Vertical
+	return t
+}
+
+// SetFstab replaces the listed fstab entries.
+func (t *Tools) SetFstab(entries []utils.FstabEntry) {
+	t.fstab = entries
+	t.removeButtons = make([]widget.Clickable, len(entries))
+}
+
+// Select fills in the mountpoint of a newly selected partition.
+func (t *Tools) Select(dev *utils.BlockDevice) {
+	if dev != nil && len(dev.MountPoint) != 0 {
+		t.dirInput.SetText(dev.MountPoint)
+	}
+}
+
+// Update handles the buttons, dev is the selected partition or nil. It
+// returns the operation the user asked for with the question to confirm it,
+// or why it cannot be run.
+func (t *Tools) Update(gtx layout.Context, dev *utils.BlockDevice) (string, *Operation, error) {
+	for i := range t.removeButtons {
+		if t.removeButtons[i].Clicked(gtx) {
+			e := t.fstab[i]
+			op := &Operation{Cmds: []string{utils.FstabRemoveCmd(e)}}
+			return fmt.Sprintf("remove the entry for %s from %s? A backup is kept in %s.bak.", e.File, utils.Fstab, utils.Fstab), op, nil
+		}
+	}
+	actions := []struct {
+		btn    *widget.Clickable
+		action func(dev *utils.BlockDevice) (string, *Operation, error)
+	}{
+		{&t.formatButton, t.mkfs},
+		{&t.mountButton, t.mount},
+		{&t.umountButton, t.umount},
+		{&t.fstabButton, t.fstabAdd},
+	}
+	for _, a := range actions {
+		if !a.btn.Clicked(gtx) {
+			continue
+		}
+		if dev == nil {
+			return "", nil, errors.New("partition not found, load the disks again after creating it")
+		}
+		warning, op, err := a.action(dev)
+		if err != nil {
+			return "", nil, err
+		}
+		if reason := op.Check(dev); len(reason) != 0 {
+			return "", nil, fmt.Errorf("refusing to change %s, %s", dev.Name, reason)
+		}
+		return warning, op, nil
+	}
+	return "", nil, nil
+}
+
+func (t *Tools) mkfs(dev *utils.BlockDevice) (string, *Operation, error) {
+	var fstype utils.FSType
+	var options []utils.MkfsOption
+	for i, fs := range utils.FSTypes {
+		if fs.Name != t.fstype.Value {
+			continue
+		}
+		fstype = fs
+		for j, o := range fs.Options {
+			if t.options[i][j].Value {
+				options = append(options, o)
+			}
+		}
+	}
+	cmd, err := utils.MkfsCmd(fstype, dev.Name, strings.TrimSpace(t.labelInput.Text()), options, t.extraInput.Text())
+	if err != nil {
+		return "", nil, err
+	}
+	warning := fmt.Sprintf("create a %s filesystem on %s?", fstype.Name, dev.Name)
+	if len(dev.FSType) != 0 {
+		warning += fmt.Sprintf(" The %s filesystem on it and all its data are lost.", dev.FSType)
+	}
+	return warning, &Operation{Device: dev.Name, Check: (*utils.BlockDevice).Busy, Cmds: []string{cmd}}, nil
+}
+
+func (t *Tools) mount(dev *utils.BlockDevice) (string, *Operation, error) {
+	dir := path.Clean(strings.TrimSpace(t.dirInput.Text()))
+	cmd, err := utils.MountCmd(dev.Name, dir, strings.TrimSpace(t.mountOptsInput.Text()))
+	if err != nil {
+		return "", nil, err
+	}
+	check := func(dev *utils.BlockDevice) string {
+		if len(dev.FSType) == 0 {
+			return "it has no filesystem"
+		}
+		if len(dev.MountPoint) != 0 {
+			return fmt.Sprintf("it is already mounted on %s", dev.MountPoint)
+		}
+		return ""
+	}
+	return fmt.Sprintf("mount %s on %s?", dev.Name, dir), &Operation{Device: dev.Name, Check: check, Cmds: []string{cmd}}, nil
+}
+
+func (t *Tools) umount(dev *utils.BlockDevice) (string, *Operation, error) {
+	check := func(dev *utils.BlockDevice) string {
+		if dev.IsRootDisk() {
+			return "it holds the root filesystem"
+		}
+		if len(dev.MountPoint) == 0 {
+			return "it is not mounted"
+		}
+		return ""
+	}
+	return fmt.Sprintf("unmount %s from %s?", dev.Name, dev.MountPoint), &Operation{Device: dev.Name, Check: check, Cmds: []string{utils.UmountCmd(dev.Name)}}, nil
+}
+
+func (t *Tools) fstabAdd(dev *utils.BlockDevice) (string, *Operation, error) {
+	line, err := utils.FstabLine(dev, strings.TrimSpace(t.dirInput.Text()), strings.TrimSpace(t.mountOptsInput.Text()))
+	if err != nil {
+		return "", nil, err
+	}
+	cmd, err := utils.FstabAddCmd(t.fstab, line)
+	if err != nil {
+		return "", nil, err
+	}
+	// 执行前确认 UUID 没有变化，例如期间重新创建了文件系统
+	uuid := dev.UUID
+	check := func(dev *utils.BlockDevice) string {
+		if dev.UUID != uuid {
+			return fmt.Sprintf("its UUID changed to %q, load the disks again", dev.UUID)
+		}
+		return ""
+	}
+	return fmt.Sprintf("add %s to %s? A backup is kept in %s.bak.", dev.Name, utils.Fstab, utils.Fstab), &Operation{Device: dev.Name, Check: check, Cmds: []string{cmd}}, nil
+}
+
+// Layout draws the filesystem operations of the partition dev.
+func (t *Tools) Layout(gtx layout.Context, th *material.Theme, dev *utils.BlockDevice) layout.Dimensions {
+	row := func(gtx layout.Context, children ...layout.FlexChild) layout.Dimensions {
+		return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+		})
+	}
+	gap := layout.Rigid(layout.Spacer{Width: 10}.Layout)
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		// 当前文件系统
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			desc := fmt.Sprintf("%s  filesystem: %s  UUID: %s", dev.Name, or(dev.FSType, "none"), or(dev.UUID, "-"))
+			if len(dev.MountPoint) != 0 {
+				desc += "  mounted on " + dev.MountPoint
+			}
+			lbl := material.Body2(th, desc)
+			lbl.Font.Weight = font.Bold
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, lbl.Layout)
+		}),
+		// 创建文件系统
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			children := []layout.FlexChild{}
+			for i, fs := range utils.FSTypes {
+				children = append(children, layout.Rigid(material.RadioButton(th, &t.fstype, fs.Name, fs.Name).Layout))
+				if fs.Name != t.fstype.Value {
+					continue
+				}
+				for j, o := range fs.Options {
+					children = append(children, layout.Rigid(material.CheckBox(th, &t.options[i][j], o.Name).Layout))
+				}
+			}
+			return row(gtx, children...)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return row(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &t.labelInput, "label", 160)
+				}),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &t.extraInput, "more mkfs options", 260)
+				}),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					b := material.Button(th, &t.formatButton, "create filesystem")
+					b.Background = common.DangerColor
+					return b.Layout(gtx)
+				}),
+			)
+		}),
+		// 挂载和 fstab
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return row(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &t.dirInput, "mountpoint", 220)
+				}),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &t.mountOptsInput, "options, e.g. noatime", 200)
+				}),
+				gap,
+				layout.Rigid(material.Button(th, &t.mountButton, "mount").Layout),
+				gap,
+				layout.Rigid(material.Button(th, &t.umountButton, "unmount").Layout),
+				gap,
+				layout.Rigid(material.Button(th, &t.fstabButton, "add to fstab").Layout),
+			)
+		}),
+	)
+}
+
+// LayoutFstab lists the fstab entries with the result of checking them
+// against devices.
+func (t *Tools) LayoutFstab(gtx layout.Context, th *material.Theme, devices []utils.BlockDevice) layout.Dimensions {
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			lbl := material.Body1(th, utils.Fstab)
+			lbl.Font.Weight = font.Bold
+			return layout.Inset{Top: unit.Dp(10), Bottom: unit.Dp(5)}.Layout(gtx, lbl.Layout)
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return material.List(th, &t.fstabList).Layout(gtx, len(t.fstab), func(gtx layout.Context, i int) layout.Dimensions {
+				e := t.fstab[i]
+				status, detail := e.Check(devices)
+				var statusText string
+				var statusColor color.NRGBA
+				switch status {
+				case utils.FstabOK:
+					statusText, statusColor = "ok "+detail, okColor
+				case utils.FstabMissing:
+					statusText, statusColor = detail, common.DangerColor
+				case utils.FstabMismatch:
+					statusText, statusColor = detail, warnColor
+				default:
+					statusText, statusColor = "not checked", grayColor
+				}
+				return layout.Inset{Bottom: unit.Dp(2)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+						layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+							lbl := material.Body2(th, strings.Join(strings.Fields(e.Line), "  "))
+							lbl.Font = common.MonoFont
+							lbl.MaxLines = 1
+							return lbl.Layout(gtx)
+						}),
+						layout.Rigid(layout.Spacer{Width: 10}.Layout),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							gtx.Constraints.Min.X = gtx.Dp(260)
+							gtx.Constraints.Max.X = gtx.Dp(260)
+							lbl := material.Body2(th, statusText)
+							lbl.Color = statusColor
+							lbl.MaxLines = 1
+							return lbl.Layout(gtx)
+						}),
+						layout.Rigid(layout.Spacer{Width: 10}.Layout),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							gtx.Constraints.Min.X = gtx.Dp(80)
+							gtx.Constraints.Max.X = gtx.Dp(80)
+							return material.Button(th, &t.removeButtons[i], "remove").Layout(gtx)
+						}),
+					)
+				})
+			})
+		}),
+	)
+}
+
+func or(s, def string) string {
+	if len(s) == 0 {
+		return def
+	}
+	return s
+}
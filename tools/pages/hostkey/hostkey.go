@@ -0,0 +1,146 @@
+package hostkey
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"golang.org/x/crypto/ssh"
+)
+
+type request struct {
+	host        string
+	keyType     string
+	fingerprint string
+	answer      chan bool
+}
+
+// Prompter asks the user to trust unknown host keys. Confirm is called from
+// background jobs and blocks, Layout draws the pending question on top of
+// whatever page is shown.
+type Prompter struct {
+	invalidate   func()
+	mu           sync.Mutex
+	queue        []*request
+	acceptButton widget.Clickable
+	rejectButton widget.Clickable
+}
+
+func New(invalidate func()) *Prompter {
+	return &Prompter{invalidate: invalidate}
+}
+
+// Confirm implements sshclient.HostKeyPrompt.
+func (p *Prompter) Confirm(ctx context.Context, host string, key ssh.PublicKey) bool {
+	req := &request{
+		host:        host,
+		keyType:     key.Type(),
+		fingerprint: ssh.FingerprintSHA256(key),
+		answer:      make(chan bool, 1),
+	}
+	p.mu.Lock()
+	p.queue = append(p.queue, req)
+	p.mu.Unlock()
+	p.invalidate()
+
+	select {
+	case ok := <-req.answer:
+		return ok
+	case <-ctx.Done():
+		p.remove(req)
+		return false
+	}
+}
+
+func (p *Prompter) remove(req *request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, r := range p.queue {
+		if r == req {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			break
+		}
+	}
+	p.invalidate()
+}
+
+func (p *Prompter) current() *request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 {
+		return nil
+	}
+	return p.queue[0]
+}
+
+func (p *Prompter) Layout(gtx layout.Context, th *material.Theme) {
+	req := p.current()
+	if req == nil {
+		return
+	}
+	if p.acceptButton.Clicked(gtx) {
+		req.answer <- true
+		p.remove(req)
+		return
+	}
+	if p.rejectButton.Clicked(gtx) {
+		req.answer <- false
+		p.remove(req)
+		return
+	}
+
+	// 全屏遮罩，同时拦截下层页面的点击
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	event.Op(gtx.Ops, p)
+	for {
+		if _, ok := gtx.Event(pointer.Filter{Target: p, Kinds: pointer.Press | pointer.Release}); !ok {
+			break
+		}
+	}
+	full.Pop()
+
+	// 窗口大小和位置（居中）
+	boxW := min(gtx.Constraints.Max.X-80, 560)
+	boxH := 220
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	offset := op.Offset(rect.Min).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	msg := fmt.Sprintf("The authenticity of host %s can't be established.\n%s key fingerprint is %s.\n"+
+		"Accept the key and add it to known_hosts?", req.host, req.keyType, req.fingerprint)
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(material.Body1(th, msg).Layout),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+					layout.Rigid(material.Button(th, &p.acceptButton, "accept").Layout),
+					layout.Rigid(layout.Spacer{Width: unit.Dp(10)}.Layout),
+					layout.Rigid(material.Button(th, &p.rejectButton, "reject").Layout),
+				)
+			}),
+		)
+	})
+	offset.Pop()
+}
@@ -0,0 +1,351 @@
+package hosts
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"tools/icon"
+	"tools/inventory"
+	page "tools/pages"
+	"tools/pages/connform"
+	"tools/sshclient"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+// Page edits the saved host inventory used by the host pickers.
+type Page struct {
+	nameInput     widget.Editor
+	addressInput  widget.Editor
+	portInput     widget.Editor
+	usernameInput widget.Editor
+	keyFileInput  widget.Editor
+	jumpInput     widget.Editor
+	tagsInput     widget.Editor
+	groupsInput   widget.Editor
+	filterInput   widget.Editor
+	authMethod    widget.Enum
+	newButton     widget.Clickable
+	saveButton    widget.Clickable
+	deleteButton  widget.Clickable
+	modalButton   widget.Clickable
+	cancelButton  widget.Clickable
+	hostList      widget.List
+	hostButtons   []widget.Clickable
+	showDialog    bool
+	confirmDelete bool
+	confirmMsg    string
+	// editing 是正在编辑的主机名，新建时为空
+	editing string
+	*page.Router
+}
+
+func New(router *page.Router) *Page {
+	page := &Page{
+		Router: router,
+	}
+	for _, e := range []*widget.Editor{
+		&page.nameInput, &page.addressInput, &page.portInput, &page.usernameInput,
+		&page.keyFileInput, &page.jumpInput, &page.tagsInput, &page.groupsInput, &page.filterInput,
+	} {
+		e.SingleLine = true
+	}
+	page.portInput.Filter = "0123456789"
+	page.authMethod.Value = string(sshclient.AuthPassword)
+	page.hostList.Axis = layout.Vertical
+	return page
+}
+
+var _ page.Page = &Page{}
+
+func (p *Page) Actions() []component.AppBarAction {
+	return []component.AppBarAction{}
+}
+
+func (p *Page) Overflow() []component.OverflowAction {
+	return []component.OverflowAction{}
+}
+
+func (p *Page) NavItem() component.NavItem {
+	return component.NavItem{
+		Name: "Hosts",
+		Icon: icon.HostsIcon,
+	}
+}
+
+func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	mainPage := layout.Flex{
+		Axis: layout.Horizontal,
+	}
+	mainPage.Layout(gtx,
+		// 主机列表
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(480)
+			gtx.Constraints.Max.X = gtx.Dp(480)
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutHosts(gtx, th)
+			})
+		}),
+		// 编辑表单
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutForm(gtx, th)
+			})
+		}),
+	)
+
+	// 弹出对话框
+	if p.showDialog {
+		p.drawConfirmDialog(gtx, th)
+	}
+
+	return mainPage.Layout(gtx)
+}
+
+func (p *Page) hosts() []inventory.Host {
+	if p.Router.Inventory == nil {
+		return nil
+	}
+	hosts := make([]inventory.Host, 0)
+	for _, h := range p.Router.Inventory.Hosts() {
+		if h.Match(p.filterInput.Text()) {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+func (p *Page) layoutHosts(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	hosts := p.hosts()
+	if len(p.hostButtons) < len(hosts) {
+		p.hostButtons = append(p.hostButtons, make([]widget.Clickable, len(hosts)-len(p.hostButtons))...)
+	}
+	for i, h := range hosts {
+		if p.hostButtons[i].Clicked(gtx) {
+			p.edit(h)
+		}
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return connform.Input(gtx, th, &p.filterInput, "filter by name, address, tag or group", 440)
+		}),
+		layout.Rigid(layout.Spacer{Height: 5}.Layout),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return material.List(th, &p.hostList).Layout(gtx, len(hosts), func(gtx layout.Context, i int) layout.Dimensions {
+				return material.Clickable(gtx, &p.hostButtons[i], func(gtx layout.Context) layout.Dimensions {
+					// 高亮正在编辑的主机
+					if hosts[i].Name == p.editing {
+						rect := clip.Rect{Max: image.Pt(gtx.Constraints.Max.X, gtx.Dp(30))}.Push(gtx.Ops)
+						paint.Fill(gtx.Ops, color.NRGBA{R: 230, G: 236, B: 250, A: 255})
+						rect.Pop()
+					}
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						return connform.HostLabel(gtx, th, hosts[i])
+					})
+				})
+			})
+		}),
+	)
+}
+
+func (p *Page) layoutForm(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	if p.newButton.Clicked(gtx) {
+		p.edit(inventory.Host{})
+	}
+	if p.saveButton.Clicked(gtx) {
+		p.save()
+	}
+	if p.deleteButton.Clicked(gtx) && len(p.editing) != 0 {
+		p.confirmMsg = "delete host " + p.editing + "?"
+		p.confirmDelete = true
+		p.showDialog = true
+	}
+
+	title := "New host"
+	if len(p.editing) != 0 {
+		title = "Edit " + p.editing
+	}
+	field := func(label string, editor *widget.Editor, hint string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						gtx.Constraints.Min.X = gtx.Dp(100)
+						return material.Body1(th, label).Layout(gtx)
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return connform.Input(gtx, th, editor, hint, 360)
+					}),
+				)
+			})
+		})
+	}
+	authChildren := make([]layout.FlexChild, 0, len(sshclient.AuthMethods)+1)
+	authChildren = append(authChildren, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Min.X = gtx.Dp(100)
+		return material.Body1(th, "Auth").Layout(gtx)
+	}))
+	for _, m := range sshclient.AuthMethods {
+		authChildren = append(authChildren, layout.Rigid(
+			material.RadioButton(th, &p.authMethod, string(m), string(m)).Layout,
+		))
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			lbl := material.H6(th, title)
+			lbl.Font.Weight = font.Bold
+			return layout.Inset{Bottom: unit.Dp(10)}.Layout(gtx, lbl.Layout)
+		}),
+		field("Name", &p.nameInput, "unique name, e.g. storage-01"),
+		field("Address", &p.addressInput, "ip address or host name"),
+		field("Port", &p.portInput, "22"),
+		field("User", &p.usernameInput, "login user name"),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, authChildren...)
+			})
+		}),
+		field("Key file", &p.keyFileInput, "private key file for publickey auth"),
+		field("Jump", &p.jumpInput, "saved host or user@host:port, comma separated"),
+		field("Groups", &p.groupsInput, "comma separated, e.g. rack-a, ceph"),
+		field("Tags", &p.tagsInput, "comma separated, e.g. nvme, prod"),
+		layout.Rigid(layout.Spacer{Height: 10}.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			children := []layout.FlexChild{
+				layout.Rigid(material.Button(th, &p.saveButton, "save").Layout),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(material.Button(th, &p.newButton, "new").Layout),
+			}
+			if len(p.editing) != 0 {
+				children = append(children,
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						btn := material.Button(th, &p.deleteButton, "delete")
+						btn.Background = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+						return btn.Layout(gtx)
+					}),
+				)
+			}
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+		}),
+	)
+}
+
+// edit loads h into the form, an empty host starts a new entry.
+func (p *Page) edit(h inventory.Host) {
+	p.editing = h.Name
+	p.nameInput.SetText(h.Name)
+	p.addressInput.SetText(h.Address)
+	p.portInput.SetText("")
+	if h.Port != 0 {
+		p.portInput.SetText(strconv.Itoa(h.Port))
+	}
+	p.usernameInput.SetText(h.User)
+	p.keyFileInput.SetText(h.KeyFile)
+	p.jumpInput.SetText(h.ProxyJump)
+	p.groupsInput.SetText(strings.Join(h.Groups, ", "))
+	p.tagsInput.SetText(strings.Join(h.Tags, ", "))
+	p.authMethod.Value = string(sshclient.AuthPassword)
+	if len(h.Auth) != 0 {
+		p.authMethod.Value = string(h.Auth)
+	}
+}
+
+func (p *Page) save() {
+	port := 0
+	if txt := p.portInput.Text(); len(txt) != 0 {
+		port, _ = strconv.Atoi(txt)
+	}
+	h := inventory.Host{
+		Name:      strings.TrimSpace(p.nameInput.Text()),
+		Address:   strings.TrimSpace(p.addressInput.Text()),
+		Port:      port,
+		User:      strings.TrimSpace(p.usernameInput.Text()),
+		Auth:      sshclient.AuthMethod(p.authMethod.Value),
+		KeyFile:   strings.TrimSpace(p.keyFileInput.Text()),
+		ProxyJump: strings.Join(inventory.SplitList(p.jumpInput.Text()), ","),
+		Groups:    inventory.SplitList(p.groupsInput.Text()),
+		Tags:      inventory.SplitList(p.tagsInput.Text()),
+	}
+	if err := p.Router.Inventory.Put(p.editing, h); err != nil {
+		p.confirmMsg = err.Error()
+		p.showDialog = true
+		return
+	}
+	p.editing = h.Name
+}
+
+func (p *Page) delete() {
+	if err := p.Router.Inventory.Delete(p.editing); err != nil {
+		p.confirmMsg = err.Error()
+		p.showDialog = true
+		return
+	}
+	p.edit(inventory.Host{})
+}
+
+func (p *Page) drawConfirmDialog(gtx layout.Context, th *material.Theme) {
+	// 全屏
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	full.Pop()
+
+	// 窗口大小和位置（居中）
+	boxW := min(gtx.Constraints.Max.X-80, 420)
+	boxH := 150
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	// 窗口背景（白色矩形）
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	// 将坐标系偏移道对话框左上角，然后在内部做正常布局
+	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(material.Body1(th, p.confirmMsg).Layout),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if p.modalButton.Clicked(gtx) {
+					p.showDialog = false
+					if p.confirmDelete {
+						p.confirmDelete = false
+						p.delete()
+					}
+				}
+				if p.cancelButton.Clicked(gtx) {
+					p.showDialog = false
+					p.confirmDelete = false
+				}
+				children := []layout.FlexChild{
+					layout.Rigid(material.Button(th, &p.modalButton, "confirm").Layout),
+				}
+				if p.confirmDelete {
+					children = append(children,
+						layout.Rigid(layout.Spacer{Width: unit.Dp(10)}.Layout),
+						layout.Rigid(material.Button(th, &p.cancelButton, "cancel").Layout),
+					)
+				}
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+			}),
+		)
+	})
+	offset.Pop()
+}
@@ -1,18 +1,22 @@
 package listdisks
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"image"
 	"image/color"
 	"strconv"
-	"strings"
 	"tools/icon"
+	"tools/job"
 	page "tools/pages"
+	"tools/pages/common"
+	"tools/pages/connform"
+	"tools/pages/fstools"
+	"tools/sshclient"
 	"tools/utils"
 
 	"gioui.org/font"
 	"gioui.org/layout"
-	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/text"
@@ -20,29 +24,43 @@ import (
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 	"gioui.org/x/component"
-	"golang.org/x/crypto/ssh"
 )
 
+// listing is the result of a job: the disks and the fstab.
+type listing struct {
+	devices []utils.BlockDevice
+	fstab   []utils.FstabEntry
+	// password 是 sudo 接受的密码，下次执行时先用它
+	password string
+}
+
 type Page struct {
-	remoteIpInput widget.Editor
-	usernameInput widget.Editor
-	passwordInput widget.Editor
-	execButton    widget.Clickable
-	modalButton   widget.Clickable
-	showDialog    bool
-	confirmMsg    string
-	resultEditor  widget.Editor
-	devices       []utils.BlockDevice
+	connForm     *connform.Form
+	execButton   widget.Clickable
+	cancelButton widget.Clickable
+	dialog       common.Dialog
+	resultEditor widget.Editor
+	devices      []utils.BlockDevice
+	runner       *job.Runner[*listing]
+	// selected 是选中的分区，partClicks 是每个分区行的按钮
+	selected     string
+	partClicks   map[string]*widget.Clickable
+	fs           *fstools.Tools
+	sudoPassword string
 	*page.Router
 }
 
+// selectedColor 标记选中的分区行
+var selectedColor = color.NRGBA{R: 63, G: 81, B: 181, A: 40}
+
 func New(router *page.Router) *Page {
 	page := &Page{
-		Router: router,
+		Router:     router,
+		runner:     job.NewRunner[*listing](router.Invalidate),
+		partClicks: make(map[string]*widget.Clickable),
+		fs:         fstools.New(),
 	}
-	page.remoteIpInput.SingleLine = true
-	page.usernameInput.SingleLine = true
-	page.passwordInput.SingleLine = true
+	page.connForm = connform.New(router)
 	page.resultEditor.ReadOnly = true
 	page.resultEditor.WrapPolicy = text.WrapGraphemes
 	return page
@@ -66,202 +84,162 @@ func (p *Page) NavItem() component.NavItem {
 }
 
 func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	// 后台任务结束后在 UI 协程中处理结果
+	if res, ok := p.runner.Poll(); ok {
+		p.handleResult(res)
+	}
+	p.update(gtx)
+
 	mainPage := layout.Flex{
 		Axis:      layout.Vertical,
 		Alignment: layout.Middle,
 	}
 	mainPage.Layout(gtx,
-		layout.Rigid(
-			func(gtx layout.Context) layout.Dimensions {
-				return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-					return layout.Flex{
-						Axis:      layout.Horizontal,
-						Alignment: layout.Middle,
-						Spacing:   layout.SpaceSides,
-					}.Layout(gtx,
-						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							return widget.Border{
-								Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
-								Width: unit.Dp(1),
-							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								return layout.Inset{
-									Top:    unit.Dp(5),
-									Bottom: unit.Dp(5),
-									Left:   unit.Dp(5),
-								}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-									gtx.Constraints.Min.X = gtx.Dp(280)
-									gtx.Constraints.Max.X = gtx.Dp(280)
-									return material.Editor(th, &p.remoteIpInput, "remote ip address").Layout(gtx)
-								})
-							})
-						}),
-						layout.Rigid(layout.Spacer{Width: 10}.Layout),
-						// 用户名输入框
-						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							return widget.Border{
-								Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
-								Width: unit.Dp(1),
-							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								return layout.Inset{
-									Top:    unit.Dp(5),
-									Bottom: unit.Dp(5),
-									Left:   unit.Dp(5),
-								}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-									gtx.Constraints.Min.X = gtx.Dp(200)
-									gtx.Constraints.Max.X = gtx.Dp(200)
-									return material.Editor(th, &p.usernameInput, "user name").Layout(gtx)
-								})
-							})
-						}),
-						layout.Rigid(layout.Spacer{Width: 10}.Layout),
-						// 密码输入框
-						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							return widget.Border{
-								Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
-								Width: unit.Dp(1),
-							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								return layout.Inset{
-									Top:    unit.Dp(5),
-									Bottom: unit.Dp(5),
-									Left:   unit.Dp(5),
-								}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-									gtx.Constraints.Min.X = gtx.Dp(200)
-									gtx.Constraints.Max.X = gtx.Dp(200)
-									p.passwordInput.Mask = '*'
-									return material.Editor(th, &p.passwordInput, "password").Layout(gtx)
-								})
-							})
-						}),
-					)
-				})
-			},
-		),
+		// 连接参数
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.connForm.Layout(gtx, th)
+		}),
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			// 点击按钮逻辑
-			if p.execButton.Clicked(gtx) {
-				p.checkInput()
-				if !p.showDialog {
-					p.executeCmd()
-				}
+			if p.execButton.Clicked(gtx) && !p.runner.Running() {
+				p.executeCmd(nil)
+			}
+			if p.cancelButton.Clicked(gtx) {
+				p.runner.Cancel()
+			}
+			if p.runner.Running() {
+				return common.Progress(gtx, th, p.runner, &p.cancelButton)
 			}
 			return Button(gtx, 80, th, &p.execButton, "execute")
 		}),
+		// 选中分区的文件系统操作
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			dev, ok := utils.FindDevice(p.devices, p.selected)
+			if !ok {
+				return layout.Dimensions{}
+			}
+			return layout.Inset{Left: unit.Dp(8), Right: unit.Dp(8), Top: unit.Dp(8)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.fs.Layout(gtx, th, dev)
+			})
+		}),
 		// 结果显示区域（占满剩余空间）
-		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+		layout.Flexed(2, func(gtx layout.Context) layout.Dimensions {
 			in := layout.UniformInset(unit.Dp(8))
 			return in.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 				return p.drawTable(gtx, th)
 			})
 		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if len(p.devices) == 0 {
+				return layout.Dimensions{}
+			}
+			return layout.Inset{Left: unit.Dp(8), Right: unit.Dp(8)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.fs.LayoutFstab(gtx, th, p.devices)
+			})
+		}),
 	)
 
 	// 弹出对话框
-	if p.showDialog {
-		p.drawConfirmDialog(gtx, th)
-	}
+	p.dialog.Layout(gtx, th)
 
 	return mainPage.Layout(gtx)
 }
 
-func (p *Page) checkInput() {
-	itemName := ""
-	switch {
-	case p.remoteIpInput.Text() == "":
-		itemName = "ip address"
-	case p.usernameInput.Text() == "":
-		itemName = "login user name"
-	case p.passwordInput.Text() == "":
-		itemName = "login user password"
+// update handles the partition rows and the filesystem operations.
+func (p *Page) update(gtx layout.Context) {
+	for name, btn := range p.partClicks {
+		if btn.Clicked(gtx) {
+			p.selected = name
+			dev, _ := utils.FindDevice(p.devices, name)
+			p.fs.Select(dev)
+		}
 	}
-	if len(itemName) != 0 {
-		p.confirmMsg = fmt.Sprintf("%s is required", itemName)
-		p.showDialog = true
+	if p.runner.Running() || len(p.devices) == 0 {
+		return
+	}
+	dev, _ := utils.FindDevice(p.devices, p.selected)
+	warning, op, err := p.fs.Update(gtx, dev)
+	switch {
+	case err != nil:
+		p.dialog.Fail(err.Error())
+	case op != nil:
+		p.dialog.Confirm(warning, op.Cmds, func() { p.executeCmd(op) })
 	}
 }
 
-func (p *Page) drawConfirmDialog(gtx layout.Context, th *material.Theme) {
-	// 全屏
-	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
-	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
-	full.Pop()
-
-	// 窗口大小和位置（居中）
-	boxW := min(gtx.Constraints.Max.X-80, 420)
-	boxH := 150
-	cx := gtx.Constraints.Max.X / 2
-	cy := gtx.Constraints.Max.Y / 2
-	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
-
-	// 窗口背景（白色矩形）
-	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
-	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
-	box.Pop()
-
-	// 将坐标系偏移道对话框左上角，然后在内部做正常布局
-	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
-	inner := gtx
-	inner.Constraints.Min = image.Pt(boxW, 0)
-	inner.Constraints.Max = image.Pt(boxW, boxH)
-
-	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
-		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-			layout.Rigid(material.Body1(th, p.confirmMsg).Layout),
-			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
-			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-				btn := material.Button(th, &p.modalButton, "confirm")
-				// 注意：Clicked() 无参
-				if p.modalButton.Clicked(gtx) {
-					p.showDialog = false
-				}
-				return btn.Layout(gtx)
-			}),
-		)
-	})
-	offset.Pop()
-}
-
-func (p *Page) executeCmd() {
-
-	config := &ssh.ClientConfig{
-		User: p.usernameInput.Text(),
-		Auth: []ssh.AuthMethod{
-			ssh.Password(p.passwordInput.Text()),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
-	host := p.remoteIpInput.Text()
-	if len(strings.Split(host, ":")) == 1 {
-		host += ":22"
-	}
-	conn, err := ssh.Dial("tcp", host, config)
-	if err != nil {
-		p.confirmMsg = fmt.Sprintf("dail %s failed, %v", host, err)
-		p.showDialog = true
+// executeCmd lists the disks, after running op when it is not nil.
+func (p *Page) executeCmd(op *fstools.Operation) {
+	if itemName := p.connForm.Missing(); len(itemName) != 0 {
+		p.dialog.Fail(fmt.Sprintf("%s is required", itemName))
 		return
 	}
-	defer conn.Close()
-
-	session, err := conn.NewSession()
+	// 编辑框只能在 UI 协程中读取，先取出参数再交给后台任务
+	cfg, err := p.connForm.Config()
 	if err != nil {
-		p.confirmMsg = fmt.Sprintf("create session failed, %v", err)
-		p.showDialog = true
+		p.dialog.Fail(err.Error())
 		return
 	}
-	defer session.Close()
+	target := p.connForm.Target()
+	password := p.sudoPassword
+	if len(password) == 0 {
+		password = cfg.Password
+	}
+	p.runner.Start(func(ctx context.Context, report func(string)) (*listing, error) {
+		report(fmt.Sprintf("connecting to %s", cfg.Host))
+		client, release, err := p.Router.Pool.Get(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 
-	output, err := session.CombinedOutput(utils.Lsblk)
-	if err != nil {
-		p.confirmMsg = fmt.Sprintf("execute command failed, %v", err)
-		p.showDialog = true
+		sudo := &sshclient.Sudo{Password: password}
+		var applyErr error
+		if op != nil {
+			applyErr = fstools.Apply(ctx, p.Router, client, sudo, target, op, report)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+
+		// 执行失败时也重新读取，显示设备当前的状态
+		report("listing block devices")
+		output, err := client.Run(ctx, utils.Lsblk)
+		if err != nil {
+			return nil, errors.Join(applyErr, err)
+		}
+		l := &listing{password: sudo.Password}
+		if l.devices, err = utils.GetBlockDevices(output); err != nil {
+			return nil, errors.Join(applyErr, err)
+		}
+		l.fstab = fstools.ReadFstab(ctx, client)
+		return l, applyErr
+	})
+}
+
+func (p *Page) handleResult(res job.Result[*listing]) {
+	if errors.Is(res.Err, context.Canceled) {
 		return
 	}
-	blocks, err := utils.GetBlockDevices(output)
-	if err != nil {
-		p.confirmMsg = err.Error()
-		p.showDialog = true
-		return
+	if l := res.Value; l != nil {
+		p.devices = l.devices
+		p.fs.SetFstab(l.fstab)
+		p.sudoPassword = l.password
+		// 只保留现有分区的按钮
+		clicks := make(map[string]*widget.Clickable)
+		for _, d := range p.devices {
+			for _, part := range d.GetParts() {
+				btn, ok := p.partClicks[part.Name]
+				if !ok {
+					btn = new(widget.Clickable)
+				}
+				clicks[part.Name] = btn
+			}
+		}
+		p.partClicks = clicks
+	}
+	if res.Err != nil {
+		p.dialog.Fail(res.Err.Error())
 	}
-	p.devices = blocks
 }
 
 func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.Clickable, txt string) layout.Dimensions {
@@ -338,6 +316,38 @@ func (p *Page) drawTable(gtx layout.Context, th *material.Theme) layout.Dimensio
 				}),
 			)
 		}))
+		// 磁盘下面列出分区，点击选中后显示文件系统操作
+		for _, part := range dev.GetParts() {
+			btn, ok := p.partClicks[part.Name]
+			if !ok {
+				continue
+			}
+			rows = append(rows, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return material.Clickable(gtx, btn, func(gtx layout.Context) layout.Dimensions {
+					return layout.Background{}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						if part.Name == p.selected {
+							paint.FillShape(gtx.Ops, selectedColor, clip.Rect{Max: gtx.Constraints.Min}.Op())
+						}
+						return layout.Dimensions{Size: gtx.Constraints.Min}
+					}, func(gtx layout.Context) layout.Dimensions {
+						return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return layoutTableCell(gtx, th, "", colWidth, rowHeight, false)
+							}),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return layoutTableCell(gtx, th, "  "+part.Name, colWidth, rowHeight, false)
+							}),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return layoutTableCell(gtx, th, part.Type, colWidth, rowHeight, false)
+							}),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return layoutTableCell(gtx, th, strconv.Itoa(int(part.Size)), colWidth, rowHeight, false)
+							}),
+						)
+					})
+				})
+			}))
+		}
 	}
 	return fx.Layout(gtx, rows...)
 }
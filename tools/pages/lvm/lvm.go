@@ -0,0 +1,634 @@
+package lvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"tools/icon"
+	"tools/job"
+	page "tools/pages"
+	"tools/pages/common"
+	"tools/pages/connform"
+	"tools/sshclient"
+	"tools/utils"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+var (
+	selectedColor = color.NRGBA{R: 63, G: 81, B: 181, A: 40}
+	grayColor     = color.NRGBA{R: 140, G: 140, B: 140, A: 255}
+)
+
+// column is a column of the volume list.
+type column struct {
+	name  string
+	width unit.Dp
+}
+
+var columns = []column{
+	{"name", 0},
+	{"type", 60},
+	{"size", 100},
+	{"free", 100},
+	{"extents", 180},
+	{"details", 260},
+}
+
+// state is the result of a job: the LVM setup of the host.
+type state struct {
+	lvm *utils.Lvm
+	// password 是 sudo 接受的密码，下次执行时先用它
+	password string
+}
+
+// operation is a change confirmed by the user. check runs against a fresh
+// report right before cmds and returns why they must not run.
+type operation struct {
+	check func(l *utils.Lvm) string
+	cmds  []string
+}
+
+// row is a line of the volume list: a volume group followed by its
+// physical and logical volumes, then the physical volumes in no group.
+type row struct {
+	key   string
+	depth int
+	vg    *utils.VolumeGroup
+	pv    *utils.PhysicalVolume
+	lv    *utils.LogicalVolume
+	// title 是分组标题，例如没有卷组的物理卷
+	title string
+}
+
+// Page shows the volume groups of a host with their physical and logical
+// volumes, and creates, extends and removes logical volumes. Every change
+// is previewed as the exact command and needs a confirmation.
+type Page struct {
+	connForm     *connform.Form
+	loadButton   widget.Clickable
+	cancelButton widget.Clickable
+	dialog       common.Dialog
+	runner       *job.Runner[*state]
+
+	lvm  *utils.Lvm
+	rows []row
+	// selected 是选中行的 key，重新读取后仍然保留
+	selected  string
+	rowClicks []widget.Clickable
+	list      widget.List
+
+	nameInput    widget.Editor
+	sizeInput    widget.Editor
+	createButton widget.Clickable
+	extendInput  widget.Editor
+	resizefs     widget.Bool
+	extendButton widget.Clickable
+	removeButton widget.Clickable
+	sudoPassword string
+	*page.Router
+}
+
+func New(router *page.Router) *Page {
+	p := &Page{
+		Router: router,
+		runner: job.NewRunner[*state](router.Invalidate),
+	}
+	p.connForm = connform.New(router)
+	p.nameInput.SingleLine = true
+	p.sizeInput.SingleLine = true
+	p.extendInput.SingleLine = true
+	p.resizefs.Value = true
+	p.list.Axis = layout.Vertical
+	return p
+}
+
+var _ page.Page = &Page{}
+
+func (p *Page) Actions() []component.AppBarAction {
+	return []component.AppBarAction{}
+}
+
+func (p *Page) Overflow() []component.OverflowAction {
+	return []component.OverflowAction{}
+}
+
+func (p *Page) NavItem() component.NavItem {
+	return component.NavItem{
+		Name: "LVM",
+		Icon: icon.LvmIcon,
+	}
+}
+
+func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	// 后台任务结束后在 UI 协程中处理结果
+	if res, ok := p.runner.Poll(); ok {
+		p.handleResult(res)
+	}
+	p.update(gtx)
+
+	mainPage := layout.Flex{
+		Axis: layout.Vertical,
+	}
+	mainPage.Layout(gtx,
+		// 连接参数
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.connForm.Layout(gtx, th)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10), Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				if p.runner.Running() {
+					return common.Progress(gtx, th, p.runner, &p.cancelButton)
+				}
+				return Button(gtx, 120, th, &p.loadButton, "load volumes")
+			})
+		}),
+		// 选中卷的操作
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if p.lvm == nil {
+				return layout.Dimensions{}
+			}
+			return layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10), Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutTools(gtx, th)
+			})
+		}),
+		// 卷列表（占满剩余空间）
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if p.lvm == nil {
+				return layout.Dimensions{}
+			}
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutVolumes(gtx, th)
+			})
+		}),
+	)
+
+	// 弹出对话框
+	p.dialog.Layout(gtx, th)
+
+	return mainPage.Layout(gtx)
+}
+
+func (p *Page) fail(msg string) {
+	p.dialog.Fail(msg)
+}
+
+// update handles the buttons, it runs before the layout so a click is not
+// drawn with stale state.
+func (p *Page) update(gtx layout.Context) {
+	if p.loadButton.Clicked(gtx) && !p.runner.Running() {
+		p.load(nil)
+	}
+	if p.cancelButton.Clicked(gtx) {
+		p.runner.Cancel()
+	}
+	for i := range p.rowClicks {
+		if p.rowClicks[i].Clicked(gtx) {
+			p.selected = p.rows[i].key
+		}
+	}
+	if p.lvm == nil || p.runner.Running() {
+		return
+	}
+	if p.createButton.Clicked(gtx) {
+		p.lvcreate()
+	}
+	if p.extendButton.Clicked(gtx) {
+		p.lvextend()
+	}
+	if p.removeButton.Clicked(gtx) {
+		p.lvremove()
+	}
+}
+
+// load reads the physical volumes, volume groups and logical volumes,
+// after running op when it is not nil.
+func (p *Page) load(op *operation) {
+	if itemName := p.connForm.Missing(); len(itemName) != 0 {
+		p.fail(fmt.Sprintf("%s is required", itemName))
+		return
+	}
+	// 编辑框只能在 UI 协程中读取，先取出参数再交给后台任务
+	cfg, err := p.connForm.Config()
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	target := p.connForm.Target()
+	password := p.sudoPassword
+	if len(password) == 0 {
+		password = cfg.Password
+	}
+	p.runner.Start(func(ctx context.Context, report func(string)) (*state, error) {
+		report(fmt.Sprintf("connecting to %s", cfg.Host))
+		client, release, err := p.Router.Pool.Get(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		sudo := &sshclient.Sudo{Password: password}
+		var applyErr error
+		if op != nil {
+			applyErr = p.apply(ctx, client, sudo, target, op, report)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+
+		// 执行失败时也重新读取，显示当前的状态
+		l, err := p.read(ctx, client, sudo, target, report)
+		if err != nil {
+			return nil, errors.Join(applyErr, err)
+		}
+		return &state{lvm: l, password: sudo.Password}, applyErr
+	})
+}
+
+// read runs the LVM reports, they need root to scan the devices.
+func (p *Page) read(ctx context.Context, client *sshclient.Client, sudo *sshclient.Sudo, target string, report func(string)) (*utils.Lvm, error) {
+	report("reading the LVM reports")
+	var outputs [3][]byte
+	for i, cmd := range []string{utils.Pvs, utils.Vgs, utils.Lvs} {
+		output, err := p.SudoRun(ctx, client, sudo, target, cmd)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = output
+	}
+	return utils.ParseLvm(outputs[0], outputs[1], outputs[2])
+}
+
+// apply reads the volumes again right before running the commands, they
+// may have changed since they were listed.
+func (p *Page) apply(ctx context.Context, client *sshclient.Client, sudo *sshclient.Sudo, target string, op *operation, report func(string)) error {
+	l, err := p.read(ctx, client, sudo, target, report)
+	if err != nil {
+		return err
+	}
+	if reason := op.check(l); len(reason) != 0 {
+		return fmt.Errorf("refusing to run %s, %s", strings.Join(op.cmds, "; "), reason)
+	}
+	for _, cmd := range op.cmds {
+		report(fmt.Sprintf("running %s", cmd))
+		if _, err := p.SudoRun(ctx, client, sudo, target, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Page) handleResult(res job.Result[*state]) {
+	if errors.Is(res.Err, context.Canceled) {
+		return
+	}
+	if s := res.Value; s != nil {
+		p.lvm = s.lvm
+		p.sudoPassword = s.password
+		p.flatten()
+	}
+	if res.Err != nil {
+		p.fail(res.Err.Error())
+	}
+}
+
+// flatten builds the rows of the volume list, the selection is dropped
+// when its volume is gone.
+func (p *Page) flatten() {
+	p.rows = p.rows[:0]
+	found := false
+	add := func(r row) {
+		found = found || r.key == p.selected
+		p.rows = append(p.rows, r)
+	}
+	for i := range p.lvm.VGs {
+		vg := &p.lvm.VGs[i]
+		add(row{key: "vg:" + vg.Name, vg: vg})
+		for j := range vg.PVs {
+			add(row{key: "pv:" + vg.PVs[j].Name, depth: 1, vg: vg, pv: &vg.PVs[j]})
+		}
+		for j := range vg.LVs {
+			add(row{key: "lv:" + vg.LVs[j].FullName(), depth: 1, vg: vg, lv: &vg.LVs[j]})
+		}
+	}
+	if len(p.lvm.Orphans) != 0 {
+		add(row{key: "orphans", title: "not in a volume group"})
+		for j := range p.lvm.Orphans {
+			add(row{key: "pv:" + p.lvm.Orphans[j].Name, depth: 1, pv: &p.lvm.Orphans[j]})
+		}
+	}
+	if !found {
+		p.selected = ""
+	}
+	p.rowClicks = make([]widget.Clickable, len(p.rows))
+}
+
+// selection returns the selected row, ok is false when nothing is
+// selected.
+func (p *Page) selection() (row, bool) {
+	for _, r := range p.rows {
+		if r.key == p.selected && len(p.selected) != 0 {
+			return r, true
+		}
+	}
+	return row{}, false
+}
+
+// confirm shows the commands of op with a warning and runs them once
+// confirmed.
+func (p *Page) confirm(warning string, op *operation) {
+	p.dialog.Confirm(warning, op.cmds, func() { p.load(op) })
+}
+
+// parseMiB reads a size in MiB, an empty input means all free space.
+func parseMiB(editor *widget.Editor) (int64, error) {
+	s := strings.TrimSpace(editor.Text())
+	if len(s) == 0 {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q, enter a number of MiB or leave it empty for all free space", s)
+	}
+	return n, nil
+}
+
+// sizeDesc describes a size for the confirmation, 0 is all free space.
+func sizeDesc(vg *utils.VolumeGroup, size int64) string {
+	if size == 0 {
+		return fmt.Sprintf("all free space (%s)", common.FormatSize(vg.Free))
+	}
+	return common.FormatSize(size * utils.MiB)
+}
+
+func (p *Page) lvcreate() {
+	r, ok := p.selection()
+	if !ok || r.vg == nil {
+		p.fail("select a volume group first")
+		return
+	}
+	size, err := parseMiB(&p.sizeInput)
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	name := strings.TrimSpace(p.nameInput.Text())
+	cmd, err := utils.LvcreateCmd(r.vg, name, size)
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	vgName := r.vg.Name
+	check := func(l *utils.Lvm) string {
+		vg, ok := l.VG(vgName)
+		if !ok {
+			return fmt.Sprintf("volume group %s is gone", vgName)
+		}
+		if _, err := utils.LvcreateCmd(vg, name, size); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	p.confirm(fmt.Sprintf("create logical volume %s/%s with %s?", vgName, name, sizeDesc(r.vg, size)), &operation{check: check, cmds: []string{cmd}})
+}
+
+func (p *Page) lvextend() {
+	r, ok := p.selection()
+	if !ok || r.lv == nil {
+		p.fail("select a logical volume first")
+		return
+	}
+	size, err := parseMiB(&p.extendInput)
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	resizefs := p.resizefs.Value
+	cmd, err := utils.LvextendCmd(r.vg, r.lv, size, resizefs)
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	vgName, lvName := r.vg.Name, r.lv.Name
+	check := func(l *utils.Lvm) string {
+		lv, ok := l.LV(vgName, lvName)
+		if !ok {
+			return fmt.Sprintf("logical volume %s/%s is gone", vgName, lvName)
+		}
+		vg, _ := l.VG(vgName)
+		if _, err := utils.LvextendCmd(vg, lv, size, resizefs); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	warning := fmt.Sprintf("extend %s by %s?", r.lv.FullName(), sizeDesc(r.vg, size))
+	if !resizefs {
+		warning += " The filesystem on it has to be grown afterwards."
+	}
+	p.confirm(warning, &operation{check: check, cmds: []string{cmd}})
+}
+
+func (p *Page) lvremove() {
+	r, ok := p.selection()
+	if !ok || r.lv == nil {
+		p.fail("select a logical volume first")
+		return
+	}
+	cmd, err := utils.LvremoveCmd(r.lv)
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	vgName, lvName := r.vg.Name, r.lv.Name
+	check := func(l *utils.Lvm) string {
+		lv, ok := l.LV(vgName, lvName)
+		if !ok {
+			return fmt.Sprintf("logical volume %s/%s is gone", vgName, lvName)
+		}
+		// 期间可能被挂载
+		if _, err := utils.LvremoveCmd(lv); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	p.confirm(fmt.Sprintf("remove logical volume %s? The data on it is lost.", r.lv.FullName()), &operation{check: check, cmds: []string{cmd}})
+}
+
+// layoutTools draws the operations: creating a logical volume in the
+// selected volume group, and extending or removing the selected logical
+// volume.
+func (p *Page) layoutTools(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	r, _ := p.selection()
+	if r.vg == nil {
+		return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, material.Body2(th, "select a volume group or logical volume").Layout)
+	}
+	row := func(gtx layout.Context, children ...layout.FlexChild) layout.Dimensions {
+		return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+		})
+	}
+	gap := layout.Rigid(layout.Spacer{Width: 10}.Layout)
+	children := []layout.FlexChild{
+		// 在选中的卷组中新建逻辑卷
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return row(gtx,
+				layout.Rigid(material.Body2(th, fmt.Sprintf("in %s (%s free)", r.vg.Name, common.FormatSize(r.vg.Free))).Layout),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.nameInput, "name", 160)
+				}),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.sizeInput, "MiB (empty: all free)", 180)
+				}),
+				gap,
+				layout.Rigid(material.Button(th, &p.createButton, "create LV").Layout),
+			)
+		}),
+	}
+	if r.lv != nil {
+		// 选中逻辑卷的操作
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return row(gtx,
+				layout.Rigid(material.Body2(th, r.lv.FullName()).Layout),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.extendInput, "add MiB (empty: all free)", 180)
+				}),
+				gap,
+				layout.Rigid(material.CheckBox(th, &p.resizefs, "grow filesystem").Layout),
+				gap,
+				layout.Rigid(material.Button(th, &p.extendButton, "extend").Layout),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					b := material.Button(th, &p.removeButton, "remove LV")
+					b.Background = common.DangerColor
+					return b.Layout(gtx)
+				}),
+			)
+		}))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// cells lays out a table row with the column widths, the name column takes
+// the remaining space.
+func cells(gtx layout.Context, widgets ...layout.Widget) layout.Dimensions {
+	children := make([]layout.FlexChild, 0, len(widgets))
+	for i, w := range widgets {
+		width := columns[i].width
+		if width == 0 {
+			children = append(children, layout.Flexed(1, w))
+			continue
+		}
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(width)
+			gtx.Constraints.Max.X = gtx.Dp(width)
+			return w(gtx)
+		}))
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+}
+
+func (p *Page) layoutVolumes(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		// 表头
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			header := make([]layout.Widget, len(columns))
+			for i, c := range columns {
+				lbl := material.Body2(th, c.name)
+				lbl.Font.Weight = font.Bold
+				header[i] = lbl.Layout
+			}
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return cells(gtx, header...)
+			})
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if len(p.rows) == 0 {
+				return material.Body2(th, "no physical volumes found").Layout(gtx)
+			}
+			return material.List(th, &p.list).Layout(gtx, len(p.rows), func(gtx layout.Context, i int) layout.Dimensions {
+				return p.layoutRow(gtx, th, i)
+			})
+		}),
+	)
+}
+
+// rowText returns the cells of a row after the name: type, size, free,
+// extents and details.
+func rowText(r row) [5]string {
+	switch {
+	case r.lv != nil:
+		details := r.lv.Path
+		if r.lv.Open() {
+			details += "  in use"
+		}
+		return [5]string{"LV", common.FormatSize(r.lv.Size), "", fmt.Sprintf("%d", r.lv.Size/max(r.vg.ExtentSize, 1)), strings.TrimSpace(r.lv.Attr + "  " + details)}
+	case r.pv != nil:
+		return [5]string{"PV", common.FormatSize(r.pv.Size), common.FormatSize(r.pv.Free),
+			fmt.Sprintf("%d used of %d", r.pv.AllocExtents, r.pv.Extents), r.pv.Format}
+	case r.vg != nil:
+		return [5]string{"VG", common.FormatSize(r.vg.Size), common.FormatSize(r.vg.Free),
+			fmt.Sprintf("%d free of %d", r.vg.FreeExtents, r.vg.Extents),
+			fmt.Sprintf("%d PV, %d LV, extent %s", len(r.vg.PVs), len(r.vg.LVs), common.FormatSize(r.vg.ExtentSize))}
+	}
+	return [5]string{}
+}
+
+func (p *Page) layoutRow(gtx layout.Context, th *material.Theme, i int) layout.Dimensions {
+	r := p.rows[i]
+	name := r.title
+	switch {
+	case r.lv != nil:
+		name = r.lv.Name
+	case r.pv != nil:
+		name = r.pv.Name
+	case r.vg != nil:
+		name = r.vg.Name
+	}
+	text := rowText(r)
+	nameCell := func(gtx layout.Context) layout.Dimensions {
+		lbl := material.Body2(th, name)
+		if r.depth == 0 {
+			lbl.Font.Weight = font.Bold
+		}
+		if len(r.title) != 0 {
+			lbl.Color = grayColor
+		}
+		return layout.Inset{Left: unit.Dp(float32(20 * r.depth))}.Layout(gtx, lbl.Layout)
+	}
+	widgets := []layout.Widget{nameCell}
+	for _, t := range text {
+		widgets = append(widgets, material.Body2(th, t).Layout)
+	}
+	return material.Clickable(gtx, &p.rowClicks[i], func(gtx layout.Context) layout.Dimensions {
+		// 选中的行加背景色
+		return layout.Background{}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			if r.key == p.selected {
+				paint.FillShape(gtx.Ops, selectedColor, clip.Rect{Max: gtx.Constraints.Min}.Op())
+			}
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}, func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return cells(gtx, widgets...)
+			})
+		})
+	})
+}
+
+func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.Clickable, txt string) layout.Dimensions {
+	gtx.Constraints.Min.X = gtx.Dp(width)
+	gtx.Constraints.Max.X = gtx.Dp(width)
+	return material.Button(th, wid, txt).Layout(gtx)
+}
@@ -0,0 +1,541 @@
+package multirun
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+	"tools/icon"
+	"tools/inventory"
+	"tools/job"
+	page "tools/pages"
+	"tools/pages/common"
+	"tools/pages/connform"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+// defaultWorkers is the number of hosts running at the same time.
+const defaultWorkers = 10
+
+var (
+	okColor  = color.NRGBA{R: 30, G: 140, B: 60, A: 255}
+	errColor = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+)
+
+// row is one host of the current run in the results table.
+type row struct {
+	host         inventory.Host
+	run          *hostRun
+	expandButton widget.Clickable
+	expanded     bool
+	output       widget.Editor
+	// shown 是输出框当前的内容，结果变化时才重新设置
+	shown string
+}
+
+// Page runs one command on the selected hosts in parallel.
+type Page struct {
+	connForm     *connform.Form
+	filterInput  widget.Editor
+	workersInput widget.Editor
+	cmdInput     widget.Editor
+	selectAll    widget.Clickable
+	selectNone   widget.Clickable
+	selected     map[string]*widget.Bool
+	hostList     widget.List
+	runButton    widget.Clickable
+	retryButton  widget.Clickable
+	cancelButton widget.Clickable
+	modalButton  widget.Clickable
+	showDialog   bool
+	confirmMsg   string
+	// cmd 是最近一次运行的命令，重试时使用
+	cmd        string
+	rows       []*row
+	resultList widget.List
+	runner     *job.Runner[struct{}]
+	*page.Router
+}
+
+func New(router *page.Router) *Page {
+	p := &Page{
+		Router:   router,
+		runner:   job.NewRunner[struct{}](router.Invalidate),
+		selected: make(map[string]*widget.Bool),
+	}
+	p.connForm = connform.New(router)
+	p.filterInput.SingleLine = true
+	p.workersInput.SingleLine = true
+	p.workersInput.Filter = "0123456789"
+	p.workersInput.SetText(strconv.Itoa(defaultWorkers))
+	p.cmdInput.SingleLine = true
+	p.hostList.Axis = layout.Vertical
+	p.resultList.Axis = layout.Vertical
+	return p
+}
+
+var _ page.Page = &Page{}
+
+func (p *Page) Actions() []component.AppBarAction {
+	return []component.AppBarAction{}
+}
+
+func (p *Page) Overflow() []component.OverflowAction {
+	return []component.OverflowAction{}
+}
+
+func (p *Page) NavItem() component.NavItem {
+	return component.NavItem{
+		Name: "Multi Run",
+		Icon: icon.MultiRunIcon,
+	}
+}
+
+func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	p.runner.Poll()
+	if p.runner.Running() {
+		// 运行中每秒刷新耗时
+		gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(time.Second)})
+	}
+
+	hosts := p.matching()
+	p.update(gtx, hosts)
+
+	mainPage := layout.Flex{
+		Axis: layout.Vertical,
+	}
+	mainPage.Layout(gtx,
+		// 主机选择
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutHosts(gtx, th, hosts)
+			})
+		}),
+		// 命令和并发数
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10), Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return connform.Input(gtx, th, &p.cmdInput, "cmd", 560)
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return connform.Input(gtx, th, &p.workersInput, "workers", 50)
+					}),
+					layout.Rigid(layout.Spacer{Width: 5}.Layout),
+					layout.Rigid(material.Body2(th, "workers").Layout),
+				)
+			})
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10), Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutButtons(gtx, th)
+			})
+		}),
+		// 结果表格（占满剩余空间）
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if len(p.rows) == 0 {
+				return layout.Dimensions{}
+			}
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutResults(gtx, th)
+			})
+		}),
+	)
+
+	// 弹出对话框
+	if p.showDialog {
+		p.drawConfirmDialog(gtx, th)
+	}
+
+	return mainPage.Layout(gtx)
+}
+
+// matching returns the saved hosts and aliases matching the filter.
+func (p *Page) matching() []inventory.Host {
+	hosts := make([]inventory.Host, 0)
+	for _, h := range p.connForm.Targets() {
+		if h.Match(p.filterInput.Text()) {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+func (p *Page) checked(name string) *widget.Bool {
+	b, ok := p.selected[name]
+	if !ok {
+		b = new(widget.Bool)
+		p.selected[name] = b
+	}
+	return b
+}
+
+func (p *Page) update(gtx layout.Context, hosts []inventory.Host) {
+	if p.selectAll.Clicked(gtx) {
+		for _, h := range hosts {
+			p.checked(h.Name).Value = true
+		}
+	}
+	if p.selectNone.Clicked(gtx) {
+		for _, b := range p.selected {
+			b.Value = false
+		}
+	}
+	if p.runButton.Clicked(gtx) && !p.runner.Running() {
+		p.startRun()
+	}
+	if p.retryButton.Clicked(gtx) && !p.runner.Running() {
+		p.retry()
+	}
+	if p.cancelButton.Clicked(gtx) {
+		p.runner.Cancel()
+	}
+	for _, r := range p.rows {
+		if r.expandButton.Clicked(gtx) {
+			r.expanded = !r.expanded
+		}
+	}
+}
+
+// startRun starts a new run on the selected hosts, replacing the results.
+func (p *Page) startRun() {
+	cmd := strings.TrimSpace(p.cmdInput.Text())
+	rows := make([]*row, 0)
+	for _, h := range p.connForm.Targets() {
+		if b, ok := p.selected[h.Name]; ok && b.Value {
+			rows = append(rows, newRow(h))
+		}
+	}
+	switch {
+	case len(rows) == 0:
+		p.confirmMsg = "select at least one host"
+		p.showDialog = true
+		return
+	case len(cmd) == 0:
+		p.confirmMsg = "command is required"
+		p.showDialog = true
+		return
+	}
+	if p.start(rows, cmd) {
+		p.cmd, p.rows = cmd, rows
+	}
+}
+
+// retry runs the command again on the hosts that did not succeed.
+func (p *Page) retry() {
+	rows := make([]*row, 0)
+	for _, r := range p.rows {
+		if r.run.snapshot().state.retryable() {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) != 0 {
+		p.start(rows, p.cmd)
+	}
+}
+
+func newRow(h inventory.Host) *row {
+	r := &row{host: h, run: &hostRun{}}
+	r.output.ReadOnly = true
+	r.output.WrapPolicy = text.WrapGraphemes
+	return r
+}
+
+// start builds the ssh configs on the UI goroutine and runs cmd on rows in
+// the background. It returns false when a config is invalid.
+func (p *Page) start(rows []*row, cmd string) bool {
+	targets := make([]target, 0, len(rows))
+	for _, r := range rows {
+		cfg, err := p.connForm.HostConfig(r.host)
+		if err != nil {
+			p.confirmMsg = err.Error()
+			p.showDialog = true
+			return false
+		}
+		targets = append(targets, target{cfg: cfg, run: r.run})
+	}
+	workers, _ := strconv.Atoi(p.workersInput.Text())
+	if workers <= 0 {
+		workers = defaultWorkers
+		p.workersInput.SetText(strconv.Itoa(workers))
+	}
+	for _, r := range rows {
+		r.run.reset()
+	}
+	p.runner.Start(func(ctx context.Context, report func(string)) (struct{}, error) {
+		report(fmt.Sprintf("running %q on %d hosts", cmd, len(targets)))
+		runAll(ctx, p.Router.Pool, targets, cmd, workers, p.Router.Invalidate)
+		return struct{}{}, ctx.Err()
+	})
+	return true
+}
+
+func (p *Page) layoutHosts(gtx layout.Context, th *material.Theme, hosts []inventory.Host) layout.Dimensions {
+	count := 0
+	for _, b := range p.selected {
+		if b.Value {
+			count++
+		}
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.filterInput, "filter by name, address, tag or group", 400)
+				}),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(material.Button(th, &p.selectAll, "select all").Layout),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(material.Button(th, &p.selectNone, "clear").Layout),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(material.Body2(th, fmt.Sprintf("%d selected", count)).Layout),
+			)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if len(hosts) == 0 {
+				return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.Body2(th, "no saved hosts, add them on the Hosts page").Layout)
+			}
+			gtx.Constraints.Max.Y = min(gtx.Constraints.Max.Y, gtx.Dp(180))
+			return material.List(th, &p.hostList).Layout(gtx, len(hosts), func(gtx layout.Context, i int) layout.Dimensions {
+				h := hosts[i]
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(material.CheckBox(th, p.checked(h.Name), "").Layout),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return connform.HostLabel(gtx, th, h)
+					}),
+				)
+			})
+		}),
+	)
+}
+
+func (p *Page) layoutButtons(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	if p.runner.Running() {
+		return common.Progress(gtx, th, p.runner, &p.cancelButton)
+	}
+	failed := 0
+	for _, r := range p.rows {
+		if r.run.snapshot().state.retryable() {
+			failed++
+		}
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return Button(gtx, 80, th, &p.runButton, "run")
+		}),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if failed == 0 {
+				return layout.Dimensions{}
+			}
+			return material.Button(th, &p.retryButton, fmt.Sprintf("retry %d failed", failed)).Layout(gtx)
+		}),
+	)
+}
+
+// summary counts the hosts of the run by state.
+func (p *Page) summary() string {
+	counts := make(map[state]int)
+	for _, r := range p.rows {
+		counts[r.run.snapshot().state]++
+	}
+	parts := make([]string, 0)
+	for s := statePending; s <= stateCanceled; s++ {
+		if counts[s] != 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+var columns = []struct {
+	name  string
+	width unit.Dp
+}{
+	{"host", 200},
+	{"status", 100},
+	{"exit", 60},
+	{"duration", 90},
+	{"output", 0},
+}
+
+// cells lays out a table row with the column widths, the last column takes
+// the remaining space.
+func cells(gtx layout.Context, widgets ...layout.Widget) layout.Dimensions {
+	children := make([]layout.FlexChild, 0, len(widgets))
+	for i, w := range widgets {
+		width := columns[i].width
+		if width == 0 {
+			children = append(children, layout.Flexed(1, w))
+			continue
+		}
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(width)
+			gtx.Constraints.Max.X = gtx.Dp(width)
+			return w(gtx)
+		}))
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+}
+
+func (p *Page) layoutResults(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, material.Body2(th, p.summary()).Layout)
+		}),
+		// 表头
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			header := make([]layout.Widget, len(columns))
+			for i, c := range columns {
+				lbl := material.Body2(th, c.name)
+				lbl.Font.Weight = font.Bold
+				header[i] = lbl.Layout
+			}
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return cells(gtx, header...)
+			})
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return material.List(th, &p.resultList).Layout(gtx, len(p.rows), func(gtx layout.Context, i int) layout.Dimensions {
+				return p.layoutRow(gtx, th, p.rows[i])
+			})
+		}),
+	)
+}
+
+func (p *Page) layoutRow(gtx layout.Context, th *material.Theme, r *row) layout.Dimensions {
+	s := r.run.snapshot()
+	exit := "-"
+	if s.result != nil {
+		exit = strconv.Itoa(s.result.ExitCode)
+		if len(s.result.Signal) != 0 {
+			exit = s.result.Signal
+		}
+	}
+	duration := "-"
+	if s.state != statePending {
+		duration = s.elapsed.Round(100 * time.Millisecond).String()
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return material.Clickable(gtx, &r.expandButton, func(gtx layout.Context) layout.Dimensions {
+				return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					status := material.Body2(th, s.state.String())
+					switch s.state {
+					case stateOK:
+						status.Color = okColor
+					case stateFailed, stateError:
+						status.Color = errColor
+					}
+					preview := material.Body2(th, s.preview())
+					preview.MaxLines = 1
+					return cells(gtx,
+						material.Body1(th, r.host.Name).Layout,
+						status.Layout,
+						material.Body2(th, exit).Layout,
+						material.Body2(th, duration).Layout,
+						preview.Layout,
+					)
+				})
+			})
+		}),
+		// 展开后显示完整输出
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !r.expanded {
+				return layout.Dimensions{}
+			}
+			if full := fullOutput(s); full != r.shown {
+				r.shown = full
+				r.output.SetText(full)
+			}
+			gtx.Constraints.Max.Y = gtx.Dp(300)
+			return layout.Inset{Left: unit.Dp(20), Bottom: unit.Dp(10)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return widget.Border{
+					Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
+					Width: unit.Dp(1),
+				}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.Editor(th, &r.output, "no output").Layout)
+				})
+			})
+		}),
+	)
+}
+
+// fullOutput joins stdout, stderr and the error of a host for the
+// expanded row.
+func fullOutput(s hostState) string {
+	parts := make([]string, 0, 3)
+	if s.result != nil {
+		if len(s.result.Stdout) != 0 {
+			parts = append(parts, strings.TrimRight(string(s.result.Stdout), "\n"))
+		}
+		if len(s.result.Stderr) != 0 {
+			parts = append(parts, "stderr:\n"+strings.TrimRight(string(s.result.Stderr), "\n"))
+		}
+		parts = append(parts, s.result.Status())
+	}
+	if s.err != nil {
+		parts = append(parts, s.err.Error())
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func (p *Page) drawConfirmDialog(gtx layout.Context, th *material.Theme) {
+	// 全屏
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	full.Pop()
+
+	// 窗口大小和位置（居中）
+	boxW := min(gtx.Constraints.Max.X-80, 420)
+	boxH := 150
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	// 窗口背景（白色矩形）
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	// 将坐标系偏移道对话框左上角，然后在内部做正常布局
+	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(material.Body1(th, p.confirmMsg).Layout),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				btn := material.Button(th, &p.modalButton, "confirm")
+				if p.modalButton.Clicked(gtx) {
+					p.showDialog = false
+				}
+				return btn.Layout(gtx)
+			}),
+		)
+	})
+	offset.Pop()
+}
+
+func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.Clickable, txt string) layout.Dimensions {
+	gtx.Constraints.Min.X = gtx.Dp(width)
+	gtx.Constraints.Max.X = gtx.Dp(width)
+	return material.Button(th, wid, txt).Layout(gtx)
+}
@@ -0,0 +1,185 @@
+package multirun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"tools/sshclient"
+)
+
+// state is the progress of one host in a run.
+type state int
+
+const (
+	statePending state = iota
+	stateConnecting
+	stateRunning
+	stateOK
+	stateFailed
+	stateError
+	stateCanceled
+)
+
+func (s state) String() string {
+	switch s {
+	case stateConnecting:
+		return "connecting"
+	case stateRunning:
+		return "running"
+	case stateOK:
+		return "ok"
+	case stateFailed:
+		return "failed"
+	case stateError:
+		return "error"
+	case stateCanceled:
+		return "canceled"
+	}
+	return "pending"
+}
+
+// retryable reports whether the host did not finish successfully.
+func (s state) retryable() bool {
+	return s == stateFailed || s == stateError || s == stateCanceled
+}
+
+// hostRun is the result of one host, written by a worker goroutine and
+// read by the UI.
+type hostRun struct {
+	mu      sync.Mutex
+	state   state
+	result  *sshclient.Result
+	err     error
+	started time.Time
+	elapsed time.Duration
+}
+
+// hostState is a copy of hostRun taken for drawing.
+type hostState struct {
+	state   state
+	result  *sshclient.Result
+	err     error
+	elapsed time.Duration
+}
+
+func (h *hostRun) snapshot() hostState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	elapsed := h.elapsed
+	if h.state == stateConnecting || h.state == stateRunning {
+		elapsed = time.Since(h.started)
+	}
+	return hostState{state: h.state, result: h.result, err: h.err, elapsed: elapsed}
+}
+
+func (h *hostRun) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state, h.result, h.err, h.elapsed = statePending, nil, nil, 0
+}
+
+func (h *hostRun) begin() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = stateConnecting
+	h.started = time.Now()
+}
+
+func (h *hostRun) set(s state) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = s
+}
+
+func (h *hostRun) finish(res *sshclient.Result, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.result, h.err = res, err
+	if !h.started.IsZero() {
+		h.elapsed = time.Since(h.started)
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		h.state = stateCanceled
+	case err != nil:
+		h.state = stateError
+	case res.Failed():
+		h.state = stateFailed
+	default:
+		h.state = stateOK
+	}
+}
+
+// preview returns the first output line, or the error, for the table.
+func (s hostState) preview() string {
+	text := ""
+	switch {
+	case s.err != nil:
+		text = s.err.Error()
+	case s.result != nil && len(strings.TrimSpace(string(s.result.Stdout))) != 0:
+		text = string(s.result.Stdout)
+	case s.result != nil:
+		text = string(s.result.Stderr)
+	}
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i] + " …"
+	}
+	if r := []rune(text); len(r) > 80 {
+		text = string(r[:80]) + "…"
+	}
+	return text
+}
+
+// target is a host queued for a run.
+type target struct {
+	cfg sshclient.Config
+	run *hostRun
+}
+
+// runAll runs cmd on every target with at most workers sessions at a time.
+// Hosts still queued when ctx is cancelled are marked canceled.
+func runAll(ctx context.Context, pool *sshclient.Pool, targets []target, cmd string, workers int, changed func()) {
+	sem := make(chan struct{}, max(workers, 1))
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			t.run.finish(nil, context.Canceled)
+			changed()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+				changed()
+			}()
+			t.run.begin()
+			changed()
+			client, release, err := pool.Get(ctx, t.cfg)
+			if err != nil {
+				t.run.finish(nil, cancelErr(ctx, err))
+				return
+			}
+			defer release()
+			t.run.set(stateRunning)
+			changed()
+			res, err := client.Exec(ctx, cmd)
+			t.run.finish(res, cancelErr(ctx, err))
+		}()
+	}
+	wg.Wait()
+}
+
+// cancelErr reports errors caused by cancelling the run as context.Canceled.
+func cancelErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return context.Canceled
+	}
+	return err
+}
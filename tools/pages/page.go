@@ -3,7 +3,16 @@ package pages
 import (
 	"fmt"
 	"time"
+	"tools/history"
 	"tools/icon"
+	"tools/inventory"
+	"tools/pages/hostkey"
+	"tools/pages/prompt"
+	"tools/snippet"
+	"tools/sshclient"
+	"tools/sshconfig"
+	"tools/tunnel"
+	"tools/vault"
 
 	"gioui.org/layout"
 	"gioui.org/unit"
@@ -18,16 +27,41 @@ type Page interface {
 	NavItem() component.NavItem
 }
 
+// Invalidator is implemented by app.Window, pages use it to request a new
+// frame when background work finishes.
+type Invalidator interface {
+	Invalidate()
+}
+
 type Router struct {
+	window         Invalidator
 	pages          map[any]Page
 	current        any
 	NavAnim        component.VisibilityAnimation
 	NonModalDrawer bool
+	// HostKeys asks the user to trust unknown ssh host keys for every page.
+	HostKeys *hostkey.Prompter
+	// Prompts asks the user for passphrases and other secrets.
+	Prompts *prompt.Prompter
+	// Inventory holds the saved hosts offered by the connection forms.
+	Inventory *inventory.Inventory
+	// SSHConfig holds the aliases from ~/.ssh/config.
+	SSHConfig *sshconfig.Config
+	// Vault holds the encrypted passwords and key passphrases.
+	Vault *vault.Vault
+	// Pool shares ssh connections between pages.
+	Pool *sshclient.Pool
+	// History holds the commands run on each host.
+	History *history.History
+	// Snippets holds the saved parameterised commands.
+	Snippets *snippet.Library
+	// Tunnels holds the saved port forwards of each host.
+	Tunnels *tunnel.Store
 	*component.AppBar
 	*component.ModalNavDrawer
 }
 
-func NewRouter() Router {
+func NewRouter(window Invalidator) Router {
 	modal := component.NewModal()
 
 	nav := component.NewNav("Tools", "")
@@ -36,18 +70,39 @@ func NewRouter() Router {
 	bar := component.NewAppBar(modal)
 	bar.NavigationIcon = icon.MenuIcon
 
+	invalidate := func() {}
+	if window != nil {
+		invalidate = window.Invalidate
+	}
+
 	na := component.VisibilityAnimation{
 		State:    component.Invisible,
 		Duration: time.Millisecond * 250,
 	}
 	return Router{
+		window:         window,
 		pages:          make(map[any]Page),
 		AppBar:         bar,
 		NavAnim:        na,
+		HostKeys:       hostkey.New(invalidate),
+		Prompts:        prompt.New(invalidate),
 		ModalNavDrawer: modalNav,
 	}
 }
 
+// Invalidate asks the window to redraw, it is safe to call from any goroutine.
+func (r *Router) Invalidate() {
+	if r.window != nil {
+		r.window.Invalidate()
+	}
+}
+
+// Secrets serves passwords from the vault to ssh connections, asking for
+// the master passphrase when the vault is locked.
+func (r *Router) Secrets() sshclient.Secrets {
+	return vault.Secrets{Vault: r.Vault, Prompt: r.Prompts.Ask}
+}
+
 func (r *Router) Register(tag any, p Page) {
 	r.pages[tag] = p
 	navItem := p.NavItem()
@@ -92,7 +147,7 @@ func (r *Router) Layout(gtx layout.Context, th *material.Theme) layout.Dimension
 		fmt.Printf("tag: %v\n", r.ModalNavDrawer.CurrentNavDestination())
 		r.SwitchTo(r.ModalNavDrawer.CurrentNavDestination())
 	}
-	return layout.Flex{
+	dims := layout.Flex{
 		Axis: layout.Vertical,
 	}.Layout(gtx,
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
@@ -117,4 +172,9 @@ func (r *Router) Layout(gtx layout.Context, th *material.Theme) layout.Dimension
 			)
 		}),
 	)
+
+	// 主机密钥确认框和输入框覆盖在所有页面之上
+	r.HostKeys.Layout(gtx, th)
+	r.Prompts.Layout(gtx, th)
+	return dims
 }
@@ -0,0 +1,695 @@
+package partitions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"tools/icon"
+	"tools/job"
+	page "tools/pages"
+	"tools/pages/common"
+	"tools/pages/connform"
+	"tools/pages/fstools"
+	"tools/sshclient"
+	"tools/utils"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+var (
+	selectedColor = color.NRGBA{R: 63, G: 81, B: 181, A: 40}
+	freeColor     = color.NRGBA{R: 224, G: 224, B: 224, A: 255}
+	// partColors 依次用于分区条中的各个分区
+	partColors = []color.NRGBA{
+		{R: 66, G: 133, B: 244, A: 255},
+		{R: 52, G: 168, B: 83, A: 255},
+		{R: 251, G: 188, B: 5, A: 255},
+		{R: 171, G: 71, B: 188, A: 255},
+		{R: 0, G: 172, B: 193, A: 255},
+	}
+)
+
+// column is a column of the partition list.
+type column struct {
+	name  string
+	width unit.Dp
+}
+
+var columns = []column{
+	{"#", 50},
+	{"start", 110},
+	{"end", 110},
+	{"size", 100},
+	{"fstype", 120},
+	{"name", 0},
+	{"flags", 200},
+}
+
+// state is the result of a job: the block devices, the fstab and the
+// partition table of the chosen disk.
+type state struct {
+	devices []utils.BlockDevice
+	fstab   []utils.FstabEntry
+	disk    string
+	table   *utils.PartTable
+	// password 是 sudo 接受的密码，下次执行时先用它
+	password string
+}
+
+// Page edits the partition table of a disk with parted. Every change is
+// previewed as the exact commands and needs a confirmation, disks that
+// hold the root filesystem or anything mounted are refused.
+type Page struct {
+	connForm     *connform.Form
+	loadButton   widget.Clickable
+	cancelButton widget.Clickable
+	dialog       common.Dialog
+	runner       *job.Runner[*state]
+
+	devices []utils.BlockDevice
+	disk    widget.Enum
+	table   *utils.PartTable
+	// selected 是选中的分区或空闲区域在 table.Parts 中的下标，-1 表示没有选中
+	selected int
+	segments []widget.Clickable
+	rows     []widget.Clickable
+	list     widget.List
+
+	label        widget.Enum
+	labelButton  widget.Clickable
+	startInput   widget.Editor
+	endInput     widget.Editor
+	nameInput    widget.Editor
+	addButton    widget.Clickable
+	resizeInput  widget.Editor
+	resizeButton widget.Clickable
+	deleteButton widget.Clickable
+	fs           *fstools.Tools
+
+	sudoPassword string
+	*page.Router
+}
+
+func New(router *page.Router) *Page {
+	p := &Page{
+		Router:   router,
+		runner:   job.NewRunner[*state](router.Invalidate),
+		selected: -1,
+	}
+	p.connForm = connform.New(router)
+	p.label.Value = utils.DiskLabelGpt
+	p.startInput.SingleLine = true
+	p.endInput.SingleLine = true
+	p.nameInput.SingleLine = true
+	p.resizeInput.SingleLine = true
+	p.list.Axis = layout.Vertical
+	p.fs = fstools.New()
+	return p
+}
+
+var _ page.Page = &Page{}
+
+func (p *Page) Actions() []component.AppBarAction {
+	return []component.AppBarAction{}
+}
+
+func (p *Page) Overflow() []component.OverflowAction {
+	return []component.OverflowAction{}
+}
+
+func (p *Page) NavItem() component.NavItem {
+	return component.NavItem{
+		Name: "Partitions",
+		Icon: icon.PartitionsIcon,
+	}
+}
+
+func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	// 后台任务结束后在 UI 协程中处理结果
+	if res, ok := p.runner.Poll(); ok {
+		p.handleResult(res)
+	}
+	p.update(gtx)
+
+	mainPage := layout.Flex{
+		Axis: layout.Vertical,
+	}
+	mainPage.Layout(gtx,
+		// 连接参数
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.connForm.Layout(gtx, th)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10), Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				if p.runner.Running() {
+					return common.Progress(gtx, th, p.runner, &p.cancelButton)
+				}
+				return Button(gtx, 120, th, &p.loadButton, "load disks")
+			})
+		}),
+		// 磁盘列表
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10), Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutDisks(gtx, th)
+			})
+		}),
+		// 分区表（占满剩余空间）
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			if p.table == nil {
+				return layout.Dimensions{}
+			}
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutTable(gtx, th)
+			})
+		}),
+	)
+
+	// 弹出对话框
+	p.dialog.Layout(gtx, th)
+
+	return mainPage.Layout(gtx)
+}
+
+func (p *Page) fail(msg string) {
+	p.dialog.Fail(msg)
+}
+
+// update handles the buttons, it runs before the layout so a click is not
+// drawn with stale state.
+func (p *Page) update(gtx layout.Context) {
+	if p.loadButton.Clicked(gtx) && !p.runner.Running() {
+		p.load(p.disk.Value, nil)
+	}
+	if p.cancelButton.Clicked(gtx) {
+		p.runner.Cancel()
+	}
+	// 任务进行中切换磁盘时，等任务结束后再读取
+	if p.disk.Update(gtx) && !p.runner.Running() {
+		p.table, p.selected = nil, -1
+		p.load(p.disk.Value, nil)
+	}
+	for i := range p.segments {
+		if p.segments[i].Clicked(gtx) {
+			p.selectPart(i)
+		}
+	}
+	for i := range p.rows {
+		if p.rows[i].Clicked(gtx) {
+			p.selectPart(i)
+		}
+	}
+	if p.table == nil || p.runner.Running() {
+		return
+	}
+	if p.labelButton.Clicked(gtx) {
+		p.mklabel()
+	}
+	if p.addButton.Clicked(gtx) {
+		p.mkpart()
+	}
+	if p.resizeButton.Clicked(gtx) {
+		p.resize()
+	}
+	if p.deleteButton.Clicked(gtx) {
+		p.rmpart()
+	}
+	dev, _ := p.partDevice()
+	warning, op, err := p.fs.Update(gtx, dev)
+	switch {
+	case err != nil:
+		p.fail(err.Error())
+	case op != nil:
+		// 文件系统操作已经检查过设备
+		p.confirmOp(warning, nil, op)
+	}
+}
+
+// load lists the disks and reads the partition table of disk, after
+// running op when it is not nil.
+func (p *Page) load(disk string, op *fstools.Operation) {
+	if itemName := p.connForm.Missing(); len(itemName) != 0 {
+		p.fail(fmt.Sprintf("%s is required", itemName))
+		return
+	}
+	// 编辑框只能在 UI 协程中读取，先取出参数再交给后台任务
+	cfg, err := p.connForm.Config()
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	target := p.connForm.Target()
+	password := p.sudoPassword
+	if len(password) == 0 {
+		password = cfg.Password
+	}
+	p.runner.Start(func(ctx context.Context, report func(string)) (*state, error) {
+		report(fmt.Sprintf("connecting to %s", cfg.Host))
+		client, release, err := p.Router.Pool.Get(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		sudo := &sshclient.Sudo{Password: password}
+		var applyErr error
+		if op != nil {
+			applyErr = fstools.Apply(ctx, p.Router, client, sudo, target, op, report)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+
+		// 执行失败时也重新读取，显示磁盘当前的状态
+		s := &state{disk: disk}
+		report("listing block devices")
+		output, err := client.Run(ctx, utils.Lsblk)
+		if err != nil {
+			return nil, errors.Join(applyErr, err)
+		}
+		if s.devices, err = utils.GetBlockDevices(output); err != nil {
+			return nil, errors.Join(applyErr, err)
+		}
+		s.fstab = fstools.ReadFstab(ctx, client)
+		if len(disk) != 0 {
+			report(fmt.Sprintf("reading the partition table of %s", disk))
+			// 没有分区表时 parted 以非零状态退出，但仍输出磁盘信息
+			res, err := p.SudoExec(ctx, client, sudo, target, utils.PartedPrint(disk))
+			if err != nil {
+				return nil, errors.Join(applyErr, err)
+			}
+			if s.table, err = utils.ParsePartTable(res.Stdout); err != nil {
+				msg := strings.TrimSpace(string(res.Stderr))
+				if len(msg) != 0 {
+					err = fmt.Errorf("read partition table failed, %s", msg)
+				}
+				return nil, errors.Join(applyErr, err)
+			}
+		}
+		s.password = sudo.Password
+		return s, applyErr
+	})
+}
+
+func (p *Page) handleResult(res job.Result[*state]) {
+	if errors.Is(res.Err, context.Canceled) {
+		return
+	}
+	if s := res.Value; s != nil {
+		p.devices = s.devices
+		p.fs.SetFstab(s.fstab)
+		p.sudoPassword = s.password
+		p.table = s.table
+		p.selected = -1
+		if p.table != nil {
+			p.segments = make([]widget.Clickable, len(p.table.Parts))
+			p.rows = make([]widget.Clickable, len(p.table.Parts))
+		}
+	}
+	if res.Err != nil {
+		p.fail(res.Err.Error())
+		return
+	}
+	if s := res.Value; s != nil && s.disk != p.disk.Value {
+		p.table = nil
+		p.load(p.disk.Value, nil)
+	}
+}
+
+// device returns the chosen disk as listed by lsblk.
+func (p *Page) device() (*utils.BlockDevice, bool) {
+	for i := range p.devices {
+		if p.devices[i].Name == p.disk.Value {
+			return &p.devices[i], true
+		}
+	}
+	return nil, false
+}
+
+// partDevice returns the lsblk device of the selected partition.
+func (p *Page) partDevice() (*utils.BlockDevice, bool) {
+	if p.table == nil || p.selected < 0 || p.selected >= len(p.table.Parts) || p.table.Parts[p.selected].Free {
+		return nil, false
+	}
+	name := utils.PartitionPath(p.table.Device, p.table.Parts[p.selected].Number)
+	return utils.FindDevice(p.devices, name)
+}
+
+// selectPart selects a partition or free region and fills in the inputs
+// for the operations on it.
+func (p *Page) selectPart(i int) {
+	p.selected = i
+	part := p.table.Parts[i]
+	if part.Free {
+		// 空闲区域按 MiB 对齐，开头向上取整，结尾向下取整
+		start := max((part.Start+utils.MiB-1)/utils.MiB, 1)
+		end := (part.End + 1) / utils.MiB
+		p.startInput.SetText(strconv.FormatInt(start, 10))
+		p.endInput.SetText(strconv.FormatInt(end, 10))
+		return
+	}
+	p.resizeInput.SetText(strconv.FormatInt((part.End+1)/utils.MiB, 10))
+	if dev, ok := p.partDevice(); ok {
+		p.fs.Select(dev)
+	}
+}
+
+// confirm shows the partition table change cmds with a warning and runs
+// them once confirmed. It refuses disks that are in use.
+func (p *Page) confirm(warning string, cmds ...string) {
+	dev, ok := p.device()
+	if !ok {
+		p.fail(fmt.Sprintf("%s not found, load the disks again", p.disk.Value))
+		return
+	}
+	cmds = append(cmds, utils.PartprobeCmd(dev.Name))
+	p.confirmOp(warning, dev, &fstools.Operation{Device: dev.Name, Check: (*utils.BlockDevice).Busy, Cmds: cmds})
+}
+
+// confirmOp checks dev with the check of op, shows the commands and runs
+// them once confirmed. A nil dev is not checked.
+func (p *Page) confirmOp(warning string, dev *utils.BlockDevice, op *fstools.Operation) {
+	if dev != nil {
+		if reason := op.Check(dev); len(reason) != 0 {
+			p.fail(fmt.Sprintf("refusing to change %s, %s", dev.Name, reason))
+			return
+		}
+	}
+	disk := p.disk.Value
+	p.dialog.Confirm(warning, op.Cmds, func() { p.load(disk, op) })
+}
+
+func (p *Page) mklabel() {
+	cmd, err := utils.MklabelCmd(p.table.Device, p.label.Value)
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	p.confirm(fmt.Sprintf("create a new %s partition table on %s? All partitions on it are lost.", p.label.Value, p.table.Device), cmd)
+}
+
+func (p *Page) mkpart() {
+	start, err1 := strconv.ParseInt(strings.TrimSpace(p.startInput.Text()), 10, 64)
+	end, err2 := strconv.ParseInt(strings.TrimSpace(p.endInput.Text()), 10, 64)
+	if err1 != nil || err2 != nil {
+		p.fail("start and end must be numbers of MiB")
+		return
+	}
+	cmd, err := utils.MkpartCmd(p.table, strings.TrimSpace(p.nameInput.Text()), start, end)
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	p.confirm(fmt.Sprintf("add a %s partition to %s?", common.FormatSize((end-start)*utils.MiB), p.table.Device), cmd)
+}
+
+// part returns the selected partition, it fails when free space or
+// nothing is selected.
+func (p *Page) part() (utils.Partition, bool) {
+	if p.selected < 0 || p.selected >= len(p.table.Parts) || p.table.Parts[p.selected].Free {
+		p.fail("select a partition first")
+		return utils.Partition{}, false
+	}
+	return p.table.Parts[p.selected], true
+}
+
+func (p *Page) resize() {
+	part, ok := p.part()
+	if !ok {
+		return
+	}
+	end, err := strconv.ParseInt(strings.TrimSpace(p.resizeInput.Text()), 10, 64)
+	if err != nil {
+		p.fail("the new end must be a number of MiB")
+		return
+	}
+	cmd, err := utils.ResizepartCmd(p.table, part.Number, end)
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	warning := fmt.Sprintf("resize partition %d of %s to %s?", part.Number, p.table.Device, common.FormatSize(end*utils.MiB-part.Start))
+	if end*utils.MiB < part.End+1 {
+		warning += " Shrink the filesystem first or data is lost."
+	} else {
+		warning += " The filesystem has to be grown afterwards."
+	}
+	p.confirm(warning, cmd)
+}
+
+func (p *Page) rmpart() {
+	part, ok := p.part()
+	if !ok {
+		return
+	}
+	cmd, err := utils.RmpartCmd(p.table, part.Number)
+	if err != nil {
+		p.fail(err.Error())
+		return
+	}
+	p.confirm(fmt.Sprintf("delete partition %d of %s? The data on it is lost.", part.Number, p.table.Device), cmd)
+}
+
+// layoutDisks draws a radio button for every disk, disks that must not be
+// changed show why.
+func (p *Page) layoutDisks(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	var children []layout.FlexChild
+	for i := range p.devices {
+		dev := &p.devices[i]
+		if dev.Type != "disk" {
+			continue
+		}
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			desc := fmt.Sprintf("%s  %s  %s", dev.Name, common.FormatSize(dev.Size), strings.TrimSpace(dev.Vendor+" "+dev.Model))
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(material.RadioButton(th, &p.disk, dev.Name, desc).Layout),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					reason := dev.Busy()
+					if len(reason) == 0 {
+						return layout.Dimensions{}
+					}
+					lbl := material.Caption(th, reason)
+					lbl.Color = common.DangerColor
+					return lbl.Layout(gtx)
+				}),
+			)
+		}))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+func (p *Page) layoutTable(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	t := p.table
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			desc := fmt.Sprintf("%s  %s  %s  label: %s", t.Device, common.FormatSize(t.Size), t.Model, t.Label)
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, material.Body1(th, desc).Layout)
+		}),
+		// 分区布局条
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(10)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutBar(gtx, th)
+			})
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(10)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutTools(gtx, th)
+			})
+		}),
+		// 选中分区的文件系统
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			dev, ok := p.partDevice()
+			if !ok {
+				return layout.Dimensions{}
+			}
+			return p.fs.Layout(gtx, th, dev)
+		}),
+		// 表头
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			header := make([]layout.Widget, len(columns))
+			for i, c := range columns {
+				lbl := material.Body2(th, c.name)
+				lbl.Font.Weight = font.Bold
+				header[i] = lbl.Layout
+			}
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return cells(gtx, header...)
+			})
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return material.List(th, &p.list).Layout(gtx, len(t.Parts), func(gtx layout.Context, i int) layout.Dimensions {
+				return p.layoutPart(gtx, th, i)
+			})
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return p.fs.LayoutFstab(gtx, th, p.devices)
+		}),
+	)
+}
+
+// layoutBar draws the partitions and free space of the disk in proportion
+// to their size, clicking a region selects it.
+func (p *Page) layoutBar(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	t := p.table
+	height := gtx.Dp(40)
+	children := make([]layout.FlexChild, len(t.Parts))
+	for i, part := range t.Parts {
+		// 很小的区域也保留一点宽度，以便点击
+		weight := max(float32(part.Size)/float32(max(t.Size, 1)), 0.02)
+		bg := freeColor
+		label := "free"
+		if !part.Free {
+			bg = partColors[(part.Number-1+len(partColors))%len(partColors)]
+			label = strconv.Itoa(part.Number)
+		}
+		label += " " + common.FormatSize(part.Size)
+		children[i] = layout.Flexed(weight, func(gtx layout.Context) layout.Dimensions {
+			return material.Clickable(gtx, &p.segments[i], func(gtx layout.Context) layout.Dimensions {
+				size := image.Pt(gtx.Constraints.Max.X, height)
+				defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+				paint.Fill(gtx.Ops, bg)
+				if i == p.selected {
+					paint.FillShape(gtx.Ops, color.NRGBA{A: 255}, clip.Stroke{Path: clip.Rect{Max: size}.Path(), Width: float32(gtx.Dp(3))}.Op())
+				}
+				gtx.Constraints = layout.Exact(size)
+				layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					lbl := material.Caption(th, label)
+					lbl.MaxLines = 1
+					lbl.Alignment = text.Middle
+					return lbl.Layout(gtx)
+				})
+				return layout.Dimensions{Size: size}
+			})
+		})
+	}
+	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+}
+
+// layoutTools draws the operations: a new partition table, adding a
+// partition in free space, and resizing or deleting the selected one.
+func (p *Page) layoutTools(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	row := func(gtx layout.Context, children ...layout.FlexChild) layout.Dimensions {
+		return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+		})
+	}
+	gap := layout.Rigid(layout.Spacer{Width: 10}.Layout)
+	danger := func(btn *widget.Clickable, txt string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			b := material.Button(th, btn, txt)
+			b.Background = common.DangerColor
+			return b.Layout(gtx)
+		})
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		// 分区表类型
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			children := []layout.FlexChild{
+				layout.Rigid(material.Body2(th, "partition table").Layout),
+				gap,
+			}
+			for _, l := range utils.DiskLabels {
+				children = append(children, layout.Rigid(material.RadioButton(th, &p.label, l, l).Layout), gap)
+			}
+			children = append(children, danger(&p.labelButton, "create partition table"))
+			return row(gtx, children...)
+		}),
+		// 新建分区
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return row(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.startInput, "start MiB", 100)
+				}),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.endInput, "end MiB", 100)
+				}),
+				gap,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.nameInput, "name (gpt)", 160)
+				}),
+				gap,
+				layout.Rigid(material.Button(th, &p.addButton, "add partition").Layout),
+			)
+		}),
+		// 选中分区的操作
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return row(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &p.resizeInput, "new end MiB", 100)
+				}),
+				gap,
+				layout.Rigid(material.Button(th, &p.resizeButton, "resize").Layout),
+				gap,
+				danger(&p.deleteButton, "delete partition"),
+			)
+		}),
+	)
+}
+
+// cells lays out a table row with the column widths, the name column takes
+// the remaining space.
+func cells(gtx layout.Context, widgets ...layout.Widget) layout.Dimensions {
+	children := make([]layout.FlexChild, 0, len(widgets))
+	for i, w := range widgets {
+		width := columns[i].width
+		if width == 0 {
+			children = append(children, layout.Flexed(1, w))
+			continue
+		}
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(width)
+			gtx.Constraints.Max.X = gtx.Dp(width)
+			return w(gtx)
+		}))
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+}
+
+func (p *Page) layoutPart(gtx layout.Context, th *material.Theme, i int) layout.Dimensions {
+	part := p.table.Parts[i]
+	number := strconv.Itoa(part.Number)
+	fstype := part.FSType
+	if part.Free {
+		number, fstype = "-", "free"
+	}
+	return material.Clickable(gtx, &p.rows[i], func(gtx layout.Context) layout.Dimensions {
+		// 选中的行加背景色
+		return layout.Background{}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			if i == p.selected {
+				paint.FillShape(gtx.Ops, selectedColor, clip.Rect{Max: gtx.Constraints.Min}.Op())
+			}
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}, func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return cells(gtx,
+					material.Body2(th, number).Layout,
+					material.Body2(th, fmt.Sprintf("%d MiB", part.Start/utils.MiB)).Layout,
+					material.Body2(th, fmt.Sprintf("%d MiB", (part.End+1)/utils.MiB)).Layout,
+					material.Body2(th, common.FormatSize(part.Size)).Layout,
+					material.Body2(th, fstype).Layout,
+					material.Body2(th, part.Name).Layout,
+					material.Body2(th, part.Flags).Layout,
+				)
+			})
+		})
+	})
+}
+
+func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.Clickable, txt string) layout.Dimensions {
+	gtx.Constraints.Min.X = gtx.Dp(width)
+	gtx.Constraints.Max.X = gtx.Dp(width)
+	return material.Button(th, wid, txt).Layout(gtx)
+}
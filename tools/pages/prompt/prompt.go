@@ -0,0 +1,186 @@
+package prompt
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"sync"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+type reply struct {
+	text string
+	ok   bool
+}
+
+type request struct {
+	question string
+	echo     bool
+	answer   chan reply
+}
+
+// Prompter asks the user for a line of text, e.g. a key passphrase. Ask is
+// called from background jobs and blocks, Layout draws the pending question
+// on top of whatever page is shown.
+type Prompter struct {
+	invalidate   func()
+	mu           sync.Mutex
+	queue        []*request
+	shown        *request
+	input        widget.Editor
+	okButton     widget.Clickable
+	cancelButton widget.Clickable
+}
+
+func New(invalidate func()) *Prompter {
+	p := &Prompter{invalidate: invalidate}
+	p.input.SingleLine = true
+	p.input.Submit = true
+	return p
+}
+
+// Ask implements sshclient.Prompt.
+func (p *Prompter) Ask(ctx context.Context, question string, echo bool) (string, bool) {
+	req := &request{
+		question: question,
+		echo:     echo,
+		answer:   make(chan reply, 1),
+	}
+	p.mu.Lock()
+	p.queue = append(p.queue, req)
+	p.mu.Unlock()
+	p.invalidate()
+
+	select {
+	case r := <-req.answer:
+		return r.text, r.ok
+	case <-ctx.Done():
+		p.remove(req)
+		return "", false
+	}
+}
+
+func (p *Prompter) remove(req *request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, r := range p.queue {
+		if r == req {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			break
+		}
+	}
+	p.invalidate()
+}
+
+func (p *Prompter) current() *request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 {
+		return nil
+	}
+	return p.queue[0]
+}
+
+func (p *Prompter) answer(req *request, ok bool) {
+	req.answer <- reply{text: p.input.Text(), ok: ok}
+	p.input.SetText("")
+	p.remove(req)
+}
+
+func (p *Prompter) Layout(gtx layout.Context, th *material.Theme) {
+	req := p.current()
+	if req == nil {
+		p.shown = nil
+		return
+	}
+	if req != p.shown {
+		// 新的问题，清空输入并获取焦点
+		p.shown = req
+		p.input.SetText("")
+		gtx.Execute(key.FocusCmd{Tag: &p.input})
+	}
+	for {
+		ev, ok := p.input.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := ev.(widget.SubmitEvent); ok {
+			p.answer(req, true)
+			return
+		}
+	}
+	if p.okButton.Clicked(gtx) {
+		p.answer(req, true)
+		return
+	}
+	if p.cancelButton.Clicked(gtx) {
+		p.answer(req, false)
+		return
+	}
+
+	// 全屏遮罩，同时拦截下层页面的点击
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	event.Op(gtx.Ops, p)
+	for {
+		if _, ok := gtx.Event(pointer.Filter{Target: p, Kinds: pointer.Press | pointer.Release}); !ok {
+			break
+		}
+	}
+	full.Pop()
+
+	// 窗口大小和位置（居中）
+	boxW := min(gtx.Constraints.Max.X-80, 480)
+	boxH := 200
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	offset := op.Offset(rect.Min).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	if req.echo {
+		p.input.Mask = 0
+	} else {
+		p.input.Mask = '*'
+	}
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(material.Body1(th, req.question).Layout),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(10)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return widget.Border{
+					Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
+					Width: unit.Dp(1),
+				}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.Editor(th, &p.input, "").Layout)
+				})
+			}),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+					layout.Rigid(material.Button(th, &p.okButton, "ok").Layout),
+					layout.Rigid(layout.Spacer{Width: unit.Dp(10)}.Layout),
+					layout.Rigid(material.Button(th, &p.cancelButton, "cancel").Layout),
+				)
+			}),
+		)
+	})
+	offset.Pop()
+}
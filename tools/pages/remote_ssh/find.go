@@ -0,0 +1,320 @@
+package remotessh
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"regexp"
+	"strings"
+	"tools/pages/connform"
+	"unicode/utf8"
+
+	"gioui.org/io/clipboard"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// findBar holds the find bar, the grep filter and the copy buttons of the
+// result area.
+type findBar struct {
+	open        bool
+	input       widget.Editor
+	openButton  widget.Clickable
+	prevButton  widget.Clickable
+	nextButton  widget.Clickable
+	closeButton widget.Clickable
+	re          *regexp.Regexp
+	grepInput   widget.Editor
+	grep        *regexp.Regexp
+	grepErr     string
+	copyAll     widget.Clickable
+	copySel     widget.Clickable
+	// edMatches 缓存纯文本输出框中的查找结果（按字符计算的位置），
+	// 输出框内容或查找内容变化后失效
+	edMatches [][2]int
+	edValid   bool
+}
+
+func (f *findBar) init() {
+	f.input.SingleLine = true
+	f.input.Submit = true
+	f.grepInput.SingleLine = true
+}
+
+// findKeys opens the find bar with Ctrl+F, closes it with Escape and moves
+// between matches with Enter and Shift+Enter. It runs before the editors
+// are laid out so it sees their events first.
+func (p *Page) findKeys(gtx layout.Context) {
+	f := &p.find
+	for {
+		ev, ok := gtx.Event(
+			key.Filter{Name: "F", Required: key.ModShortcut},
+			key.Filter{Focus: &f.input, Name: key.NameEscape},
+			key.Filter{Focus: &f.input, Name: key.NameReturn, Required: key.ModShift},
+			key.Filter{Focus: &f.input, Name: key.NameEnter, Required: key.ModShift},
+		)
+		if !ok {
+			break
+		}
+		e, ok := ev.(key.Event)
+		if !ok || e.State != key.Press {
+			continue
+		}
+		switch e.Name {
+		case "F":
+			p.openFind(gtx)
+		case key.NameEscape:
+			p.closeFind()
+		default:
+			p.step(false)
+		}
+	}
+	for {
+		ev, ok := f.input.Update(gtx)
+		if !ok {
+			break
+		}
+		switch ev.(type) {
+		case widget.ChangeEvent:
+			p.applyFind()
+		case widget.SubmitEvent:
+			p.step(true)
+		}
+	}
+	for {
+		ev, ok := f.grepInput.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := ev.(widget.ChangeEvent); ok {
+			p.applyGrep()
+		}
+	}
+
+	if f.openButton.Clicked(gtx) {
+		p.openFind(gtx)
+	}
+	if f.closeButton.Clicked(gtx) {
+		p.closeFind()
+	}
+	if f.prevButton.Clicked(gtx) {
+		p.step(false)
+	}
+	if f.nextButton.Clicked(gtx) {
+		p.step(true)
+	}
+	if f.copyAll.Clicked(gtx) {
+		copyText(gtx, p.styled.Text())
+	}
+	if f.copySel.Clicked(gtx) {
+		copyText(gtx, p.resultEditor.SelectedText())
+	}
+}
+
+func (p *Page) openFind(gtx layout.Context) {
+	f := &p.find
+	f.open = true
+	gtx.Execute(key.FocusCmd{Tag: &f.input})
+	f.input.SetCaret(f.input.Len(), 0)
+}
+
+func (p *Page) closeFind() {
+	p.find.open = false
+	p.find.input.SetText("")
+	p.applyFind()
+}
+
+// applyFind highlights the text of the find input, matched literally and
+// ignoring case.
+func (p *Page) applyFind() {
+	f := &p.find
+	f.re = nil
+	if q := f.input.Text(); len(q) != 0 {
+		f.re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(q))
+	}
+	f.edValid = false
+	p.styled.SetFind(f.re)
+}
+
+// applyGrep shows only the output lines matching the grep expression, an
+// invalid expression keeps the previous filter.
+func (p *Page) applyGrep() {
+	f := &p.find
+	f.grepErr = ""
+	var re *regexp.Regexp
+	if expr := f.grepInput.Text(); len(expr) != 0 {
+		var err error
+		if re, err = regexp.Compile(expr); err != nil {
+			f.grepErr = fmt.Sprintf("invalid expression, %v", err)
+			return
+		}
+	}
+	f.grep = re
+	p.styled.SetFilter(re)
+	p.resultEditor.SetText(p.styled.Text())
+	f.edValid = false
+}
+
+// grepLines keeps the lines of text matching the grep filter.
+func (f *findBar) grepLines(text string) string {
+	if f.grep == nil || len(text) == 0 {
+		return text
+	}
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if len(line) != 0 && f.grep.MatchString(strings.TrimSuffix(line, "\n")) {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// step moves to the next or previous match in whichever view is shown.
+func (p *Page) step(forward bool) {
+	if !p.strip.Value {
+		p.styled.NextMatch(forward)
+		return
+	}
+	matches := p.editorMatches()
+	if len(matches) == 0 {
+		return
+	}
+	cur := p.editorCurrent(matches)
+	_, caret := p.resultEditor.Selection()
+	next := 0
+	switch {
+	case cur >= 0 && forward:
+		next = (cur + 1) % len(matches)
+	case cur >= 0:
+		next = (cur + len(matches) - 1) % len(matches)
+	case forward:
+		// 从光标位置开始查找
+		for next < len(matches) && matches[next][0] < caret {
+			next++
+		}
+		next %= len(matches)
+	default:
+		next = len(matches) - 1
+		for next >= 0 && matches[next][1] > caret {
+			next--
+		}
+		next = (next + len(matches)) % len(matches)
+	}
+	// 选中结果，输出框会滚动到光标处
+	p.resultEditor.SetCaret(matches[next][0], matches[next][1])
+}
+
+// editorMatches returns the matches in the plain text editor in runes.
+func (p *Page) editorMatches() [][2]int {
+	f := &p.find
+	if f.re == nil {
+		return nil
+	}
+	if f.edValid {
+		return f.edMatches
+	}
+	text := p.resultEditor.Text()
+	f.edMatches = f.edMatches[:0]
+	prev, runes := 0, 0
+	for _, loc := range f.re.FindAllStringIndex(text, -1) {
+		runes += utf8.RuneCountInString(text[prev:loc[0]])
+		start := runes
+		runes += utf8.RuneCountInString(text[loc[0]:loc[1]])
+		f.edMatches = append(f.edMatches, [2]int{start, runes})
+		prev = loc[1]
+	}
+	f.edValid = true
+	return f.edMatches
+}
+
+// editorCurrent returns the match selected in the editor, or -1.
+func (p *Page) editorCurrent(matches [][2]int) int {
+	start, end := p.resultEditor.Selection()
+	for i, m := range matches {
+		if m[0] == min(start, end) && m[1] == max(start, end) {
+			return i
+		}
+	}
+	return -1
+}
+
+func copyText(gtx layout.Context, text string) {
+	gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
+}
+
+// layoutTools draws the grep filter, the copy buttons and the find bar.
+func (p *Page) layoutTools(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	f := &p.find
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &f.grepInput, "grep (regular expression)", 240)
+				}),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(material.Button(th, &f.openButton, "find").Layout),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(material.Button(th, &f.copyAll, "copy all").Layout),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				// 只有纯文本输出框可以选择文字
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					if !p.strip.Value {
+						return layout.Dimensions{}
+					}
+					return material.Button(th, &f.copySel, "copy selection").Layout(gtx)
+				}),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					if len(f.grepErr) == 0 {
+						return layout.Dimensions{}
+					}
+					lbl := material.Caption(th, f.grepErr)
+					lbl.Color = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+					return lbl.Layout(gtx)
+				}),
+			)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !f.open {
+				return layout.Dimensions{}
+			}
+			return layout.Inset{Top: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return connform.Input(gtx, th, &f.input, "find", 240)
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Button(th, &f.prevButton, "previous").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Button(th, &f.nextButton, "next").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Body2(th, p.matchCount()).Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Button(th, &f.closeButton, "close").Layout),
+				)
+			})
+		}),
+	)
+}
+
+// matchCount describes the current match, e.g. "3/17".
+func (p *Page) matchCount() string {
+	if p.find.re == nil {
+		return ""
+	}
+	cur, total := p.styled.Matches()
+	if p.strip.Value {
+		matches := p.editorMatches()
+		cur, total = p.editorCurrent(matches), len(matches)
+	}
+	switch {
+	case total == 0:
+		return "no matches"
+	case cur < 0:
+		return fmt.Sprintf("%d matches", total)
+	}
+	return fmt.Sprintf("%d/%d", cur+1, total)
+}
@@ -0,0 +1,66 @@
+package remotessh
+
+import (
+	"unicode/utf8"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+)
+
+// historyKeys browses the command history of the current host with the up
+// and down arrows while the command input is focused. The key events are
+// taken before the editor sees them, so it must run before the editor is
+// laid out.
+func (p *Page) historyKeys(gtx layout.Context) {
+	for {
+		ev, ok := gtx.Event(
+			key.Filter{Focus: &p.cmdInput, Name: key.NameUpArrow},
+			key.Filter{Focus: &p.cmdInput, Name: key.NameDownArrow},
+		)
+		if !ok {
+			break
+		}
+		if e, ok := ev.(key.Event); ok && e.State == key.Press {
+			p.browse(e.Name == key.NameUpArrow)
+		}
+	}
+}
+
+// browse shows the previous or next command of the history. Typing or
+// switching hosts starts browsing again from the newest command, the text
+// typed so far comes back after the newest one.
+func (p *Page) browse(older bool) {
+	target := p.connForm.Target()
+	if p.histCmds == nil || target != p.histTarget || p.cmdInput.Text() != p.histShown {
+		p.histCmds = p.Router.History.Commands(target)
+		p.histIdx = len(p.histCmds)
+		p.histDraft = p.cmdInput.Text()
+		p.histTarget = target
+	}
+	switch {
+	case older && p.histIdx > 0:
+		p.histIdx--
+	case !older && p.histIdx < len(p.histCmds):
+		p.histIdx++
+	default:
+		return
+	}
+	cmd := p.histDraft
+	if p.histIdx < len(p.histCmds) {
+		cmd = p.histCmds[p.histIdx]
+	}
+	p.cmdInput.SetText(cmd)
+	// 光标位置按字符计算
+	n := utf8.RuneCountInString(cmd)
+	p.cmdInput.SetCaret(n, n)
+	p.histShown = cmd
+}
+
+// remember adds cmd to the history of the current host.
+func (p *Page) remember(cmd string) {
+	p.histCmds = nil
+	if err := p.Router.History.Add(p.connForm.Target(), cmd); err != nil {
+		p.confirmMsg = err.Error()
+		p.showDialog = true
+	}
+}
@@ -0,0 +1,99 @@
+package remotessh
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gioui.org/widget"
+)
+
+// maxScrollback 限制结果区域保留的字符数，超出后丢弃最早的输出
+const maxScrollback = 1 << 20
+
+// output collects streamed lines on the job goroutine until the UI
+// goroutine takes them.
+type output struct {
+	mu     sync.Mutex
+	stdout strings.Builder
+	stderr strings.Builder
+	// cleared 表示界面需要先清掉已经取走的输出
+	cleared    bool
+	invalidate func()
+}
+
+func (o *output) add(line string, stderr bool) {
+	o.mu.Lock()
+	b := &o.stdout
+	if stderr {
+		b = &o.stderr
+	}
+	b.WriteString(line)
+	b.WriteByte('\n')
+	// 界面长时间没有取走输出时同样限制大小
+	if b.Len() > 2*maxScrollback {
+		s := trimScrollback(b.String())
+		b.Reset()
+		b.WriteString(s)
+	}
+	o.mu.Unlock()
+	o.invalidate()
+}
+
+// clear drops the collected lines and tells the UI goroutine to drop the
+// ones it already took, for a command that starts over.
+func (o *output) clear() {
+	o.mu.Lock()
+	o.stdout.Reset()
+	o.stderr.Reset()
+	o.cleared = true
+	o.mu.Unlock()
+	o.invalidate()
+}
+
+// take returns and clears the lines collected since the last call, cleared
+// reports whether the output shown so far has to be dropped first.
+func (o *output) take() (stdout, stderr string, cleared bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	stdout, stderr, cleared = o.stdout.String(), o.stderr.String(), o.cleared
+	o.stdout.Reset()
+	o.stderr.Reset()
+	o.cleared = false
+	return stdout, stderr, cleared
+}
+
+// trimScrollback keeps about the last three quarters of maxScrollback,
+// starting at a line boundary.
+func trimScrollback(s string) string {
+	if len(s) <= maxScrollback {
+		return s
+	}
+	s = s[len(s)-maxScrollback*3/4:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return s
+}
+
+// appendOutput adds text at the end of ed. With follow the caret moves
+// there, which scrolls the editor to the new output, otherwise the caret
+// and the selection stay where they were.
+func appendOutput(ed *widget.Editor, text string, follow bool) {
+	if len(text) == 0 {
+		return
+	}
+	start, end := ed.Selection()
+	if ed.Len()+utf8.RuneCountInString(text) > maxScrollback {
+		// 删掉开头的内容后原来的位置已经没有意义，直接放到末尾
+		ed.SetText(trimScrollback(ed.Text() + text))
+		ed.SetCaret(ed.Len(), ed.Len())
+		return
+	}
+	ed.SetCaret(ed.Len(), ed.Len())
+	ed.Insert(text)
+	if !follow {
+		// 光标仍在可见范围内时不会滚动
+		ed.SetCaret(start, end)
+	}
+}
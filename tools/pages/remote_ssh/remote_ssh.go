@@ -1,12 +1,19 @@
 package remotessh
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
-	"strings"
 	"tools/icon"
+	"tools/job"
 	page "tools/pages"
+	"tools/pages/ansiview"
+	"tools/pages/common"
+	"tools/pages/connform"
+	"tools/sshclient"
+	"tools/vt"
 
 	"gioui.org/layout"
 	"gioui.org/op"
@@ -17,31 +24,61 @@ import (
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 	"gioui.org/x/component"
-	"golang.org/x/crypto/ssh"
 )
 
 type Page struct {
-	remoteIpInput widget.Editor
-	usernameInput widget.Editor
-	passwordInput widget.Editor
-	cmdInput      widget.Editor
-	execButton    widget.Clickable
-	modalButton   widget.Clickable
-	showDialog    bool
-	confirmMsg    string
-	resultEditor  widget.Editor
+	connForm     *connform.Form
+	cmdInput     widget.Editor
+	execButton   widget.Clickable
+	cancelButton widget.Clickable
+	modalButton  widget.Clickable
+	showDialog   bool
+	confirmMsg   string
+	// styled 显示带颜色的输出，勾选 strip 时改为显示去掉转义序列的
+	// resultEditor，便于选择和复制
+	styled       *ansiview.View
+	strip        widget.Bool
+	resultEditor widget.Editor
+	stderrEditor widget.Editor
+	// follow 为 true 时新输出追加后滚动到末尾，否则保持当前位置
+	follow widget.Bool
+	output *output
+	// started 表示已经执行过命令，result 是最近一次执行的结果
+	started bool
+	result  *sshclient.Result
+	// stopped 是没有结果时命令结束的原因，例如被取消
+	stopped string
+	runner  *job.Runner[*sshclient.Result]
+	// 浏览历史命令时的状态，histShown 是最后填入输入框的命令
+	histCmds   []string
+	histIdx    int
+	histDraft  string
+	histTarget string
+	histShown  string
+	snippets   snippets
+	find       findBar
+	// useSudo 勾选时以 root 执行命令，sudo 保存输入过的 sudo 密码
+	useSudo widget.Bool
+	sudo    sudoPasswords
 	*page.Router
 }
 
 func New(router *page.Router) *Page {
 	page := &Page{
 		Router: router,
+		runner: job.NewRunner[*sshclient.Result](router.Invalidate),
+		output: &output{invalidate: router.Invalidate},
+		styled: &ansiview.View{Limit: maxScrollback},
 	}
-	page.remoteIpInput.SingleLine = true
-	page.usernameInput.SingleLine = true
-	page.passwordInput.SingleLine = true
+	page.follow.Value = true
+	page.styled.SetFollow(true)
+	page.connForm = connform.New(router)
+	page.snippets.init()
+	page.find.init()
 	page.resultEditor.ReadOnly = true
 	page.resultEditor.WrapPolicy = text.WrapGraphemes
+	page.stderrEditor.ReadOnly = true
+	page.stderrEditor.WrapPolicy = text.WrapGraphemes
 	return page
 }
 
@@ -63,76 +100,25 @@ func (p *Page) NavItem() component.NavItem {
 }
 
 func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	// 方向键先于输入框处理，用于浏览历史命令
+	p.historyKeys(gtx)
+	p.findKeys(gtx)
+	// 先取走流式输出，再在 UI 协程中处理后台任务的结果
+	p.takeOutput(gtx)
+	if res, ok := p.runner.Poll(); ok {
+		p.takeOutput(gtx)
+		p.handleResult(res)
+	}
+
 	mainPage := layout.Flex{
 		Axis:      layout.Vertical,
 		Alignment: layout.Middle,
 	}
 	mainPage.Layout(gtx,
-		layout.Rigid(
-			func(gtx layout.Context) layout.Dimensions {
-				return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-					return layout.Flex{
-						Axis:      layout.Horizontal,
-						Alignment: layout.Middle,
-						Spacing:   layout.SpaceSides,
-					}.Layout(gtx,
-						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							return widget.Border{
-								Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
-								Width: unit.Dp(1),
-							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								return layout.Inset{
-									Top:    unit.Dp(5),
-									Bottom: unit.Dp(5),
-									Left:   unit.Dp(5),
-								}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-									gtx.Constraints.Min.X = gtx.Dp(280)
-									gtx.Constraints.Max.X = gtx.Dp(280)
-									return material.Editor(th, &p.remoteIpInput, "remote ip address").Layout(gtx)
-								})
-							})
-						}),
-						layout.Rigid(layout.Spacer{Width: 10}.Layout),
-						// 用户名输入框
-						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							return widget.Border{
-								Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
-								Width: unit.Dp(1),
-							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								return layout.Inset{
-									Top:    unit.Dp(5),
-									Bottom: unit.Dp(5),
-									Left:   unit.Dp(5),
-								}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-									gtx.Constraints.Min.X = gtx.Dp(200)
-									gtx.Constraints.Max.X = gtx.Dp(200)
-									return material.Editor(th, &p.usernameInput, "user name").Layout(gtx)
-								})
-							})
-						}),
-						layout.Rigid(layout.Spacer{Width: 10}.Layout),
-						// 密码输入框
-						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-							return widget.Border{
-								Color: color.NRGBA{R: 204, G: 204, B: 204, A: 255},
-								Width: unit.Dp(1),
-							}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-								return layout.Inset{
-									Top:    unit.Dp(5),
-									Bottom: unit.Dp(5),
-									Left:   unit.Dp(5),
-								}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
-									gtx.Constraints.Min.X = gtx.Dp(200)
-									gtx.Constraints.Max.X = gtx.Dp(200)
-									p.passwordInput.Mask = '*'
-									return material.Editor(th, &p.passwordInput, "password").Layout(gtx)
-								})
-							})
-						}),
-					)
-				})
-			},
-		),
+		// 连接参数
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.connForm.Layout(gtx, th)
+		}),
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			return layout.Inset{
 				Left:   unit.Dp(10),
@@ -164,24 +150,53 @@ func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions
 		}),
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			// 点击按钮逻辑
-			if p.execButton.Clicked(gtx) {
-				p.checkInput()
-				if !p.showDialog {
-					p.executeCmd()
-				}
+			if p.execButton.Clicked(gtx) && !p.runner.Running() {
+				p.run()
+			}
+			if p.cancelButton.Clicked(gtx) {
+				p.runner.Cancel()
 			}
-			return Button(gtx, 80, th, &p.execButton, "execute")
+			if p.snippets.toggleButton.Clicked(gtx) {
+				p.snippets.show = !p.snippets.show
+			}
+			if p.runner.Running() {
+				return common.Progress(gtx, th, p.runner, &p.cancelButton)
+			}
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return Button(gtx, 80, th, &p.execButton, "execute")
+				}),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return Button(gtx, 90, th, &p.snippets.toggleButton, "snippets")
+				}),
+				layout.Rigid(layout.Spacer{Width: 10}.Layout),
+				layout.Rigid(material.CheckBox(th, &p.useSudo, "sudo").Layout),
+			)
+		}),
+		// 代码片段
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !p.snippets.show {
+				return layout.Dimensions{}
+			}
+			return p.layoutSnippets(gtx, th)
 		}),
 		// 结果显示区域（占满剩余空间）
 		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
-			if len(p.resultEditor.Text()) == 0 {
+			if !p.started {
 				return layout.Dimensions{}
 			}
 			in := layout.UniformInset(unit.Dp(8))
-			return in.Layout(gtx, material.Editor(th, &p.resultEditor, "result").Layout)
+			return in.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutResult(gtx, th)
+			})
 		}),
 	)
 
+	// 代码片段参数
+	if p.snippets.chosen != nil {
+		p.drawParamsDialog(gtx, th)
+	}
 	// 弹出对话框
 	if p.showDialog {
 		p.drawConfirmDialog(gtx, th)
@@ -190,16 +205,20 @@ func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions
 	return mainPage.Layout(gtx)
 }
 
+// run checks the inputs and executes the command.
+func (p *Page) run() {
+	if p.runner.Running() {
+		return
+	}
+	p.checkInput()
+	if !p.showDialog {
+		p.executeCmd()
+	}
+}
+
 func (p *Page) checkInput() {
-	itemName := ""
-	switch {
-	case p.remoteIpInput.Text() == "":
-		itemName = "ip address"
-	case p.usernameInput.Text() == "":
-		itemName = "login user name"
-	case p.passwordInput.Text() == "":
-		itemName = "login user password"
-	case p.cmdInput.Text() == "":
+	itemName := p.connForm.Missing()
+	if len(itemName) == 0 && p.cmdInput.Text() == "" {
 		itemName = "command"
 	}
 	if len(itemName) != 0 {
@@ -250,41 +269,140 @@ func (p *Page) drawConfirmDialog(gtx layout.Context, th *material.Theme) {
 }
 
 func (p *Page) executeCmd() {
-
-	config := &ssh.ClientConfig{
-		User: p.usernameInput.Text(),
-		Auth: []ssh.AuthMethod{
-			ssh.Password(p.passwordInput.Text()),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
-	host := p.remoteIpInput.Text()
-	if len(strings.Split(host, ":")) == 1 {
-		host += ":22"
-	}
-	conn, err := ssh.Dial("tcp", host, config)
+	// 编辑框只能在 UI 协程中读取，先取出参数再交给后台任务
+	cfg, err := p.connForm.Config()
 	if err != nil {
-		p.confirmMsg = fmt.Sprintf("dail %s failed, %v", host, err)
+		p.confirmMsg = err.Error()
 		p.showDialog = true
 		return
 	}
-	defer conn.Close()
+	cmd := p.cmdInput.Text()
+	useSudo, target := p.useSudo.Value, p.connForm.Target()
+	p.output.take()
+	p.started = true
+	p.result, p.stopped = nil, ""
+	p.styled.Reset()
+	p.find.edValid = false
+	p.resultEditor.SetText("")
+	p.stderrEditor.SetText("")
+	p.runner.Start(func(ctx context.Context, report func(string)) (*sshclient.Result, error) {
+		report(fmt.Sprintf("connecting to %s", cfg.Host))
+		client, release, err := p.Router.Pool.Get(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
 
-	session, err := conn.NewSession()
-	if err != nil {
-		p.confirmMsg = fmt.Sprintf("create session failed, %v", err)
-		p.showDialog = true
-		return
+		if useSudo {
+			report(fmt.Sprintf("running %q with sudo", cmd))
+			return p.streamSudo(ctx, client, target, cfg.Password, cmd, report)
+		}
+		report(fmt.Sprintf("running %q", cmd))
+		return client.Stream(ctx, cmd, p.output.add)
+	})
+	p.remember(cmd)
+}
+
+// takeOutput appends the streamed lines to the result editors, scrolling
+// to the end only while follow is on.
+func (p *Page) takeOutput(gtx layout.Context) {
+	stdout, stderr, cleared := p.output.take()
+	if cleared {
+		p.styled.Reset()
+		p.find.edValid = false
+		p.resultEditor.SetText("")
+		p.stderrEditor.SetText("")
+	}
+	if p.follow.Update(gtx) && p.follow.Value {
+		// 重新开启跟随时滚动到已有输出的末尾
+		p.resultEditor.SetCaret(p.resultEditor.Len(), p.resultEditor.Len())
+		p.stderrEditor.SetCaret(p.stderrEditor.Len(), p.stderrEditor.Len())
 	}
-	defer session.Close()
+	p.styled.SetFollow(p.follow.Value)
+	if len(stdout) != 0 {
+		p.find.edValid = false
+	}
+	p.styled.Append(stdout)
+	appendOutput(&p.resultEditor, p.find.grepLines(vt.StripANSI(stdout)), p.follow.Value)
+	appendOutput(&p.stderrEditor, vt.StripANSI(stderr), p.follow.Value)
+}
 
-	output, err := session.CombinedOutput(p.cmdInput.Text())
-	if err != nil {
-		p.confirmMsg = fmt.Sprintf("execute command failed, %v", err)
-		p.showDialog = true
+func (p *Page) handleResult(res job.Result[*sshclient.Result]) {
+	if errors.Is(res.Err, context.Canceled) {
+		p.stopped = "cancelled"
 		return
 	}
-	p.resultEditor.SetText(string(output))
+	// 连接中断时也保留已经收到的输出
+	if res.Value != nil {
+		p.result = res.Value
+	} else if res.Err != nil {
+		p.stopped = "failed"
+	}
+	if res.Err != nil {
+		p.confirmMsg = res.Err.Error()
+		p.showDialog = true
+	}
+}
+
+// layoutResult draws the exit status with the follow toggle, stdout and,
+// when there is any, stderr highlighted below it.
+func (p *Page) layoutResult(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if p.result == nil && len(p.stopped) != 0 {
+							lbl := material.Body2(th, p.stopped)
+							lbl.Color = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+							return lbl.Layout(gtx)
+						}
+						if p.result == nil {
+							return material.Body2(th, "running").Layout(gtx)
+						}
+						lbl := material.Body2(th, p.result.Status())
+						lbl.Color = color.NRGBA{R: 30, G: 140, B: 60, A: 255}
+						if p.result.Failed() {
+							lbl.Color = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+						}
+						return lbl.Layout(gtx)
+					}),
+					layout.Rigid(layout.Spacer{Width: 20}.Layout),
+					layout.Rigid(material.CheckBox(th, &p.follow, "follow").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.CheckBox(th, &p.strip, "strip escapes").Layout),
+				)
+			})
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutTools(gtx, th)
+			})
+		}),
+		layout.Flexed(2, func(gtx layout.Context) layout.Dimensions {
+			if p.strip.Value || p.styled.Len() == 0 {
+				return material.Editor(th, &p.resultEditor, "no output").Layout(gtx)
+			}
+			return p.styled.Layout(gtx, th)
+		}),
+	}
+	if len(p.stderrEditor.Text()) != 0 {
+		children = append(children,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Inset{Top: unit.Dp(5), Bottom: unit.Dp(5)}.Layout(gtx, material.Caption(th, "stderr").Layout)
+			}),
+			layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+				// stderr 使用浅红色背景和红色文字
+				rect := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+				paint.Fill(gtx.Ops, color.NRGBA{R: 253, G: 236, B: 236, A: 255})
+				rect.Pop()
+				ed := material.Editor(th, &p.stderrEditor, "")
+				ed.Color = color.NRGBA{R: 180, G: 30, B: 30, A: 255}
+				return ed.Layout(gtx)
+			}),
+		)
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
 }
 
 func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.Clickable, txt string) layout.Dimensions {
@@ -0,0 +1,231 @@
+package remotessh
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"tools/pages/connform"
+	"tools/snippet"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// snippetRow holds the buttons of one saved snippet.
+type snippetRow struct {
+	useButton    widget.Clickable
+	deleteButton widget.Clickable
+}
+
+// snippets is the library panel and the dialog asking for the parameters
+// of the chosen snippet.
+type snippets struct {
+	toggleButton widget.Clickable
+	show         bool
+	nameInput    widget.Editor
+	cmdInput     widget.Editor
+	saveButton   widget.Clickable
+	list         widget.List
+	rows         map[string]*snippetRow
+	// chosen 是正在填写参数的代码片段
+	chosen       *snippet.Snippet
+	paramInputs  []widget.Editor
+	runButton    widget.Clickable
+	cancelButton widget.Clickable
+}
+
+func (s *snippets) init() {
+	s.nameInput.SingleLine = true
+	s.cmdInput.SingleLine = true
+	s.list.Axis = layout.Vertical
+	s.rows = make(map[string]*snippetRow)
+}
+
+func (s *snippets) row(name string) *snippetRow {
+	r, ok := s.rows[name]
+	if !ok {
+		r = new(snippetRow)
+		s.rows[name] = r
+	}
+	return r
+}
+
+// useSnippet runs a snippet, asking for its parameters first if it has any.
+func (p *Page) useSnippet(sn snippet.Snippet) {
+	params := sn.Params()
+	if len(params) == 0 {
+		p.cmdInput.SetText(sn.Command)
+		p.run()
+		return
+	}
+	p.snippets.chosen = &sn
+	p.snippets.paramInputs = make([]widget.Editor, len(params))
+	for i := range p.snippets.paramInputs {
+		p.snippets.paramInputs[i].SingleLine = true
+	}
+}
+
+// runChosen fills the parameters into the chosen snippet and runs it.
+func (p *Page) runChosen() {
+	sn := p.snippets.chosen
+	values := make(map[string]string)
+	for i, name := range sn.Params() {
+		value := p.snippets.paramInputs[i].Text()
+		if len(value) == 0 {
+			p.confirmMsg = fmt.Sprintf("%s is required", name)
+			p.showDialog = true
+			return
+		}
+		values[name] = value
+	}
+	p.snippets.chosen = nil
+	p.cmdInput.SetText(sn.Expand(values))
+	p.run()
+}
+
+// layoutSnippets draws the inputs adding a snippet and the saved snippets.
+func (p *Page) layoutSnippets(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	s := &p.snippets
+	if s.saveButton.Clicked(gtx) {
+		sn := snippet.Snippet{Name: s.nameInput.Text(), Command: s.cmdInput.Text()}
+		if err := p.Router.Snippets.Put(sn); err != nil {
+			p.confirmMsg = err.Error()
+			p.showDialog = true
+		} else {
+			s.nameInput.SetText("")
+			s.cmdInput.SetText("")
+		}
+	}
+	list := p.Router.Snippets.Snippets()
+	for _, sn := range list {
+		r := s.row(sn.Name)
+		if r.useButton.Clicked(gtx) && !p.runner.Running() {
+			p.useSnippet(sn)
+		}
+		if r.deleteButton.Clicked(gtx) {
+			if err := p.Router.Snippets.Delete(sn.Name); err != nil {
+				p.confirmMsg = err.Error()
+				p.showDialog = true
+			}
+		}
+	}
+
+	gtx.Constraints.Max.X = min(gtx.Constraints.Max.X, gtx.Dp(800))
+	gtx.Constraints.Max.Y = min(gtx.Constraints.Max.Y, gtx.Dp(240))
+	return layout.Inset{Top: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			// 新增代码片段
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return connform.Input(gtx, th, &s.nameInput, "snippet name", 160)
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return connform.Input(gtx, th, &s.cmdInput, "command, e.g. smartctl -a {{device}}", 420)
+					}),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Button(th, &s.saveButton, "save").Layout),
+				)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if len(list) == 0 {
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, material.Body2(th, "no saved snippets").Layout)
+				}
+				return material.List(th, &s.list).Layout(gtx, len(list), func(gtx layout.Context, i int) layout.Dimensions {
+					sn := list[i]
+					r := s.row(sn.Name)
+					return layout.UniformInset(unit.Dp(5)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								gtx.Constraints.Min.X = gtx.Dp(160)
+								name := material.Body1(th, sn.Name)
+								name.Font.Weight = font.Bold
+								return name.Layout(gtx)
+							}),
+							layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+								cmd := material.Body2(th, sn.Command)
+								cmd.MaxLines = 1
+								return cmd.Layout(gtx)
+							}),
+							layout.Rigid(layout.Spacer{Width: 10}.Layout),
+							layout.Rigid(material.Button(th, &r.useButton, "run").Layout),
+							layout.Rigid(layout.Spacer{Width: 10}.Layout),
+							layout.Rigid(material.Button(th, &r.deleteButton, "delete").Layout),
+						)
+					})
+				})
+			}),
+		)
+	})
+}
+
+// drawParamsDialog asks for the parameters of the chosen snippet.
+func (p *Page) drawParamsDialog(gtx layout.Context, th *material.Theme) {
+	s := &p.snippets
+	if s.runButton.Clicked(gtx) {
+		p.runChosen()
+	}
+	if s.cancelButton.Clicked(gtx) {
+		s.chosen = nil
+	}
+	if s.chosen == nil {
+		return
+	}
+	params := s.chosen.Params()
+
+	// 全屏
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	full.Pop()
+
+	// 窗口大小和位置（居中），每个参数一行
+	boxW := min(gtx.Constraints.Max.X-80, 480)
+	boxH := gtx.Dp(unit.Dp(130 + 45*len(params)))
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	// 窗口背景（白色矩形）
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		children := []layout.FlexChild{
+			layout.Rigid(material.Body1(th, s.chosen.Name).Layout),
+			layout.Rigid(material.Caption(th, s.chosen.Command).Layout),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(10)}.Layout),
+		}
+		for i, name := range params {
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return connform.Input(gtx, th, &s.paramInputs[i], name, 300)
+				})
+			}))
+		}
+		children = append(children,
+			layout.Rigid(layout.Spacer{Height: unit.Dp(10)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+					layout.Rigid(material.Button(th, &s.runButton, "run").Layout),
+					layout.Rigid(layout.Spacer{Width: 10}.Layout),
+					layout.Rigid(material.Button(th, &s.cancelButton, "cancel").Layout),
+				)
+			}),
+		)
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+	offset.Pop()
+}
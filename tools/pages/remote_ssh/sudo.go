@@ -0,0 +1,70 @@
+package remotessh
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"tools/sshclient"
+)
+
+// sudoPasswords remembers the sudo passwords that worked, per user@host.
+// They are only kept in memory for as long as the program runs.
+type sudoPasswords struct {
+	mu        sync.Mutex
+	passwords map[string]string
+}
+
+func (s *sudoPasswords) get(target string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	password, ok := s.passwords[target]
+	return password, ok
+}
+
+func (s *sudoPasswords) set(target, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.passwords == nil {
+		s.passwords = make(map[string]string)
+	}
+	s.passwords[target] = password
+}
+
+func (s *sudoPasswords) forget(target string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.passwords, target)
+}
+
+// streamSudo runs cmd as root. It tries the remembered password, then the
+// login password and asks the user when sudo wants a password it does not
+// accept. Only cmd goes into the history, the password is sent on stdin.
+func (p *Page) streamSudo(ctx context.Context, client *sshclient.Client, target, login, cmd string, report func(string)) (*sshclient.Result, error) {
+	password, ok := p.sudo.get(target)
+	if !ok {
+		password = login
+	}
+	sudo := &sshclient.Sudo{Password: password}
+	// 后台任务中等待用户输入密码
+	ask := func(ctx context.Context, question string, echo bool) (string, bool) {
+		p.sudo.forget(target)
+		report("waiting for the sudo password")
+		answer, ok := p.Prompts.Ask(ctx, question, echo)
+		if ok {
+			// 重试前清掉被拒绝的那次尝试的输出
+			p.output.clear()
+		}
+		report(fmt.Sprintf("running %q with sudo", cmd))
+		return answer, ok
+	}
+	var res *sshclient.Result
+	err := sudo.Retry(ctx, target, ask, func(s sshclient.Sudo) error {
+		var err error
+		res, err = client.StreamSudo(ctx, cmd, s, p.output.add)
+		return err
+	})
+	if err == nil && len(sudo.Password) != 0 {
+		p.sudo.set(target, sudo.Password)
+	}
+	return res, err
+}
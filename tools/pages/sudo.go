@@ -0,0 +1,43 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"tools/sshclient"
+)
+
+// SudoExec runs cmd as root, the user is asked for the sudo password when
+// sudo does not accept the one in sudo. The accepted password is kept in
+// sudo for the next commands. The result only holds the output of the
+// attempt sudo accepted.
+func (r *Router) SudoExec(ctx context.Context, client *sshclient.Client, sudo *sshclient.Sudo, target, cmd string) (*sshclient.Result, error) {
+	var res *sshclient.Result
+	err := sudo.Retry(ctx, target, r.Prompts.Ask, func(s sshclient.Sudo) error {
+		var err error
+		res, err = client.ExecSudo(ctx, cmd, s)
+		// 被拒绝的尝试只有 sudo 的输出，不能当作命令的结果
+		if sshclient.SudoRejected(err) {
+			res = nil
+		}
+		return err
+	})
+	return res, err
+}
+
+// SudoRun runs cmd as root like SudoExec, a non-zero exit is an error with
+// the command's stderr.
+func (r *Router) SudoRun(ctx context.Context, client *sshclient.Client, sudo *sshclient.Sudo, target, cmd string) ([]byte, error) {
+	res, err := r.SudoExec(ctx, client, sudo, target, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if res.Failed() {
+		msg := strings.TrimSpace(string(res.Stderr))
+		if len(msg) == 0 {
+			msg = res.Status()
+		}
+		return res.Stdout, fmt.Errorf("%s failed, %s", cmd, msg)
+	}
+	return res.Stdout, nil
+}
@@ -0,0 +1,338 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+	"tools/icon"
+	"tools/job"
+	page "tools/pages"
+	"tools/pages/common"
+	"tools/pages/connform"
+	"tools/sshclient"
+	"tools/vt"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+// session is an open shell, its output is copied into the terminal by a
+// reader goroutine and keyboard input is written by a writer goroutine so
+// the UI never blocks on the network.
+type session struct {
+	shell   *sshclient.Shell
+	release func()
+	// pending 是等待发送的键盘输入，不限长度，大段粘贴也不会丢失
+	mu      sync.Mutex
+	pending [][]byte
+	// wake 在有新的输入时通知写入协程
+	wake chan struct{}
+	// done 在远端 shell 退出或连接断开后关闭
+	done chan struct{}
+}
+
+// queue adds input for the writer goroutine without blocking.
+func (s *session) queue(b []byte) {
+	s.mu.Lock()
+	s.pending = append(s.pending, b)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued input in order and empties the queue.
+func (s *session) take() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := s.pending
+	s.pending = nil
+	return pending
+}
+
+func (s *session) close() {
+	s.shell.Close()
+	<-s.done
+	s.release()
+}
+
+// Page runs an interactive shell on a remote pseudo terminal.
+type Page struct {
+	connForm         *connform.Form
+	connectButton    widget.Clickable
+	disconnectButton widget.Clickable
+	cancelButton     widget.Clickable
+	modalButton      widget.Clickable
+	showDialog       bool
+	confirmMsg       string
+	term             *vt.Terminal
+	view             *view
+	session          *session
+	runner           *job.Runner[*session]
+	*page.Router
+}
+
+func New(router *page.Router) *Page {
+	p := &Page{
+		Router: router,
+		runner: job.NewRunner[*session](router.Invalidate),
+		term:   vt.New(80, 24),
+	}
+	p.connForm = connform.New(router)
+	p.view = &view{
+		term:   p.term,
+		send:   p.send,
+		resize: p.resize,
+	}
+	return p
+}
+
+var _ page.Page = &Page{}
+
+func (p *Page) Actions() []component.AppBarAction {
+	return []component.AppBarAction{}
+}
+
+func (p *Page) Overflow() []component.OverflowAction {
+	return []component.OverflowAction{}
+}
+
+func (p *Page) NavItem() component.NavItem {
+	return component.NavItem{
+		Name: "Terminal",
+		Icon: icon.TerminalIcon,
+	}
+}
+
+func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	// 后台任务结束后在 UI 协程中处理结果
+	if res, ok := p.runner.Poll(); ok {
+		p.handleResult(gtx, res)
+	}
+	// 远端 shell 退出后释放连接
+	if p.session != nil {
+		select {
+		case <-p.session.done:
+			p.session.close()
+			p.session = nil
+		default:
+		}
+	}
+
+	mainPage := layout.Flex{
+		Axis:      layout.Vertical,
+		Alignment: layout.Middle,
+	}
+	mainPage.Layout(gtx,
+		// 连接参数
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if p.session != nil {
+				return layout.Dimensions{}
+			}
+			return p.connForm.Layout(gtx, th)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutButtons(gtx, th)
+			})
+		}),
+		// 终端（占满剩余空间）
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return p.view.Layout(gtx, th)
+		}),
+	)
+
+	// 弹出对话框
+	if p.showDialog {
+		p.drawConfirmDialog(gtx, th)
+	}
+
+	return mainPage.Layout(gtx)
+}
+
+func (p *Page) layoutButtons(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	if p.connectButton.Clicked(gtx) && !p.runner.Running() && p.session == nil {
+		p.checkInput()
+		if !p.showDialog {
+			p.connect()
+		}
+	}
+	if p.cancelButton.Clicked(gtx) {
+		p.runner.Cancel()
+	}
+	if p.disconnectButton.Clicked(gtx) && p.session != nil {
+		p.session.close()
+		p.session = nil
+	}
+
+	if p.runner.Running() {
+		return common.Progress(gtx, th, p.runner, &p.cancelButton)
+	}
+	if p.session == nil {
+		return Button(gtx, 100, th, &p.connectButton, "connect")
+	}
+	title := p.term.Title()
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return Button(gtx, 100, th, &p.disconnectButton, "disconnect")
+		}),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(material.Body2(th, title).Layout),
+	)
+}
+
+func (p *Page) checkInput() {
+	if itemName := p.connForm.Missing(); len(itemName) != 0 {
+		p.confirmMsg = fmt.Sprintf("%s is required", itemName)
+		p.showDialog = true
+	}
+}
+
+func (p *Page) connect() {
+	// 编辑框只能在 UI 协程中读取，先取出参数再交给后台任务
+	cfg, err := p.connForm.Config()
+	if err != nil {
+		p.confirmMsg = err.Error()
+		p.showDialog = true
+		return
+	}
+	cols, rows := p.term.Size()
+	p.runner.Start(func(ctx context.Context, report func(string)) (*session, error) {
+		report(fmt.Sprintf("connecting to %s", cfg.Host))
+		client, release, err := p.Router.Pool.Get(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		report("starting shell")
+		shell, err := client.Shell(ctx, sshclient.DefaultTerm, cols, rows)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		return &session{
+			shell:   shell,
+			release: release,
+			wake:    make(chan struct{}, 1),
+			done:    make(chan struct{}),
+		}, nil
+	})
+}
+
+func (p *Page) handleResult(gtx layout.Context, res job.Result[*session]) {
+	if res.Err != nil {
+		if res.Value != nil {
+			res.Value.shell.Close()
+			res.Value.release()
+		}
+		if !errors.Is(res.Err, context.Canceled) {
+			p.confirmMsg = res.Err.Error()
+			p.showDialog = true
+		}
+		return
+	}
+
+	s := res.Value
+	p.session = s
+	p.term.Write([]byte("\x1bc"))
+	p.term.SetReply(func(b []byte) { s.shell.Write(b) })
+	// 输出写入终端，结束后通知界面
+	go func() {
+		defer close(s.done)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := s.shell.Read(buf)
+			if n > 0 {
+				p.term.Write(buf[:n])
+				p.Router.Invalidate()
+			}
+			if err != nil {
+				p.Router.Invalidate()
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-s.wake:
+				for _, b := range s.take() {
+					s.shell.Write(b)
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	// 连接时的窗口大小可能已经变化
+	s.shell.Resize(p.term.Size())
+	p.view.Focus(gtx)
+}
+
+// send queues keyboard input, it is kept until the remote side takes it.
+func (p *Page) send(b []byte) {
+	if p.session != nil {
+		p.session.queue(b)
+	}
+}
+
+func (p *Page) resize(cols, rows int) {
+	if p.session != nil {
+		p.session.shell.Resize(cols, rows)
+	}
+}
+
+func (p *Page) drawConfirmDialog(gtx layout.Context, th *material.Theme) {
+	// 全屏
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	full.Pop()
+
+	// 窗口大小和位置（居中）
+	boxW := min(gtx.Constraints.Max.X-80, 420)
+	boxH := 150
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	// 窗口背景（白色矩形）
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	// 将坐标系偏移道对话框左上角，然后在内部做正常布局
+	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(material.Body1(th, p.confirmMsg).Layout),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				btn := material.Button(th, &p.modalButton, "confirm")
+				if p.modalButton.Clicked(gtx) {
+					p.showDialog = false
+				}
+				return btn.Layout(gtx)
+			}),
+		)
+	})
+	offset.Pop()
+}
+
+func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.Clickable, txt string) layout.Dimensions {
+	gtx.Constraints.Min.X = gtx.Dp(width)
+	gtx.Constraints.Max.X = gtx.Dp(width)
+	return material.Button(th, wid, txt).Layout(gtx)
+}
@@ -0,0 +1,304 @@
+package terminal
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"tools/vt"
+
+	"gioui.org/font"
+	"gioui.org/io/clipboard"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/io/transfer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+var (
+	defaultFG   = color.NRGBA{R: 220, G: 220, B: 220, A: 255}
+	defaultBG   = color.NRGBA{R: 30, G: 30, B: 30, A: 255}
+	cursorColor = color.NRGBA{R: 200, G: 200, B: 200, A: 160}
+	monoFont    = font.Font{Typeface: "Go Mono"}
+)
+
+// view draws a vt.Terminal as a grid of monospace cells and turns key
+// presses into input for the remote shell.
+type view struct {
+	term *vt.Terminal
+	// send 发送键盘输入，resize 在网格大小变化时通知远端
+	send    func([]byte)
+	resize  func(cols, rows int)
+	focused bool
+	cols    int
+	rows    int
+	// cell 是按当前字号测量的单元格大小
+	cell     image.Point
+	cellSize unit.Sp
+	cellPx   float32
+}
+
+// Focus asks for keyboard focus on the next frame.
+func (v *view) Focus(gtx layout.Context) {
+	gtx.Execute(key.FocusCmd{Tag: v})
+}
+
+func (v *view) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	v.update(gtx)
+
+	size := gtx.Constraints.Max
+	cell := v.measure(gtx, th)
+	cols, rows := max(size.X/cell.X, 1), max(size.Y/cell.Y, 1)
+	if cols != v.cols || rows != v.rows {
+		v.cols, v.rows = cols, rows
+		v.term.Resize(cols, rows)
+		if v.resize != nil {
+			v.resize(cols, rows)
+		}
+	}
+
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	paint.Fill(gtx.Ops, defaultBG)
+	event.Op(gtx.Ops, v)
+
+	screen := v.term.Snapshot()
+	for y, line := range screen.Cells {
+		v.drawLine(gtx, th, y, line)
+	}
+	if screen.CursorVisible {
+		v.drawCursor(gtx, screen.CursorX, screen.CursorY)
+	}
+	return layout.Dimensions{Size: size}
+}
+
+func (v *view) update(gtx layout.Context) {
+	for {
+		ev, ok := gtx.Event(
+			key.FocusFilter{Target: v},
+			// Name 为空时匹配所有其它过滤器没有处理的按键
+			key.Filter{Focus: v, Optional: key.ModCtrl | key.ModShift | key.ModAlt},
+			key.Filter{Focus: v, Name: key.NameTab, Optional: key.ModShift},
+			pointer.Filter{Target: v, Kinds: pointer.Press},
+			transfer.TargetFilter{Target: v, Type: "application/text"},
+		)
+		if !ok {
+			break
+		}
+		switch e := ev.(type) {
+		case key.FocusEvent:
+			v.focused = e.Focus
+		case pointer.Event:
+			gtx.Execute(key.FocusCmd{Tag: v})
+		case key.EditEvent:
+			v.send([]byte(e.Text))
+		case key.Event:
+			if e.State != key.Press {
+				continue
+			}
+			// Ctrl+Shift+V 粘贴
+			if e.Name == "V" && e.Modifiers.Contain(key.ModCtrl|key.ModShift) {
+				gtx.Execute(clipboard.ReadCmd{Tag: v})
+				continue
+			}
+			if b := keyInput(e, v.term.AppCursorKeys()); b != nil {
+				v.send(b)
+			}
+		case transfer.DataEvent:
+			r := e.Open()
+			data, _ := io.ReadAll(r)
+			r.Close()
+			v.send([]byte(strings.ReplaceAll(string(data), "\n", "\r")))
+		}
+	}
+}
+
+// measure returns the cell size for the theme's text size.
+func (v *view) measure(gtx layout.Context, th *material.Theme) image.Point {
+	if v.cell.X != 0 && v.cellSize == th.TextSize && v.cellPx == gtx.Metric.PxPerSp {
+		return v.cell
+	}
+	macro := op.Record(gtx.Ops)
+	gtx.Constraints.Min = image.Point{}
+	dims := widget.Label{MaxLines: 1}.Layout(gtx, th.Shaper, monoFont, th.TextSize, "M", op.CallOp{})
+	macro.Stop()
+	v.cell = image.Pt(max(dims.Size.X, 1), max(dims.Size.Y, 1))
+	v.cellSize, v.cellPx = th.TextSize, gtx.Metric.PxPerSp
+	return v.cell
+}
+
+// drawLine draws runs of cells sharing the same attributes. Non ASCII
+// characters are drawn one by one so a fallback font with other advances
+// does not shift the grid.
+func (v *view) drawLine(gtx layout.Context, th *material.Theme, y int, line []vt.Cell) {
+	for x := 0; x < len(line); {
+		c := line[x]
+		if c.Rune == 0 {
+			x++
+			continue
+		}
+		var run strings.Builder
+		run.WriteRune(c.Rune)
+		end := x + 1
+		if c.Rune < 0x80 {
+			for end < len(line) && line[end].Rune != 0 && line[end].Rune < 0x80 &&
+				line[end].FG == c.FG && line[end].BG == c.BG && line[end].Attr == c.Attr {
+				run.WriteRune(line[end].Rune)
+				end++
+			}
+		} else if end < len(line) && line[end].Rune == 0 {
+			// 宽字符占两个单元格
+			end++
+		}
+		v.drawRun(gtx, th, x, y, end-x, c, run.String())
+		x = end
+	}
+}
+
+func (v *view) drawRun(gtx layout.Context, th *material.Theme, x, y, n int, c vt.Cell, txt string) {
+	fg, bg := c.Colors(defaultFG, defaultBG)
+	rect := image.Rect(x*v.cell.X, y*v.cell.Y, (x+n)*v.cell.X, (y+1)*v.cell.Y)
+	if bg != defaultBG {
+		paint.FillShape(gtx.Ops, bg, clip.Rect(rect).Op())
+	}
+	if strings.TrimSpace(txt) == "" {
+		return
+	}
+
+	fnt := monoFont
+	if c.Attr&vt.Bold != 0 {
+		fnt.Weight = font.Bold
+	}
+	if c.Attr&vt.Italic != 0 {
+		fnt.Style = font.Italic
+	}
+	offset := op.Offset(rect.Min).Push(gtx.Ops)
+	colMacro := op.Record(gtx.Ops)
+	paint.ColorOp{Color: fg}.Add(gtx.Ops)
+	textMaterial := colMacro.Stop()
+	lgtx := gtx
+	lgtx.Constraints = layout.Constraints{Max: image.Pt(gtx.Constraints.Max.X, rect.Dy())}
+	widget.Label{MaxLines: 1}.Layout(lgtx, th.Shaper, fnt, th.TextSize, txt, textMaterial)
+	offset.Pop()
+
+	// 下划线和删除线
+	if c.Attr&vt.Underline != 0 {
+		line := image.Rect(rect.Min.X, rect.Max.Y-max(1, v.cell.Y/16), rect.Max.X, rect.Max.Y)
+		paint.FillShape(gtx.Ops, fg, clip.Rect(line).Op())
+	}
+	if c.Attr&vt.Strike != 0 {
+		mid := (rect.Min.Y + rect.Max.Y) / 2
+		paint.FillShape(gtx.Ops, fg, clip.Rect(image.Rect(rect.Min.X, mid, rect.Max.X, mid+max(1, v.cell.Y/16))).Op())
+	}
+}
+
+// drawCursor draws a block cursor when focused and an outline otherwise.
+func (v *view) drawCursor(gtx layout.Context, x, y int) {
+	rect := image.Rect(x*v.cell.X, y*v.cell.Y, (x+1)*v.cell.X, (y+1)*v.cell.Y)
+	if v.focused {
+		paint.FillShape(gtx.Ops, cursorColor, clip.Rect(rect).Op())
+		return
+	}
+	w := max(1, v.cell.X/8)
+	for _, edge := range []image.Rectangle{
+		{Min: rect.Min, Max: image.Pt(rect.Max.X, rect.Min.Y+w)},
+		{Min: image.Pt(rect.Min.X, rect.Max.Y-w), Max: rect.Max},
+		{Min: rect.Min, Max: image.Pt(rect.Min.X+w, rect.Max.Y)},
+		{Min: image.Pt(rect.Max.X-w, rect.Min.Y), Max: rect.Max},
+	} {
+		paint.FillShape(gtx.Ops, cursorColor, clip.Rect(edge).Op())
+	}
+}
+
+// keyInput translates keys that do not produce text into the bytes an
+// xterm sends, printable text arrives as key.EditEvent instead.
+func keyInput(e key.Event, appCursor bool) []byte {
+	arrow := func(final byte) []byte {
+		if appCursor {
+			return []byte{0x1b, 'O', final}
+		}
+		return []byte{0x1b, '[', final}
+	}
+	switch e.Name {
+	case key.NameReturn, key.NameEnter:
+		return []byte{'\r'}
+	case key.NameDeleteBackward:
+		return []byte{0x7f}
+	case key.NameDeleteForward:
+		return []byte("\x1b[3~")
+	case key.NameEscape:
+		return []byte{0x1b}
+	case key.NameTab:
+		if e.Modifiers.Contain(key.ModShift) {
+			return []byte("\x1b[Z")
+		}
+		return []byte{'\t'}
+	case key.NameUpArrow:
+		return arrow('A')
+	case key.NameDownArrow:
+		return arrow('B')
+	case key.NameRightArrow:
+		return arrow('C')
+	case key.NameLeftArrow:
+		return arrow('D')
+	case key.NameHome:
+		return arrow('H')
+	case key.NameEnd:
+		return arrow('F')
+	case key.NamePageUp:
+		return []byte("\x1b[5~")
+	case key.NamePageDown:
+		return []byte("\x1b[6~")
+	case key.NameF1:
+		return []byte("\x1bOP")
+	case key.NameF2:
+		return []byte("\x1bOQ")
+	case key.NameF3:
+		return []byte("\x1bOR")
+	case key.NameF4:
+		return []byte("\x1bOS")
+	case key.NameF5:
+		return []byte("\x1b[15~")
+	case key.NameF6:
+		return []byte("\x1b[17~")
+	case key.NameF7:
+		return []byte("\x1b[18~")
+	case key.NameF8:
+		return []byte("\x1b[19~")
+	case key.NameF9:
+		return []byte("\x1b[20~")
+	case key.NameF10:
+		return []byte("\x1b[21~")
+	case key.NameF11:
+		return []byte("\x1b[23~")
+	case key.NameF12:
+		return []byte("\x1b[24~")
+	}
+	if !e.Modifiers.Contain(key.ModCtrl) {
+		return nil
+	}
+	// Ctrl 组合键发送对应的控制字符
+	if e.Name == key.NameSpace {
+		return []byte{0}
+	}
+	if len(e.Name) != 1 {
+		return nil
+	}
+	switch c := e.Name[0]; {
+	case c >= 'A' && c <= 'Z':
+		return []byte{c - 'A' + 1}
+	case c == '[':
+		return []byte{0x1b}
+	case c == '\\':
+		return []byte{0x1c}
+	case c == ']':
+		return []byte{0x1d}
+	}
+	return nil
+}
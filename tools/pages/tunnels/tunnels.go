@@ -0,0 +1,472 @@
+package tunnels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"time"
+	"tools/icon"
+	"tools/job"
+	page "tools/pages"
+	"tools/pages/common"
+	"tools/pages/connform"
+	"tools/tunnel"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/component"
+)
+
+var (
+	okColor  = color.NRGBA{R: 30, G: 140, B: 60, A: 255}
+	errColor = color.NRGBA{R: 200, G: 40, B: 40, A: 255}
+)
+
+// entry is a saved or running tunnel of a host.
+type entry struct {
+	host   string
+	tunnel *tunnel.Tunnel
+	// runner 连接主机并启动隧道，err 是最近一次启动失败的原因
+	runner       *job.Runner[struct{}]
+	err          error
+	startButton  widget.Clickable
+	stopButton   widget.Clickable
+	deleteButton widget.Clickable
+}
+
+func (e *entry) running() bool {
+	return e.runner.Running() || e.tunnel.Stats().State == tunnel.Running
+}
+
+// Page defines, starts and stops ssh port forwards.
+type Page struct {
+	connForm    *connform.Form
+	kind        widget.Enum
+	listenInput widget.Editor
+	targetInput widget.Editor
+	addButton   widget.Clickable
+	modalButton widget.Clickable
+	showDialog  bool
+	confirmMsg  string
+	// entries 以主机和隧道定义为键，保存过的和正在运行的隧道都在这里
+	entries map[string]*entry
+	list    widget.List
+	*page.Router
+}
+
+func New(router *page.Router) *Page {
+	p := &Page{
+		Router:  router,
+		entries: make(map[string]*entry),
+	}
+	p.connForm = connform.New(router)
+	p.kind.Value = string(tunnel.Local)
+	p.listenInput.SingleLine = true
+	p.targetInput.SingleLine = true
+	p.list.Axis = layout.Vertical
+	return p
+}
+
+var _ page.Page = &Page{}
+
+func (p *Page) Actions() []component.AppBarAction {
+	return []component.AppBarAction{}
+}
+
+func (p *Page) Overflow() []component.OverflowAction {
+	return []component.OverflowAction{}
+}
+
+func (p *Page) NavItem() component.NavItem {
+	return component.NavItem{
+		Name: "Tunnels",
+		Icon: icon.TunnelsIcon,
+	}
+}
+
+func (p *Page) Layout(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	host := p.host()
+	entries := p.shown(host)
+	p.update(gtx, host, entries)
+	for _, e := range entries {
+		if e.running() {
+			// 运行中每秒刷新连接数和流量
+			gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(time.Second)})
+			break
+		}
+	}
+
+	mainPage := layout.Flex{
+		Axis: layout.Vertical,
+	}
+	mainPage.Layout(gtx,
+		// 连接参数
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return p.connForm.Layout(gtx, th)
+		}),
+		// 新增隧道
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Left: unit.Dp(10), Right: unit.Dp(10), Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutDefine(gtx, th)
+			})
+		}),
+		// 隧道列表（占满剩余空间）
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return p.layoutTunnels(gtx, th, host, entries)
+			})
+		}),
+	)
+
+	// 弹出对话框
+	if p.showDialog {
+		p.drawConfirmDialog(gtx, th)
+	}
+
+	return mainPage.Layout(gtx)
+}
+
+// host returns user@address of the connection form, or "" while the
+// address or user is not filled in.
+func (p *Page) host() string {
+	switch p.connForm.Missing() {
+	case "ip address", "login user name":
+		return ""
+	}
+	return p.connForm.Target()
+}
+
+func key(host string, spec tunnel.Spec) string {
+	return host + " " + spec.String()
+}
+
+func (p *Page) entry(host string, spec tunnel.Spec) *entry {
+	k := key(host, spec)
+	e, ok := p.entries[k]
+	if !ok {
+		e = &entry{
+			host:   host,
+			tunnel: tunnel.New(spec, p.Router.Invalidate),
+			runner: job.NewRunner[struct{}](p.Router.Invalidate),
+		}
+		p.entries[k] = e
+	}
+	return e
+}
+
+// shown returns the saved tunnels of host and the running tunnels of every
+// host, sorted by host and definition.
+func (p *Page) shown(host string) []*entry {
+	seen := make(map[*entry]bool)
+	if len(host) != 0 {
+		for _, spec := range p.Router.Tunnels.Specs(host) {
+			seen[p.entry(host, spec)] = true
+		}
+	}
+	for _, e := range p.entries {
+		if e.running() {
+			seen[e] = true
+		}
+	}
+	entries := make([]*entry, 0, len(seen))
+	for e := range seen {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.host != b.host {
+			return a.host < b.host
+		}
+		return a.tunnel.Spec.String() < b.tunnel.Spec.String()
+	})
+	return entries
+}
+
+func (p *Page) update(gtx layout.Context, host string, entries []*entry) {
+	if p.addButton.Clicked(gtx) {
+		p.add(host)
+	}
+	for _, e := range entries {
+		if res, ok := e.runner.Poll(); ok {
+			e.err = nil
+			if res.Err != nil && !errors.Is(res.Err, context.Canceled) {
+				e.err = res.Err
+			}
+		}
+		if e.startButton.Clicked(gtx) && e.host == host && !e.running() {
+			p.start(e)
+		}
+		if e.stopButton.Clicked(gtx) {
+			e.runner.Cancel()
+			e.tunnel.Stop()
+		}
+		if e.deleteButton.Clicked(gtx) {
+			if err := p.Router.Tunnels.Delete(e.host, e.tunnel.Spec); err != nil {
+				p.confirmMsg = err.Error()
+				p.showDialog = true
+				continue
+			}
+			e.runner.Cancel()
+			e.tunnel.Stop()
+			delete(p.entries, key(e.host, e.tunnel.Spec))
+		}
+	}
+}
+
+// add saves the forward defined by the inputs for host.
+func (p *Page) add(host string) {
+	if len(host) == 0 {
+		p.confirmMsg = "fill in the host before adding a tunnel"
+		p.showDialog = true
+		return
+	}
+	spec := tunnel.Spec{
+		Kind:   tunnel.Kind(p.kind.Value),
+		Listen: p.listenInput.Text(),
+		Target: p.targetInput.Text(),
+	}
+	if err := p.Router.Tunnels.Put(host, spec); err != nil {
+		p.confirmMsg = err.Error()
+		p.showDialog = true
+		return
+	}
+	p.listenInput.SetText("")
+	p.targetInput.SetText("")
+}
+
+// start connects to the host of the form and starts the tunnel, the
+// pooled connection is held until the tunnel stops.
+func (p *Page) start(e *entry) {
+	if itemName := p.connForm.Missing(); len(itemName) != 0 {
+		p.confirmMsg = fmt.Sprintf("%s is required", itemName)
+		p.showDialog = true
+		return
+	}
+	// 编辑框只能在 UI 协程中读取，先取出参数再交给后台任务
+	cfg, err := p.connForm.Config()
+	if err != nil {
+		p.confirmMsg = err.Error()
+		p.showDialog = true
+		return
+	}
+	t := e.tunnel
+	e.err = nil
+	e.runner.Start(func(ctx context.Context, report func(string)) (struct{}, error) {
+		report(fmt.Sprintf("connecting to %s", cfg.Host))
+		client, release, err := p.Router.Pool.Get(ctx, cfg)
+		if err != nil {
+			return struct{}{}, err
+		}
+		report("starting")
+		return struct{}{}, t.Start(ctx, client, release)
+	})
+}
+
+func (p *Page) layoutDefine(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	dynamic := p.kind.Value == string(tunnel.Dynamic)
+	listenHint := "listen port or address, e.g. 8443"
+	if p.kind.Value == string(tunnel.Remote) {
+		listenHint = "remote listen port or address"
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(material.RadioButton(th, &p.kind, string(tunnel.Local), "local -L").Layout),
+		layout.Rigid(material.RadioButton(th, &p.kind, string(tunnel.Remote), "remote -R").Layout),
+		layout.Rigid(material.RadioButton(th, &p.kind, string(tunnel.Dynamic), "SOCKS5 -D").Layout),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return connform.Input(gtx, th, &p.listenInput, listenHint, 240)
+		}),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		// SOCKS5 代理的目标地址由客户端指定
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if dynamic {
+				return layout.Dimensions{}
+			}
+			return connform.Input(gtx, th, &p.targetInput, "target host:port, e.g. 10.0.0.5:443", 280)
+		}),
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return Button(gtx, 80, th, &p.addButton, "add")
+		}),
+	)
+}
+
+var columns = []struct {
+	name  string
+	width unit.Dp
+}{
+	{"host", 200},
+	{"forward", 0},
+	{"status", 100},
+	{"connections", 110},
+	{"sent / received", 170},
+	{"", 260},
+}
+
+// cells lays out a table row with the column widths, the forward column
+// takes the remaining space.
+func cells(gtx layout.Context, widgets ...layout.Widget) layout.Dimensions {
+	children := make([]layout.FlexChild, 0, len(widgets))
+	for i, w := range widgets {
+		width := columns[i].width
+		if width == 0 {
+			children = append(children, layout.Flexed(1, w))
+			continue
+		}
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min.X = gtx.Dp(width)
+			gtx.Constraints.Max.X = gtx.Dp(width)
+			return w(gtx)
+		}))
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+}
+
+func (p *Page) layoutTunnels(gtx layout.Context, th *material.Theme, host string, entries []*entry) layout.Dimensions {
+	if len(entries) == 0 {
+		return material.Body2(th, "no tunnels, add one for the host above").Layout(gtx)
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		// 表头
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			header := make([]layout.Widget, len(columns))
+			for i, c := range columns {
+				lbl := material.Body2(th, c.name)
+				lbl.Font.Weight = font.Bold
+				header[i] = lbl.Layout
+			}
+			return layout.Inset{Bottom: unit.Dp(5)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return cells(gtx, header...)
+			})
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return material.List(th, &p.list).Layout(gtx, len(entries), func(gtx layout.Context, i int) layout.Dimensions {
+				return p.layoutEntry(gtx, th, host, entries[i])
+			})
+		}),
+	)
+}
+
+func (p *Page) layoutEntry(gtx layout.Context, th *material.Theme, host string, e *entry) layout.Dimensions {
+	stats := e.tunnel.Stats()
+	status := material.Body2(th, stats.State.String())
+	switch {
+	case e.runner.Running():
+		status.Text = "starting"
+	case stats.State == tunnel.Running:
+		status.Color = okColor
+	case stats.State == tunnel.Failed:
+		status.Color = errColor
+	}
+	errMsg := ""
+	switch {
+	case e.err != nil:
+		errMsg = e.err.Error()
+	case stats.Err != nil:
+		errMsg = stats.Err.Error()
+	}
+	return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return cells(gtx,
+					material.Body1(th, e.host).Layout,
+					material.Body1(th, e.tunnel.Spec.String()).Layout,
+					status.Layout,
+					material.Body2(th, fmt.Sprintf("%d / %d", stats.Active, stats.Total)).Layout,
+					material.Body2(th, fmt.Sprintf("%s / %s", common.FormatSize(stats.Sent), common.FormatSize(stats.Received))).Layout,
+					func(gtx layout.Context) layout.Dimensions {
+						return p.layoutActions(gtx, th, host, e)
+					},
+				)
+			}),
+			// 失败原因或最近一次连接错误
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if len(errMsg) == 0 {
+					return layout.Dimensions{}
+				}
+				lbl := material.Caption(th, errMsg)
+				lbl.Color = errColor
+				return layout.Inset{Left: unit.Dp(200)}.Layout(gtx, lbl.Layout)
+			}),
+		)
+	})
+}
+
+func (p *Page) layoutActions(gtx layout.Context, th *material.Theme, host string, e *entry) layout.Dimensions {
+	children := make([]layout.FlexChild, 0, 3)
+	switch {
+	case e.running():
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return Button(gtx, 80, th, &e.stopButton, "stop")
+		}))
+	case e.host == host:
+		// 只能启动连接表单中主机的隧道
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return Button(gtx, 80, th, &e.startButton, "start")
+		}))
+	}
+	children = append(children,
+		layout.Rigid(layout.Spacer{Width: 10}.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			btn := material.Button(th, &e.deleteButton, "delete")
+			btn.Background = errColor
+			return btn.Layout(gtx)
+		}),
+	)
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+}
+
+func (p *Page) drawConfirmDialog(gtx layout.Context, th *material.Theme) {
+	// 全屏
+	full := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{A: 150})
+	full.Pop()
+
+	// 窗口大小和位置（居中）
+	boxW := min(gtx.Constraints.Max.X-80, 420)
+	boxH := 150
+	cx := gtx.Constraints.Max.X / 2
+	cy := gtx.Constraints.Max.Y / 2
+	rect := image.Rect(cx-boxW/2, cy-boxH/2, cx+boxW/2, cy+boxH/2)
+
+	// 窗口背景（白色矩形）
+	box := clip.Rect{Min: rect.Min, Max: rect.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	box.Pop()
+
+	// 将坐标系偏移道对话框左上角，然后在内部做正常布局
+	offset := op.Offset(image.Pt(rect.Min.X, rect.Min.Y)).Push(gtx.Ops)
+	inner := gtx
+	inner.Constraints.Min = image.Pt(boxW, 0)
+	inner.Constraints.Max = image.Pt(boxW, boxH)
+
+	layout.UniformInset(unit.Dp(16)).Layout(inner, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(material.Body1(th, p.confirmMsg).Layout),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				btn := material.Button(th, &p.modalButton, "confirm")
+				if p.modalButton.Clicked(gtx) {
+					p.showDialog = false
+				}
+				return btn.Layout(gtx)
+			}),
+		)
+	})
+	offset.Pop()
+}
+
+func Button(gtx layout.Context, width unit.Dp, th *material.Theme, wid *widget.Clickable, txt string) layout.Dimensions {
+	gtx.Constraints.Min.X = gtx.Dp(width)
+	gtx.Constraints.Max.X = gtx.Dp(width)
+	return material.Button(th, wid, txt).Layout(gtx)
+}
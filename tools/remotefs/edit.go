@@ -0,0 +1,208 @@
+package remotefs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+	"tools/sshclient"
+	"unicode/utf8"
+)
+
+// MaxEditSize is the largest file ReadFile opens.
+const MaxEditSize = 1 << 20
+
+var (
+	ErrConflict   = errors.New("file changed on the server since it was opened")
+	ErrPermission = errors.New("permission denied, save with sudo to write this file")
+)
+
+// File is a remote text file read for editing. ModTime is checked when the
+// file is written back to detect changes made by someone else.
+type File struct {
+	Path    string
+	Content string
+	Mode    fs.FileMode
+	ModTime time.Time
+}
+
+// WriteOptions control how WriteFile saves a file.
+type WriteOptions struct {
+	// Backup keeps the previous content in a .bak file next to it.
+	Backup bool
+	// Sudo writes the file as root with sudo when not nil, for files the
+	// user cannot write. Prompt asks for the sudo password of SudoTarget
+	// when sudo does not accept Sudo.Password, the accepted password is
+	// left in Sudo.
+	Sudo       *sshclient.Sudo
+	SudoTarget string
+	Prompt     sshclient.Prompt
+	// Force writes even when the file changed since it was read.
+	Force bool
+}
+
+// ReadFile reads a text file for editing, binary and large files are
+// refused.
+func (f *FS) ReadFile(p string) (*File, error) {
+	info, err := f.client.Stat(p)
+	if err != nil {
+		return nil, fmt.Errorf("open %s failed, %v", p, err)
+	}
+	switch {
+	case info.IsDir():
+		return nil, fmt.Errorf("open %s failed, it is a directory", p)
+	case info.Size() > MaxEditSize:
+		return nil, fmt.Errorf("open %s failed, larger than %d bytes", p, MaxEditSize)
+	}
+	src, err := f.client.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("open %s failed, %v", p, err)
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, MaxEditSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %s failed, %v", p, err)
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return nil, fmt.Errorf("open %s failed, not a text file", p)
+	}
+	return &File{Path: p, Content: string(data), Mode: info.Mode(), ModTime: info.ModTime()}, nil
+}
+
+// WriteFile replaces the content of file. The content is written to a
+// temporary file next to it which is renamed over the old one, so readers
+// never see a partial file. It returns the file as written, or ErrConflict
+// when the file was modified after it was read and opts.Force is not set.
+func (f *FS) WriteFile(ctx context.Context, file *File, content string, opts WriteOptions) (*File, error) {
+	info, err := f.client.Stat(file.Path)
+	switch {
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("save %s failed, %v", file.Path, err)
+	case !opts.Force && (err != nil || !info.ModTime().Equal(file.ModTime)):
+		// 文件被删除也算冲突
+		return nil, ErrConflict
+	}
+	exists := err == nil
+
+	if opts.Sudo != nil {
+		err = f.sudoWrite(ctx, file, content, opts.Backup && exists, opts)
+	} else {
+		err = f.write(file, content, opts.Backup && exists)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &File{Path: file.Path, Content: content, Mode: file.Mode, ModTime: time.Now()}
+	if info, err := f.client.Stat(file.Path); err == nil {
+		saved.Mode, saved.ModTime = info.Mode(), info.ModTime()
+	}
+	return saved, nil
+}
+
+func (f *FS) write(file *File, content string, backup bool) error {
+	if backup {
+		if err := f.copy(file.Path, file.Path+".bak", file.Mode.Perm()); err != nil {
+			return permission(fmt.Errorf("backup %s failed, %v", file.Path, err), err)
+		}
+	}
+	tmp := file.Path + ".part"
+	dst, err := f.client.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return permission(fmt.Errorf("save %s failed, %v", file.Path, err), err)
+	}
+	_, err = io.WriteString(dst, content)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = f.client.Chmod(tmp, file.Mode.Perm())
+	}
+	if err == nil {
+		err = f.move(tmp, file.Path, true)
+	}
+	if err != nil {
+		f.client.Remove(tmp)
+		return permission(fmt.Errorf("save %s failed, %v", file.Path, err), err)
+	}
+	return nil
+}
+
+// permission turns permission errors into ErrPermission so the caller can
+// offer sudo.
+func permission(wrapped, err error) error {
+	if errors.Is(err, os.ErrPermission) {
+		return ErrPermission
+	}
+	return wrapped
+}
+
+func (f *FS) copy(src, dst string, perm fs.FileMode) error {
+	in, err := f.client.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := f.client.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = f.client.Chmod(dst, perm)
+	}
+	return err
+}
+
+// sudoScript replaces $1 with the content of $2 as root. The new file is
+// made by copying the old one so it keeps its owner and mode.
+const sudoScript = `set -e
+f="$1"; t="$2"
+if [ "$3" = 1 ]; then cp -p -- "$f" "$f.bak"; fi
+if [ -e "$f" ]; then cp -p -- "$f" "$f.part"; fi
+cat -- "$t" > "$f.part"
+mv -f -- "$f.part" "$f"`
+
+// sudoWrite uploads the content to a private temporary file and moves it
+// into place with sudo.
+func (f *FS) sudoWrite(ctx context.Context, file *File, content string, backup bool, opts WriteOptions) error {
+	tmp := path.Join("/tmp", fmt.Sprintf(".tools-edit-%s-%d", path.Base(file.Path), time.Now().UnixNano()))
+	dst, err := f.client.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return fmt.Errorf("save %s failed, %v", file.Path, err)
+	}
+	defer f.client.Remove(tmp)
+	err = f.client.Chmod(tmp, 0600)
+	if err == nil {
+		_, err = io.WriteString(dst, content)
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("save %s failed, %v", file.Path, err)
+	}
+
+	flag := "0"
+	if backup {
+		flag = "1"
+	}
+	cmd := fmt.Sprintf("sh -c %s sh %s %s %s",
+		sshclient.Quote(sudoScript), sshclient.Quote(file.Path), sshclient.Quote(tmp), flag)
+	err = opts.Sudo.Retry(ctx, opts.SudoTarget, opts.Prompt, func(sudo sshclient.Sudo) error {
+		_, err := f.ssh.RunSudo(ctx, cmd, sudo)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("save %s with sudo failed, %v", file.Path, err)
+	}
+	return nil
+}
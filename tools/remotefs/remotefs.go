@@ -0,0 +1,344 @@
+package remotefs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"tools/sshclient"
+
+	"github.com/pkg/sftp"
+)
+
+// Entry is one file of a remote directory listing.
+type Entry struct {
+	Name    string
+	Size    int64
+	Mode    fs.FileMode
+	Owner   string
+	Group   string
+	ModTime time.Time
+}
+
+func (e Entry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// Progress is told how many bytes of a transfer are done, total is -1 when
+// the size is unknown.
+type Progress func(done, total int64)
+
+// FS is a remote file system reached over sftp. It is not safe for
+// concurrent use.
+type FS struct {
+	client *sftp.Client
+	// ssh 用于 sudo 写文件等 sftp 做不到的操作
+	ssh *sshclient.Client
+	// owners 和 groups 从远端 /etc/passwd 和 /etc/group 读取，
+	// 用于显示用户名和组名
+	owners map[uint32]string
+	groups map[uint32]string
+}
+
+// Open starts sftp on the connection. Close stops it, the connection
+// stays open.
+func Open(ctx context.Context, c *sshclient.Client) (*FS, error) {
+	client, err := c.SFTP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &FS{
+		client: client,
+		ssh:    c,
+		owners: readIDs(client, "/etc/passwd"),
+		groups: readIDs(client, "/etc/group"),
+	}, nil
+}
+
+// readIDs maps the numeric ids of a passwd or group file to names, a
+// missing or unreadable file gives an empty map.
+func readIDs(client *sftp.Client, file string) map[uint32]string {
+	ids := make(map[uint32]string)
+	f, err := client.Open(file)
+	if err != nil {
+		return ids
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 {
+			continue
+		}
+		if id, err := strconv.ParseUint(fields[2], 10, 32); err == nil {
+			ids[uint32(id)] = fields[0]
+		}
+	}
+	return ids
+}
+
+func (f *FS) Close() error {
+	return f.client.Close()
+}
+
+// Home returns the directory the sftp session started in, normally the
+// home directory of the user.
+func (f *FS) Home() (string, error) {
+	dir, err := f.client.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("get working directory failed, %v", err)
+	}
+	return dir, nil
+}
+
+// Clean resolves dir against the server, following symbolic links.
+func (f *FS) Clean(dir string) (string, error) {
+	dir, err := f.client.RealPath(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s failed, %v", dir, err)
+	}
+	return dir, nil
+}
+
+// List returns the entries of dir. Symbolic links to directories are
+// listed as directories so they can be opened.
+func (f *FS) List(dir string) ([]Entry, error) {
+	infos, err := f.client.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("list %s failed, %v", dir, err)
+	}
+	entries := make([]Entry, 0, len(infos))
+	for _, info := range infos {
+		e := Entry{
+			Name:    info.Name(),
+			Size:    info.Size(),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
+		}
+		if stat, ok := info.Sys().(*sftp.FileStat); ok {
+			e.Owner = name(f.owners, stat.UID)
+			e.Group = name(f.groups, stat.GID)
+		}
+		if e.Mode&fs.ModeSymlink != 0 {
+			if target, err := f.client.Stat(path.Join(dir, e.Name)); err == nil && target.IsDir() {
+				e.Mode |= fs.ModeDir
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func name(names map[uint32]string, id uint32) string {
+	if n, ok := names[id]; ok {
+		return n
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func (f *FS) Mkdir(p string) error {
+	if err := f.client.Mkdir(p); err != nil {
+		return fmt.Errorf("create directory %s failed, %v", p, err)
+	}
+	return nil
+}
+
+// ErrExist is returned when a rename or upload would replace a file and
+// overwrite is not set.
+var ErrExist = errors.New("target already exists")
+
+// Rename moves oldPath to newPath. An existing newPath is only replaced
+// with overwrite.
+func (f *FS) Rename(oldPath, newPath string, overwrite bool) error {
+	if err := f.move(oldPath, newPath, overwrite); err != nil {
+		return fmt.Errorf("rename %s failed, %w", oldPath, err)
+	}
+	return nil
+}
+
+// move renames without checking when overwrite is set, servers without
+// posix renames get the target removed first.
+func (f *FS) move(oldPath, newPath string, overwrite bool) error {
+	if !overwrite {
+		if err := f.checkNotExist(newPath); err != nil {
+			return err
+		}
+		return f.client.Rename(oldPath, newPath)
+	}
+	if err := f.client.PosixRename(oldPath, newPath); err == nil {
+		return nil
+	}
+	if err := f.client.Remove(newPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return f.client.Rename(oldPath, newPath)
+}
+
+func (f *FS) checkNotExist(p string) error {
+	_, err := f.client.Lstat(p)
+	switch {
+	case err == nil:
+		return fmt.Errorf("%s: %w", p, ErrExist)
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	}
+	return err
+}
+
+// Remove deletes a file, or a directory with everything in it.
+func (f *FS) Remove(p string) error {
+	info, err := f.client.Lstat(p)
+	if err != nil {
+		return fmt.Errorf("delete %s failed, %v", p, err)
+	}
+	if info.IsDir() {
+		err = f.client.RemoveAll(p)
+	} else {
+		err = f.client.Remove(p)
+	}
+	if err != nil {
+		return fmt.Errorf("delete %s failed, %v", p, err)
+	}
+	return nil
+}
+
+func (f *FS) Chmod(p string, mode fs.FileMode) error {
+	if err := f.client.Chmod(p, mode); err != nil {
+		return fmt.Errorf("chmod %s failed, %v", p, err)
+	}
+	return nil
+}
+
+// Upload copies the local file to remotePath. It is written to a
+// temporary file first and renamed when complete, so an interrupted
+// upload never leaves a truncated file behind. An existing remotePath is
+// only replaced with overwrite.
+func (f *FS) Upload(ctx context.Context, local, remotePath string, overwrite bool, progress Progress) error {
+	src, err := os.Open(local)
+	if err != nil {
+		return fmt.Errorf("upload %s failed, %v", local, err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("upload %s failed, %v", local, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("upload %s failed, it is a directory", local)
+	}
+	// 传输前检查，避免上传完成后才发现目标已存在
+	if !overwrite {
+		if err := f.checkNotExist(remotePath); err != nil {
+			return fmt.Errorf("upload %s failed, %w", local, err)
+		}
+	}
+
+	tmp := remotePath + ".part"
+	dst, err := f.client.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("upload %s failed, %v", local, err)
+	}
+	_, err = io.Copy(dst, &reader{ctx: ctx, r: src, total: info.Size(), progress: progress})
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = f.move(tmp, remotePath, overwrite)
+	}
+	if err != nil {
+		f.client.Remove(tmp)
+		return fmt.Errorf("upload %s failed, %w", local, err)
+	}
+	// 保留本地文件的权限
+	f.client.Chmod(remotePath, info.Mode().Perm())
+	return nil
+}
+
+// Download copies remotePath to the local file, through a temporary file
+// like Upload.
+func (f *FS) Download(ctx context.Context, remotePath, local string, progress Progress) error {
+	src, err := f.client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("download %s failed, %v", remotePath, err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("download %s failed, %v", remotePath, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("download %s failed, it is a directory", remotePath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return fmt.Errorf("download %s failed, %v", remotePath, err)
+	}
+	tmp := local + ".part"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm()|0600)
+	if err != nil {
+		return fmt.Errorf("download %s failed, %v", remotePath, err)
+	}
+	_, err = io.Copy(&writer{ctx: ctx, w: dst, total: info.Size(), progress: progress}, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, local)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("download %s failed, %v", remotePath, err)
+	}
+	return nil
+}
+
+// reader counts the bytes read for the progress and stops when ctx is
+// cancelled.
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	done     int64
+	total    int64
+	progress Progress
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	r.done += int64(n)
+	if r.progress != nil {
+		r.progress(r.done, r.total)
+	}
+	return n, err
+}
+
+// writer is the counterpart of reader for downloads.
+type writer struct {
+	ctx      context.Context
+	w        io.Writer
+	done     int64
+	total    int64
+	progress Progress
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := w.w.Write(p)
+	w.done += int64(n)
+	if w.progress != nil {
+		w.progress(w.done, w.total)
+	}
+	return n, err
+}
@@ -0,0 +1,217 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// pipe joins the two halves of io.Pipes into a connection.
+type pipe struct {
+	io.Reader
+	io.WriteCloser
+}
+
+// newTestFS serves the local file system over sftp in memory pipes and
+// returns an FS connected to it together with an empty directory.
+func newTestFS(t *testing.T) (*FS, string) {
+	t.Helper()
+	serverRead, clientWrite := io.Pipe()
+	clientRead, serverWrite := io.Pipe()
+	server, err := sftp.NewServer(pipe{serverRead, serverWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	client, err := sftp.NewClientPipe(clientRead, clientWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		// 先关闭服务端，客户端读到 EOF 后才能关闭
+		server.Close()
+		client.Close()
+	})
+	return &FS{client: client, owners: map[uint32]string{}, groups: map[uint32]string{}}, t.TempDir()
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestList(t *testing.T) {
+	f, dir := newTestFS(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "hello")
+	if err := f.Mkdir(filepath.Join(dir, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "sub"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := f.List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Entry{}
+	for _, e := range entries {
+		got[e.Name] = e
+	}
+	if len(got) != 3 {
+		t.Fatalf("List returned %d entries, want 3: %v", len(entries), entries)
+	}
+	if e := got["a.txt"]; e.IsDir() || e.Size != 5 {
+		t.Errorf("a.txt = %+v, want a 5 byte file", e)
+	}
+	if !got["sub"].IsDir() {
+		t.Errorf("sub is not listed as a directory")
+	}
+	// 指向目录的符号链接当作目录
+	if !got["link"].IsDir() {
+		t.Errorf("link to a directory is not listed as a directory")
+	}
+	if _, err := f.List(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("List of a missing directory succeeded")
+	}
+}
+
+func TestUploadDownload(t *testing.T) {
+	f, dir := newTestFS(t)
+	local := filepath.Join(t.TempDir(), "local.txt")
+	writeFile(t, local, "first")
+	remote := filepath.Join(dir, "remote.txt")
+
+	var done []int64
+	progress := func(n, total int64) { done = append(done, n) }
+	if err := f.Upload(context.Background(), local, remote, false, progress); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, remote); got != "first" {
+		t.Errorf("uploaded content = %q, want %q", got, "first")
+	}
+	if len(done) == 0 || done[len(done)-1] != 5 {
+		t.Errorf("progress = %v, want it to end at 5", done)
+	}
+
+	// 目标已存在时不覆盖，除非指定 overwrite
+	writeFile(t, local, "second")
+	if err := f.Upload(context.Background(), local, remote, false, nil); !errors.Is(err, ErrExist) {
+		t.Errorf("Upload over an existing file = %v, want ErrExist", err)
+	}
+	if got := readFile(t, remote); got != "first" {
+		t.Errorf("refused upload changed the file to %q", got)
+	}
+	if err := f.Upload(context.Background(), local, remote, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, remote); got != "second" {
+		t.Errorf("overwritten content = %q, want %q", got, "second")
+	}
+
+	downloaded := filepath.Join(t.TempDir(), "new", "downloaded.txt")
+	if err := f.Download(context.Background(), remote, downloaded, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, downloaded); got != "second" {
+		t.Errorf("downloaded content = %q, want %q", got, "second")
+	}
+
+	// 没有留下临时文件
+	for _, d := range []string{dir, filepath.Dir(downloaded)} {
+		entries, _ := os.ReadDir(d)
+		for _, e := range entries {
+			if filepath.Ext(e.Name()) == ".part" {
+				t.Errorf("temporary file %s left behind", filepath.Join(d, e.Name()))
+			}
+		}
+	}
+}
+
+func TestUploadCancelled(t *testing.T) {
+	f, dir := newTestFS(t)
+	local := filepath.Join(t.TempDir(), "local.txt")
+	writeFile(t, local, "content")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	remote := filepath.Join(dir, "remote.txt")
+	if err := f.Upload(ctx, local, remote, false, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled Upload = %v, want context.Canceled", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("cancelled upload left %v behind", entries)
+	}
+}
+
+func TestRename(t *testing.T) {
+	f, dir := newTestFS(t)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	writeFile(t, a, "a")
+	writeFile(t, b, "b")
+
+	if err := f.Rename(a, b, false); !errors.Is(err, ErrExist) {
+		t.Errorf("Rename onto an existing file = %v, want ErrExist", err)
+	}
+	if got := readFile(t, b); got != "b" {
+		t.Errorf("refused rename changed the target to %q", got)
+	}
+	if err := f.Rename(a, b, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, b); got != "a" {
+		t.Errorf("renamed content = %q, want %q", got, "a")
+	}
+	c := filepath.Join(dir, "c")
+	if err := f.Rename(b, c, false); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if !slices.Equal(names, []string{"c"}) {
+		t.Errorf("directory has %v after the renames, want [c]", names)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	f, dir := newTestFS(t)
+	file := filepath.Join(dir, "file")
+	writeFile(t, file, "x")
+	sub := filepath.Join(dir, "sub")
+	if err := os.MkdirAll(filepath.Join(sub, "deeper"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(sub, "deeper", "file"), "x")
+
+	for _, p := range []string{file, sub} {
+		if err := f.Remove(p); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after Remove", p)
+		}
+	}
+	if err := f.Remove(file); err == nil {
+		t.Errorf("Remove of a missing file succeeded")
+	}
+}
@@ -0,0 +1,136 @@
+package snippet
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"tools/jsonstore"
+)
+
+// paramPattern matches a {{name}} placeholder.
+var paramPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// Snippet is a named command, {{name}} placeholders are filled in before
+// it runs, e.g. "smartctl -a {{device}}".
+type Snippet struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+}
+
+// Params returns the placeholder names in order of first use.
+func (s Snippet) Params() []string {
+	params := make([]string, 0)
+	for _, m := range paramPattern.FindAllStringSubmatch(s.Command, -1) {
+		if !slices.Contains(params, m[1]) {
+			params = append(params, m[1])
+		}
+	}
+	return params
+}
+
+// Expand replaces the placeholders with values, unknown names are left as
+// they are.
+func (s Snippet) Expand(values map[string]string) string {
+	return paramPattern.ReplaceAllStringFunc(s.Command, func(p string) string {
+		name := paramPattern.FindStringSubmatch(p)[1]
+		if v, ok := values[name]; ok {
+			return v
+		}
+		return p
+	})
+}
+
+func (s Snippet) validate() error {
+	switch {
+	case len(strings.TrimSpace(s.Name)) == 0:
+		return errors.New("snippet name is required")
+	case len(strings.TrimSpace(s.Command)) == 0:
+		return errors.New("snippet command is required")
+	}
+	return nil
+}
+
+// Library is the list of saved snippets backed by a JSON file, it is safe
+// for concurrent use.
+type Library struct {
+	store    *jsonstore.File
+	mu       sync.Mutex
+	snippets []Snippet
+}
+
+type file struct {
+	Snippets []Snippet `json:"snippets"`
+}
+
+// DefaultPath returns snippets.json in the user config directory.
+func DefaultPath() string {
+	return jsonstore.DefaultPath("snippets.json")
+}
+
+// Load reads the library at path, a missing file is an empty library.
+// A file that cannot be parsed is moved to path.bak, saving is refused
+// when it cannot be moved or read.
+func Load(path string) (*Library, error) {
+	store, f, err := jsonstore.Open[file](path, "snippets")
+	return &Library{store: store, snippets: f.Snippets}, err
+}
+
+// Snippets returns a copy of all snippets sorted by name.
+func (lib *Library) Snippets() []Snippet {
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	snippets := slices.Clone(lib.snippets)
+	slices.SortFunc(snippets, func(a, b Snippet) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return snippets
+}
+
+func (lib *Library) index(name string) int {
+	return slices.IndexFunc(lib.snippets, func(s Snippet) bool { return s.Name == name })
+}
+
+// Put adds s, replacing the snippet with the same name, and saves the
+// library.
+func (lib *Library) Put(s Snippet) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	snippets := slices.Clone(lib.snippets)
+	if i := lib.index(s.Name); i >= 0 {
+		snippets[i] = s
+	} else {
+		snippets = append(snippets, s)
+	}
+	if err := lib.save(snippets); err != nil {
+		return err
+	}
+	lib.snippets = snippets
+	return nil
+}
+
+// Delete removes the snippet named name and saves the library.
+func (lib *Library) Delete(name string) error {
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	i := lib.index(name)
+	if i < 0 {
+		return fmt.Errorf("snippet %q does not exist", name)
+	}
+	snippets := slices.Delete(slices.Clone(lib.snippets), i, i+1)
+	if err := lib.save(snippets); err != nil {
+		return err
+	}
+	lib.snippets = snippets
+	return nil
+}
+
+// save writes snippets to the library file.
+func (lib *Library) save(snippets []Snippet) error {
+	return lib.store.Save(file{Snippets: snippets})
+}
@@ -0,0 +1,161 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+type AuthMethod string
+
+const (
+	AuthPassword            AuthMethod = "password"
+	AuthPublicKey           AuthMethod = "publickey"
+	AuthAgent               AuthMethod = "agent"
+	AuthKeyboardInteractive AuthMethod = "keyboard-interactive"
+)
+
+// AuthMethods lists the supported methods in the order they are offered in the UI.
+var AuthMethods = []AuthMethod{AuthPassword, AuthPublicKey, AuthAgent, AuthKeyboardInteractive}
+
+// Prompt asks the user a question, echo is false for secrets such as
+// passphrases. It blocks until the user answers, ok is false if the user
+// cancelled or ctx is done.
+type Prompt func(ctx context.Context, question string, echo bool) (answer string, ok bool)
+
+var ErrPromptCancelled = errors.New("authentication cancelled by user")
+
+// Secrets looks up stored credentials, such as the encrypted vault. The
+// lookups may block while the user unlocks the store, ok is false when
+// nothing is stored.
+type Secrets interface {
+	Password(ctx context.Context, user, addr string) (password string, ok bool)
+	Passphrase(ctx context.Context, keyFile string) (passphrase string, ok bool)
+}
+
+// ExpandHome replaces a leading "~/" with the user's home directory.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// authMethods builds the ssh auth methods for cfg connecting to addr. The
+// returned closer releases the agent connection and must be called after
+// the handshake.
+func authMethods(ctx context.Context, cfg *Config, addr string) ([]ssh.AuthMethod, func(), error) {
+	noop := func() {}
+	switch cfg.Auth {
+	case "", AuthPassword, AuthKeyboardInteractive:
+		// 没有输入密码时使用保存的密码
+		if len(cfg.Password) == 0 && cfg.Secrets != nil {
+			cfg.Password, _ = cfg.Secrets.Password(ctx, cfg.User, addr)
+		}
+	}
+	switch cfg.Auth {
+	case "", AuthPassword:
+		// 部分服务器只开启了 keyboard-interactive 方式的密码登录
+		return []ssh.AuthMethod{
+			ssh.Password(cfg.Password),
+			ssh.KeyboardInteractive(keyboardInteractive(ctx, cfg)),
+		}, noop, nil
+	case AuthPublicKey:
+		signer, err := loadPrivateKey(ctx, cfg)
+		if err != nil {
+			return nil, noop, err
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, noop, nil
+	case AuthAgent:
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if len(sock) == 0 {
+			return nil, noop, errors.New("ssh agent is not available, SSH_AUTH_SOCK is not set")
+		}
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, noop, fmt.Errorf("connect to ssh agent failed, %v", err)
+		}
+		client := agent.NewClient(conn)
+		return []ssh.AuthMethod{ssh.PublicKeysCallback(client.Signers)}, func() { conn.Close() }, nil
+	case AuthKeyboardInteractive:
+		return []ssh.AuthMethod{ssh.KeyboardInteractive(keyboardInteractive(ctx, cfg))}, noop, nil
+	}
+	return nil, noop, fmt.Errorf("unsupported auth method %q", cfg.Auth)
+}
+
+func loadPrivateKey(ctx context.Context, cfg *Config) (ssh.Signer, error) {
+	path := ExpandHome(cfg.KeyFile)
+	if len(path) == 0 {
+		return nil, errors.New("private key file is required")
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key failed, %v", err)
+	}
+	signer, err := ssh.ParsePrivateKey(pem)
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		if err != nil {
+			return nil, fmt.Errorf("parse private key %s failed, %v", path, err)
+		}
+		return signer, nil
+	}
+
+	// 加密的私钥，先用配置中或保存的口令，没有则询问用户
+	passphrase := cfg.Passphrase
+	if len(passphrase) == 0 && cfg.Secrets != nil {
+		passphrase, _ = cfg.Secrets.Passphrase(ctx, path)
+	}
+	if len(passphrase) == 0 {
+		if cfg.Prompt == nil {
+			return nil, fmt.Errorf("private key %s is encrypted, passphrase is required", path)
+		}
+		var ok bool
+		passphrase, ok = cfg.Prompt(ctx, fmt.Sprintf("Enter passphrase for key %s:", path), false)
+		if !ok {
+			return nil, ErrPromptCancelled
+		}
+	}
+	signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key %s failed, %v", path, err)
+	}
+	return signer, nil
+}
+
+// keyboardInteractive answers a single hidden question with the configured
+// password and asks the user for everything else.
+func keyboardInteractive(ctx context.Context, cfg *Config) ssh.KeyboardInteractiveChallenge {
+	passwordUsed := false
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i, q := range questions {
+			if len(questions) == 1 && !echos[i] && len(cfg.Password) != 0 && !passwordUsed {
+				passwordUsed = true
+				answers[i] = cfg.Password
+				continue
+			}
+			if cfg.Prompt == nil {
+				return nil, fmt.Errorf("server asked %q, but prompting is not available", q)
+			}
+			text := strings.TrimSpace(strings.Join([]string{name, instruction, q}, "\n"))
+			answer, ok := cfg.Prompt(ctx, text, echos[i])
+			if !ok {
+				return nil, ErrPromptCancelled
+			}
+			answers[i] = answer
+		}
+		return answers, nil
+	}
+}
@@ -0,0 +1,331 @@
+package sshclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	DefaultPort    = "22"
+	DefaultTimeout = 10 * time.Second
+)
+
+// Config describes how to reach and authenticate against a remote host.
+type Config struct {
+	Host string
+	User string
+	// Auth selects the authentication method, empty means password.
+	Auth     AuthMethod
+	Password string
+	// KeyFile and Passphrase are used by public key authentication, the
+	// user is prompted when the key is encrypted and Passphrase is empty.
+	KeyFile    string
+	Passphrase string
+	// Prompt asks the user for passphrases and keyboard-interactive answers.
+	Prompt Prompt
+	// Secrets supplies stored passwords and passphrases that were not
+	// given in the config, it is consulted before prompting.
+	Secrets Secrets
+	Timeout time.Duration
+	// KnownHostsFile defaults to ~/.ssh/known_hosts.
+	KnownHostsFile string
+	// HostKeyPrompt is asked about unknown hosts, when nil they are rejected.
+	HostKeyPrompt HostKeyPrompt
+	// Jumps are the bastion hosts dialed in order before the target, each
+	// with its own authentication. Unset prompts, secrets, timeout and
+	// known_hosts are inherited from the target config.
+	Jumps []Config
+}
+
+// ErrClientClosed is returned by a client used after Close, it does not
+// dial again.
+var ErrClientClosed = errors.New("ssh connection is closed")
+
+// Client owns a single SSH connection. The connection is opened lazily
+// on the first session and reused until Close is called, after that the
+// client fails with ErrClientClosed. A client is safe for concurrent use.
+type Client struct {
+	cfg   Config
+	addr  string
+	jumps []hop
+	// mu 保护 conn、via 和 closed，拨号期间不持有
+	mu   sync.Mutex
+	conn *ssh.Client
+	// via 是已连接的跳板机，关闭时与目标连接一起关闭
+	via    []*ssh.Client
+	closed bool
+}
+
+// New validates the config and normalises the host address, it does
+// not dial the remote host.
+func New(cfg Config) (*Client, error) {
+	addr, err := NormalizeAddr(cfg.Host)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.User) == 0 {
+		return nil, errors.New("login user name is required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if len(cfg.KnownHostsFile) == 0 {
+		cfg.KnownHostsFile = DefaultKnownHostsFile()
+	}
+	jumps, err := newHops(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{cfg: cfg, addr: addr, jumps: jumps}, nil
+}
+
+// NormalizeAddr returns host in "host:port" form, adding the default ssh
+// port when it is missing. IPv6 literals are accepted with or without
+// brackets, e.g. "fe80::1", "[fe80::1]" and "[fe80::1]:2222".
+func NormalizeAddr(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if len(host) == 0 {
+		return "", errors.New("ip address is required")
+	}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if len(h) == 0 {
+			return "", fmt.Errorf("invalid address %q, missing host", host)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return "", fmt.Errorf("invalid address %q, bad port %q", host, port)
+		}
+		return net.JoinHostPort(h, port), nil
+	}
+	h := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.Contains(h, ":") && net.ParseIP(h) == nil {
+		return "", fmt.Errorf("invalid address %q", host)
+	}
+	return net.JoinHostPort(h, DefaultPort), nil
+}
+
+// Addr returns the normalised "host:port" address.
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+// User returns the login user name.
+func (c *Client) User() string {
+	return c.cfg.User
+}
+
+// Connect dials the remote host, through the jump hosts if any, when there
+// is no open connection yet. Dialing is aborted when ctx is cancelled.
+func (c *Client) Connect(ctx context.Context) error {
+	_, err := c.connect(ctx)
+	return err
+}
+
+// connect returns the open connection, dialing it first if needed.
+func (c *Client) connect(ctx context.Context) (*ssh.Client, error) {
+	c.mu.Lock()
+	conn, closed := c.conn, c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrClientClosed
+	}
+	if conn != nil {
+		return conn, nil
+	}
+
+	via := make([]*ssh.Client, 0, len(c.jumps))
+	var prev *ssh.Client
+	for _, h := range c.jumps {
+		conn, err := h.dial(ctx, prev)
+		if err != nil {
+			closeAll(via)
+			return nil, err
+		}
+		via = append(via, conn)
+		prev = conn
+	}
+	target := hop{cfg: c.cfg, addr: c.addr}
+	conn, err := target.dial(ctx, prev)
+	if err != nil {
+		closeAll(via)
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 拨号期间被关闭或者已经由其他调用者连接，丢弃这次的连接
+	if c.closed || c.conn != nil {
+		conn.Close()
+		closeAll(via)
+		if c.closed {
+			return nil, ErrClientClosed
+		}
+		return c.conn, nil
+	}
+	c.conn = conn
+	c.via = via
+	return conn, nil
+}
+
+// NewSession opens a new session on the connection, dialing first if needed.
+func (c *Client) NewSession(ctx context.Context) (*ssh.Session, error) {
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("create session failed, %v", err)
+	}
+	return session, nil
+}
+
+// Result is the outcome of a command that ran to completion.
+type Result struct {
+	Stdout []byte
+	Stderr []byte
+	// ExitCode is -1 when the server did not report an exit status.
+	ExitCode int
+	// Signal is the name of the signal that killed the command, e.g. "KILL".
+	Signal   string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Failed reports whether the command exited non-zero or was killed.
+func (r *Result) Failed() bool {
+	return r.ExitCode != 0 || len(r.Signal) != 0
+}
+
+// Status describes how the command ended, e.g. "exit 1 after 2.1s".
+func (r *Result) Status() string {
+	status := fmt.Sprintf("exit %d", r.ExitCode)
+	switch {
+	case len(r.Signal) != 0:
+		status = "killed by signal " + r.Signal
+	case r.ExitCode < 0:
+		status = "exit status unknown"
+	}
+	return fmt.Sprintf("%s after %v", status, r.Duration.Round(time.Millisecond))
+}
+
+// Exec runs cmd in a new session with stdout and stderr captured
+// separately. A command exiting non-zero is not an error, the exit status
+// is in the result. Cancelling ctx closes the session, which unblocks the
+// remote command.
+func (c *Client) Exec(ctx context.Context, cmd string) (*Result, error) {
+	return c.exec(ctx, cmd, nil)
+}
+
+func (c *Client) exec(ctx context.Context, cmd string, sudo *Sudo) (*Result, error) {
+	var stdout, stderr bytes.Buffer
+	res, err := c.execute(ctx, cmd, sudo, &stdout, &stderr)
+	if res != nil {
+		res.Stdout = stdout.Bytes()
+		res.Stderr = stderr.Bytes()
+	}
+	return res, err
+}
+
+// execute runs cmd with its output copied to the writers, which are
+// written from different goroutines. A non-nil sudo runs cmd as root.
+func (c *Client) execute(ctx context.Context, cmd string, sudo *Sudo, stdout, stderr io.Writer) (*Result, error) {
+	session, err := c.NewSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	var sw *sudoWriter
+	if sudo != nil {
+		if cmd, sw, err = sudo.attach(session, cmd, stderr); err != nil {
+			return nil, err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() {
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+	})
+	defer stop()
+
+	res := &Result{Start: time.Now()}
+	err = session.Run(cmd)
+	res.Duration = time.Since(res.Start)
+	if sw != nil {
+		sw.flush()
+	}
+	if ctx.Err() != nil {
+		return res, ctx.Err()
+	}
+
+	var exitErr *ssh.ExitError
+	var missing *ssh.ExitMissingError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitStatus()
+		res.Signal = exitErr.Signal()
+	case errors.As(err, &missing):
+		res.ExitCode = -1
+	default:
+		return res, fmt.Errorf("execute command failed, %v", err)
+	}
+	if sw != nil && res.Failed() {
+		if err := sw.err(); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// Run executes cmd and returns its stdout. A failing command is an error
+// that includes its stderr.
+func (c *Client) Run(ctx context.Context, cmd string) ([]byte, error) {
+	return c.run(ctx, cmd, nil)
+}
+
+func (c *Client) run(ctx context.Context, cmd string, sudo *Sudo) ([]byte, error) {
+	res, err := c.exec(ctx, cmd, sudo)
+	if err != nil {
+		if res != nil {
+			return res.Stdout, err
+		}
+		return nil, err
+	}
+	if res.Failed() {
+		msg := strings.TrimSpace(string(res.Stderr))
+		if len(msg) == 0 {
+			return res.Stdout, fmt.Errorf("execute command failed, %s", res.Status())
+		}
+		return res.Stdout, fmt.Errorf("execute command failed, %s: %s", res.Status(), msg)
+	}
+	return res.Stdout, nil
+}
+
+// Close closes the underlying connection and the jump host connections, it
+// is safe to call more than once.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	conn, via := c.conn, c.via
+	c.conn = nil
+	c.via = nil
+	c.closed = true
+	c.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	err := conn.Close()
+	closeAll(via)
+	return err
+}
@@ -0,0 +1,34 @@
+package sshclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// Dial connects to addr from the remote host, like the target of ssh -L.
+func (c *Client) Dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s failed, %v", addr, err)
+	}
+	return conn, nil
+}
+
+// Listen asks the remote host to listen on addr and hands its connections
+// to the returned listener, like ssh -R.
+func (c *Client) Listen(ctx context.Context, network, addr string) (net.Listener, error) {
+	client, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := client.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("remote listen on %s failed, %v", addr, err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,136 @@
+package sshclient
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyPrompt asks the user whether the key of an unknown host should be
+// trusted. It blocks until the user answers or ctx is cancelled.
+type HostKeyPrompt func(ctx context.Context, host string, key ssh.PublicKey) bool
+
+var (
+	ErrHostKeyRejected = errors.New("host key verification failed, the key was not accepted")
+	ErrHostKeyUnknown  = errors.New("host key verification failed, host is not in known_hosts")
+)
+
+// HostKeyChangedError is returned when the remote host presents a key that
+// differs from the one recorded in known_hosts. It is never prompted for.
+type HostKeyChangedError struct {
+	Host        string
+	Fingerprint string
+	File        string
+	Line        int
+}
+
+func (e *HostKeyChangedError) Error() string {
+	return fmt.Sprintf("WARNING: the host key of %s has changed, someone could be eavesdropping on you! "+
+		"Offered key %s does not match %s:%d, remove that line if the change is expected",
+		e.Host, e.Fingerprint, e.File, e.Line)
+}
+
+// DefaultKnownHostsFile returns ~/.ssh/known_hosts.
+func DefaultKnownHostsFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+// ensureKnownHosts creates an empty known_hosts file (and its directory) the
+// way ssh does, knownhosts.New refuses to open missing files.
+func ensureKnownHosts(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// hostKeyCallback verifies host keys of addr against path. Unknown hosts are
+// passed to prompt and saved when accepted, changed keys are always a hard
+// error. It also returns the key algorithms already known for addr.
+func hostKeyCallback(ctx context.Context, path, addr string, prompt HostKeyPrompt) (ssh.HostKeyCallback, []string, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("unable to locate known_hosts file")
+	}
+	if err := ensureKnownHosts(path); err != nil {
+		return nil, nil, fmt.Errorf("open known_hosts failed, %v", err)
+	}
+	known, err := knownhosts.New(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load known_hosts failed, %v", err)
+	}
+	callback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := known(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) > 0 {
+			return &HostKeyChangedError{
+				Host:        knownhosts.Normalize(hostname),
+				Fingerprint: ssh.FingerprintSHA256(key),
+				File:        keyErr.Want[0].Filename,
+				Line:        keyErr.Want[0].Line,
+			}
+		}
+		if prompt == nil {
+			return ErrHostKeyUnknown
+		}
+		if !prompt(ctx, knownhosts.Normalize(hostname), key) {
+			return ErrHostKeyRejected
+		}
+		return appendKnownHost(path, hostname, key)
+	}
+	return callback, knownHostKeyAlgorithms(known, addr), nil
+}
+
+func appendKnownHost(path, hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("save host key failed, %v", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{hostname}, key)); err != nil {
+		return fmt.Errorf("save host key failed, %v", err)
+	}
+	return nil
+}
+
+// knownHostKeyAlgorithms returns the host key algorithms already recorded for
+// addr. Restricting the handshake to them keeps a server that offers several
+// key types from being reported as changed. Nil means the host is unknown.
+func knownHostKeyAlgorithms(callback ssh.HostKeyCallback, addr string) []string {
+	// 用一个不可能匹配的公钥探测 known_hosts，KeyError.Want 里就是已知的密钥
+	probe, err := ssh.NewPublicKey(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public())
+	if err != nil {
+		return nil
+	}
+	var keyErr *knownhosts.KeyError
+	if !errors.As(callback(addr, &net.TCPAddr{}, probe), &keyErr) || len(keyErr.Want) == 0 {
+		return nil
+	}
+	algos := make([]string, 0, len(keyErr.Want))
+	for _, want := range keyErr.Want {
+		switch t := want.Key.Type(); t {
+		case ssh.KeyAlgoRSA:
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algos = append(algos, t)
+		}
+	}
+	return algos
+}
@@ -0,0 +1,141 @@
+package sshclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// hop is one connection in the chain from the local machine to the target.
+type hop struct {
+	cfg  Config
+	addr string
+	// jump 为 true 时表示这是跳板机，错误信息里注明
+	jump bool
+}
+
+// newHops validates the jump hosts of cfg and fills in the settings they
+// inherit from the target.
+func newHops(cfg Config) ([]hop, error) {
+	hops := make([]hop, 0, len(cfg.Jumps))
+	for _, j := range cfg.Jumps {
+		if len(j.Jumps) != 0 {
+			return nil, errors.New("nested jump hosts are not supported, list them in order instead")
+		}
+		addr, err := NormalizeAddr(j.Host)
+		if err != nil {
+			return nil, fmt.Errorf("jump host: %v", err)
+		}
+		if len(j.User) == 0 {
+			j.User = cfg.User
+		}
+		if j.Timeout <= 0 {
+			j.Timeout = cfg.Timeout
+		}
+		if len(j.KnownHostsFile) == 0 {
+			j.KnownHostsFile = cfg.KnownHostsFile
+		}
+		if j.Prompt == nil {
+			j.Prompt = cfg.Prompt
+		}
+		if j.HostKeyPrompt == nil {
+			j.HostKeyPrompt = cfg.HostKeyPrompt
+		}
+		if j.Secrets == nil {
+			j.Secrets = cfg.Secrets
+		}
+		hops = append(hops, hop{cfg: j, addr: addr, jump: true})
+	}
+	return hops, nil
+}
+
+// dial connects to the hop, directly when via is nil or through the
+// previous hop otherwise.
+func (h hop) dial(ctx context.Context, via *ssh.Client) (*ssh.Client, error) {
+	hostKeys, algos, err := hostKeyCallback(ctx, h.cfg.KnownHostsFile, h.addr, h.cfg.HostKeyPrompt)
+	if err != nil {
+		return nil, err
+	}
+	auth, release, err := authMethods(ctx, &h.cfg, h.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	config := &ssh.ClientConfig{
+		User:              h.cfg.User,
+		Auth:              auth,
+		HostKeyCallback:   hostKeys,
+		HostKeyAlgorithms: algos,
+	}
+
+	var netConn net.Conn
+	if via == nil {
+		dialer := net.Dialer{Timeout: h.cfg.Timeout}
+		netConn, err = dialer.DialContext(ctx, "tcp", h.addr)
+	} else {
+		dialCtx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+		netConn, err = via.DialContext(dialCtx, "tcp", h.addr)
+		cancel()
+	}
+	if err != nil {
+		return nil, h.wrap(err)
+	}
+
+	// ssh.NewClientConn 没有超时和取消，握手期间通过关闭底层连接来中断
+	stop := context.AfterFunc(ctx, func() { netConn.Close() })
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, h.addr, config)
+	if !stop() {
+		err = ctx.Err()
+	}
+	if err != nil {
+		netConn.Close()
+		var changed *HostKeyChangedError
+		if errors.As(err, &changed) || errors.Is(err, ErrHostKeyRejected) ||
+			errors.Is(err, ErrHostKeyUnknown) || errors.Is(err, ErrPromptCancelled) {
+			return nil, err
+		}
+		return nil, h.wrap(err)
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+func (h hop) wrap(err error) error {
+	if h.jump {
+		return fmt.Errorf("dial jump host %s failed, %v", h.addr, err)
+	}
+	return fmt.Errorf("dial %s failed, %v", h.addr, err)
+}
+
+func closeAll(conns []*ssh.Client) {
+	// 从靠近目标的一端开始关闭
+	for i := len(conns) - 1; i >= 0; i-- {
+		conns[i].Close()
+	}
+}
+
+// ParseProxyJump parses an OpenSSH ProxyJump list, "[user@]host[:port]"
+// entries separated by commas. "none" yields no jump hosts.
+func ParseProxyJump(spec string) ([]Config, error) {
+	spec = strings.TrimSpace(spec)
+	if len(spec) == 0 || strings.EqualFold(spec, "none") {
+		return nil, nil
+	}
+	jumps := make([]Config, 0)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(entry), "ssh://"))
+		if len(entry) == 0 {
+			return nil, fmt.Errorf("invalid jump host list %q", spec)
+		}
+		var j Config
+		if i := strings.LastIndex(entry, "@"); i >= 0 {
+			j.User, entry = entry[:i], entry[i+1:]
+		}
+		j.Host = entry
+		jumps = append(jumps, j)
+	}
+	return jumps, nil
+}
@@ -0,0 +1,318 @@
+package sshclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	DefaultIdleTimeout = 10 * time.Minute
+	// DefaultKeepAlive is how often pooled connections are probed.
+	DefaultKeepAlive = 30 * time.Second
+	keepAliveTimeout = 10 * time.Second
+)
+
+var ErrPoolClosed = errors.New("connection pool is closed")
+
+// Pool shares one connection per PoolKey between pages: jobs that reach
+// the same host as the same user with the same credentials and jump hosts
+// run their sessions on one connection. Pooled connections are probed with
+// keepalive requests, redialed when they die and closed after being unused
+// for the idle timeout.
+type Pool struct {
+	mu          sync.Mutex
+	conns       map[string]*pooled
+	idleTimeout time.Duration
+	stop        chan struct{}
+}
+
+type pooled struct {
+	key string
+	// dial 在建立连接期间持有，同一主机的并发请求等待同一次连接
+	dial   sync.Mutex
+	client *Client
+	// live 是仍然可用的连接，断开后置为 nil
+	live     atomic.Pointer[ssh.Client]
+	refs     int
+	lastUsed time.Time
+}
+
+// ConnInfo describes a pooled connection for display.
+type ConnInfo struct {
+	Key      string
+	Alive    bool
+	InUse    int
+	LastUsed time.Time
+}
+
+// NewPool starts a pool that closes connections idle for idleTimeout, zero
+// means DefaultIdleTimeout.
+func NewPool(idleTimeout time.Duration) *Pool {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	p := &Pool{
+		conns:       make(map[string]*pooled),
+		idleTimeout: idleTimeout,
+		stop:        make(chan struct{}),
+	}
+	go p.maintain(DefaultKeepAlive)
+	return p
+}
+
+// PoolKey identifies the connection of the client: the user and address,
+// the authentication and the jump hosts in order, followed by a
+// fingerprint of the passwords and passphrases. A connection is only
+// shared when all of them match, e.g. after switching to a key, another
+// jump host or another password a new connection is dialed.
+func (c *Client) PoolKey() string {
+	var b strings.Builder
+	b.WriteString(authKey(c.cfg.User, c.addr, c.cfg))
+	for _, h := range c.jumps {
+		b.WriteString(" via ")
+		b.WriteString(authKey(h.cfg.User, h.addr, h.cfg))
+	}
+	fmt.Fprintf(&b, " [credentials %s]", c.credentials())
+	return b.String()
+}
+
+func authKey(user, addr string, cfg Config) string {
+	method := cfg.Auth
+	if len(method) == 0 {
+		method = AuthPassword
+	}
+	if method == AuthPublicKey && len(cfg.KeyFile) != 0 {
+		return fmt.Sprintf("%s@%s (%s %s)", user, addr, method, cfg.KeyFile)
+	}
+	return fmt.Sprintf("%s@%s (%s)", user, addr, method)
+}
+
+// credentialKey is the HMAC key of the credential fingerprints, it is
+// random per process so a fingerprint shown in the UI reveals nothing about
+// the password.
+var credentialKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+// credentials returns a fingerprint of the passwords and passphrases of the
+// target and the jump hosts.
+func (c *Client) credentials() string {
+	mac := hmac.New(sha256.New, credentialKey)
+	for _, cfg := range append([]Config{c.cfg}, c.cfg.Jumps...) {
+		// 以 0 分隔，避免不同的拆分得到相同的输入
+		fmt.Fprintf(mac, "%s\x00%s\x00", cfg.Password, cfg.Passphrase)
+	}
+	return hex.EncodeToString(mac.Sum(nil)[:4])
+}
+
+// Get returns a connected client for cfg, reusing the pooled connection
+// with the same PoolKey when it is still alive. The client must not be
+// closed, call release when done with it instead. When the pool replaces a
+// dead connection the clients handed out before fail with ErrClientClosed
+// instead of dialing on their own, Get again to use the new one.
+func (p *Pool) Get(ctx context.Context, cfg Config) (*Client, func(), error) {
+	client, err := New(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := client.PoolKey()
+
+	p.mu.Lock()
+	if p.conns == nil {
+		p.mu.Unlock()
+		return nil, nil, ErrPoolClosed
+	}
+	e, ok := p.conns[key]
+	if !ok {
+		e = &pooled{key: key}
+		p.conns[key] = e
+	}
+	e.refs++
+	idle := time.Since(e.lastUsed)
+	p.mu.Unlock()
+
+	e.dial.Lock()
+	defer e.dial.Unlock()
+	// 空闲较久的连接可能已经断开但还没有被发现，使用前先探测
+	if conn := e.live.Load(); conn != nil && idle > DefaultKeepAlive {
+		if keepAlive(conn, keepAliveTimeout) != nil {
+			e.live.CompareAndSwap(conn, nil)
+		}
+	}
+	if e.live.Load() == nil {
+		if e.client != nil {
+			e.client.Close()
+			e.client = nil
+		}
+		// 只在这里拨号，已关闭的 Client 不会自己重新连接
+		conn, err := client.connect(ctx)
+		if err != nil {
+			p.release(e)
+			return nil, nil, err
+		}
+		e.client = client
+		e.live.Store(conn)
+		// 连接断开时 Wait 返回，下次 Get 会重新连接
+		go func() {
+			conn.Wait()
+			e.live.CompareAndSwap(conn, nil)
+		}()
+	}
+	var once sync.Once
+	return e.client, func() { once.Do(func() { p.release(e) }) }, nil
+}
+
+func (p *Pool) release(e *pooled) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.refs--
+	e.lastUsed = time.Now()
+}
+
+// SetIdleTimeout changes how long unused connections are kept open.
+func (p *Pool) SetIdleTimeout(d time.Duration) {
+	if d <= 0 {
+		d = DefaultIdleTimeout
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idleTimeout = d
+}
+
+func (p *Pool) IdleTimeout() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.idleTimeout
+}
+
+// Conns returns the pooled connections sorted by key.
+func (p *Pool) Conns() []ConnInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	infos := make([]ConnInfo, 0, len(p.conns))
+	for _, e := range p.conns {
+		infos = append(infos, ConnInfo{
+			Key:      e.key,
+			Alive:    e.live.Load() != nil,
+			InUse:    e.refs,
+			LastUsed: e.lastUsed,
+		})
+	}
+	slices.SortFunc(infos, func(a, b ConnInfo) int {
+		if a.Key < b.Key {
+			return -1
+		}
+		if a.Key > b.Key {
+			return 1
+		}
+		return 0
+	})
+	return infos
+}
+
+// Drop closes the connection with key, clients in use get errors.
+func (p *Pool) Drop(key string) {
+	p.mu.Lock()
+	e, ok := p.conns[key]
+	delete(p.conns, key)
+	p.mu.Unlock()
+	if ok {
+		go e.close()
+	}
+}
+
+// Close stops the maintenance goroutine and closes every connection.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	conns := p.conns
+	if conns != nil {
+		close(p.stop)
+	}
+	p.conns = nil
+	p.mu.Unlock()
+	for _, e := range conns {
+		e.close()
+	}
+}
+
+func (e *pooled) close() {
+	e.dial.Lock()
+	defer e.dial.Unlock()
+	if e.client != nil {
+		e.client.Close()
+		e.client = nil
+	}
+	e.live.Store(nil)
+}
+
+// maintain probes the pooled connections every interval and closes the
+// idle ones.
+func (p *Pool) maintain(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		idle := make([]*pooled, 0)
+		probe := make([]*ssh.Client, 0)
+		p.mu.Lock()
+		for key, e := range p.conns {
+			if e.refs == 0 && (e.live.Load() == nil || time.Since(e.lastUsed) > p.idleTimeout) {
+				delete(p.conns, key)
+				idle = append(idle, e)
+				continue
+			}
+			if conn := e.live.Load(); conn != nil {
+				probe = append(probe, conn)
+			}
+		}
+		p.mu.Unlock()
+
+		for _, e := range idle {
+			e.close()
+		}
+		for _, conn := range probe {
+			go keepAlive(conn, keepAliveTimeout)
+		}
+	}
+}
+
+// keepAlive sends an OpenSSH keepalive request and closes conn when there
+// is no reply within timeout. A refusal still proves the peer is alive.
+func keepAlive(conn *ssh.Client, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			conn.Close()
+		}
+		return err
+	case <-timer.C:
+		conn.Close()
+		return errors.New("keepalive timed out")
+	}
+}
@@ -0,0 +1,13 @@
+package sshclient
+
+import "strings"
+
+// Quote quotes s for the remote POSIX shell, e.g. it's becomes
+//
+//	'it'\''s'
+func Quote(s string) string {
+	if len(s) != 0 && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@%+,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,22 @@
+package sshclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/sftp"
+)
+
+// SFTP starts the sftp subsystem on the connection, dialing first if
+// needed. Close the returned client when done, the connection stays open.
+func (c *Client) SFTP(ctx context.Context) (*sftp.Client, error) {
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return nil, fmt.Errorf("start sftp failed, %v", err)
+	}
+	return client, nil
+}
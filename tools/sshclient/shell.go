@@ -0,0 +1,78 @@
+package sshclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultTerm is the terminal type announced to the remote host.
+const DefaultTerm = "xterm-256color"
+
+// Shell is an interactive login shell running on a pseudo terminal.
+type Shell struct {
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+}
+
+// Shell requests a pty of cols x rows cells and starts the login shell.
+func (c *Client) Shell(ctx context.Context, term string, cols, rows int) (*Shell, error) {
+	session, err := c.NewSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 38400,
+		ssh.TTY_OP_OSPEED: 38400,
+	}
+	if err := session.RequestPty(term, rows, cols, modes); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("request pty failed, %v", err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	// pty 会把 stderr 合并到 stdout
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.Shell(); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("start shell failed, %v", err)
+	}
+	return &Shell{session: session, stdin: stdin, stdout: stdout}, nil
+}
+
+// Read returns output of the shell, it returns io.EOF after the shell
+// exits.
+func (s *Shell) Read(p []byte) (int, error) {
+	return s.stdout.Read(p)
+}
+
+// Write sends keyboard input to the shell.
+func (s *Shell) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// Resize tells the remote pty about the new window size.
+func (s *Shell) Resize(cols, rows int) error {
+	return s.session.WindowChange(rows, cols)
+}
+
+// Wait waits for the shell to exit.
+func (s *Shell) Wait() error {
+	return s.session.Wait()
+}
+
+// Close ends the session, the connection stays open.
+func (s *Shell) Close() error {
+	return s.session.Close()
+}
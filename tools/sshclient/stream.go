@@ -0,0 +1,78 @@
+package sshclient
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// maxLineLength splits very long lines so a command without newlines does
+// not grow the buffer forever.
+const maxLineLength = 64 * 1024
+
+// Stream runs cmd like Exec but hands the output to onLine as it arrives,
+// one line at a time without the line ending. A carriage return also ends
+// a line, so progress output such as dd's is shown as it updates. The
+// result holds no output.
+func (c *Client) Stream(ctx context.Context, cmd string, onLine func(line string, stderr bool)) (*Result, error) {
+	return c.stream(ctx, cmd, nil, onLine)
+}
+
+func (c *Client) stream(ctx context.Context, cmd string, sudo *Sudo, onLine func(line string, stderr bool)) (*Result, error) {
+	// stdout 和 stderr 在不同的协程中写入，回调串行执行
+	var mu sync.Mutex
+	emit := func(stderr bool) func(string) {
+		return func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			onLine(line, stderr)
+		}
+	}
+	stdout := &lineWriter{emit: emit(false)}
+	stderr := &lineWriter{emit: emit(true)}
+	res, err := c.execute(ctx, cmd, sudo, stdout, stderr)
+	stdout.flush()
+	stderr.flush()
+	return res, err
+}
+
+// lineWriter splits written bytes into lines.
+type lineWriter struct {
+	buf  []byte
+	emit func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			if len(w.buf) >= maxLineLength {
+				w.emit(string(w.buf))
+				w.buf = w.buf[:0]
+			}
+			return len(p), nil
+		}
+		end := i + 1
+		if w.buf[i] == '\r' {
+			// \r\n 可能被拆到两次写入中，等待下一次写入
+			if i+1 == len(w.buf) {
+				return len(p), nil
+			}
+			if w.buf[i+1] == '\n' {
+				end++
+			}
+		}
+		w.emit(string(w.buf[:i]))
+		w.buf = w.buf[:copy(w.buf, w.buf[end:])]
+	}
+}
+
+// flush emits the last line when the output does not end with a newline.
+func (w *lineWriter) flush() {
+	line := bytes.TrimSuffix(w.buf, []byte("\r"))
+	if len(line) != 0 {
+		w.emit(string(line))
+	}
+	w.buf = nil
+}
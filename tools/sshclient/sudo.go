@@ -0,0 +1,177 @@
+package sshclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrSudoPassword      = errors.New("sudo: a password is required")
+	ErrSudoWrongPassword = errors.New("sudo: incorrect password")
+)
+
+// Sudo runs commands as root. Password is sent to sudo on stdin when it asks
+// for one, it never shows up in the output. An empty Password relies on
+// NOPASSWD rules or cached credentials and fails with ErrSudoPassword when
+// sudo wants a password.
+type Sudo struct {
+	Password string
+}
+
+// ExecSudo runs cmd like Exec as root, cmd is run by sh -c.
+func (c *Client) ExecSudo(ctx context.Context, cmd string, sudo Sudo) (*Result, error) {
+	return c.exec(ctx, cmd, &sudo)
+}
+
+// RunSudo runs cmd like Run as root.
+func (c *Client) RunSudo(ctx context.Context, cmd string, sudo Sudo) ([]byte, error) {
+	return c.run(ctx, cmd, &sudo)
+}
+
+// StreamSudo runs cmd like Stream as root.
+func (c *Client) StreamSudo(ctx context.Context, cmd string, sudo Sudo, onLine func(line string, stderr bool)) (*Result, error) {
+	return c.stream(ctx, cmd, &sudo, onLine)
+}
+
+// Retry calls run with s until sudo accepts the password. When sudo needs a
+// password it does not have or rejects it, prompt asks the user for the
+// password of target. The accepted password stays in s for the next
+// commands. The error of run is returned when prompt is nil or the user
+// cancels.
+func (s *Sudo) Retry(ctx context.Context, target string, prompt Prompt, run func(sudo Sudo) error) error {
+	for {
+		err := run(*s)
+		if !SudoRejected(err) || prompt == nil {
+			return err
+		}
+		question := fmt.Sprintf("sudo password for %s:", target)
+		if errors.Is(err, ErrSudoWrongPassword) {
+			question = "Sorry, try again. " + question
+		}
+		answer, ok := prompt(ctx, question, false)
+		if !ok {
+			return err
+		}
+		s.Password = answer
+	}
+}
+
+// SudoRejected reports whether err means sudo did not run the command
+// because it needs a password or rejected the one it got.
+func SudoRejected(err error) bool {
+	return errors.Is(err, ErrSudoPassword) || errors.Is(err, ErrSudoWrongPassword)
+}
+
+// attach wraps cmd in sudo and sets up the session to answer the password
+// prompt, the returned writer filters the prompt out of stderr.
+func (s *Sudo) attach(session *ssh.Session, cmd string, stderr io.Writer) (string, *sudoWriter, error) {
+	sw := &sudoWriter{w: stderr}
+	if len(s.Password) == 0 {
+		session.Stderr = sw
+		return "sudo -n -- sh -c " + Quote(cmd), sw, nil
+	}
+
+	// 用随机的提示符识别 sudo 询问密码，避免和命令的输出混淆
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", nil, err
+	}
+	sw.marker = []byte("[sudo-" + hex.EncodeToString(b[:]) + "]")
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return "", nil, fmt.Errorf("open stdin failed, %v", err)
+	}
+	password := s.Password
+	sw.prompted = func(n int) {
+		// 只回答第一次询问，再次询问说明密码错误，sudo 读到 EOF 后退出
+		if n == 1 {
+			io.WriteString(stdin, password+"\n")
+			stdin.Close()
+		}
+	}
+	session.Stderr = sw
+	// sudo 不询问密码时 stdin 一直打开，命令改为从 /dev/null 读取
+	cmd = "exec </dev/null\n" + cmd
+	return fmt.Sprintf("sudo -S -p %s -- sh -c %s", Quote(string(sw.marker)), Quote(cmd)), sw, nil
+}
+
+// sudoHead is how much of stderr is kept to look for sudo's own errors,
+// which come before any output of the command.
+const sudoHead = 512
+
+// sudoWriter passes stderr through with the password prompts removed.
+type sudoWriter struct {
+	w        io.Writer
+	marker   []byte
+	prompted func(n int)
+	prompts  int
+	// buf 保存可能是提示符开头的部分，等待下一次写入
+	buf  []byte
+	head []byte
+}
+
+func (s *sudoWriter) Write(p []byte) (int, error) {
+	if n := min(len(p), sudoHead-len(s.head)); n > 0 {
+		s.head = append(s.head, p[:n]...)
+	}
+	if len(s.marker) == 0 {
+		return s.w.Write(p)
+	}
+	s.buf = append(s.buf, p...)
+	for {
+		i := bytes.Index(s.buf, s.marker)
+		if i < 0 {
+			break
+		}
+		if _, err := s.w.Write(s.buf[:i]); err != nil {
+			return 0, err
+		}
+		s.buf = s.buf[i+len(s.marker):]
+		s.prompts++
+		s.prompted(s.prompts)
+	}
+	keep := partialSuffix(s.buf, s.marker)
+	if _, err := s.w.Write(s.buf[:len(s.buf)-keep]); err != nil {
+		return 0, err
+	}
+	s.buf = append(s.buf[:0], s.buf[len(s.buf)-keep:]...)
+	return len(p), nil
+}
+
+// flush writes what was held back as a possible prompt.
+func (s *sudoWriter) flush() {
+	if len(s.buf) != 0 {
+		s.w.Write(s.buf)
+		s.buf = nil
+	}
+}
+
+// err reports whether a failed command was stopped by sudo for lack of a
+// valid password.
+func (s *sudoWriter) err() error {
+	switch {
+	case s.prompts > 1:
+		return ErrSudoWrongPassword
+	case bytes.Contains(s.head, []byte("a password is required")):
+		return ErrSudoPassword
+	}
+	return nil
+}
+
+// partialSuffix returns the length of the longest suffix of b that is a
+// proper prefix of marker.
+func partialSuffix(b, marker []byte) int {
+	for n := min(len(b), len(marker)-1); n > 0; n-- {
+		if bytes.HasSuffix(b, marker[:n]) {
+			return n
+		}
+	}
+	return 0
+}
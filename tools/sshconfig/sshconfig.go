@@ -0,0 +1,320 @@
+package sshconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxIncludeDepth matches the limit used by OpenSSH.
+const maxIncludeDepth = 16
+
+// Host is the result of resolving an alias, the fields hold the values the
+// ssh command line tool would use.
+type Host struct {
+	Alias         string
+	HostName      string
+	Port          int
+	User          string
+	IdentityFiles []string
+	// ProxyJump is the raw comma separated jump list, empty when not set
+	// or set to "none".
+	ProxyJump string
+}
+
+// Addr returns HostName:Port, the port defaults to 22.
+func (h Host) Addr() string {
+	port := h.Port
+	if port == 0 {
+		port = 22
+	}
+	host := h.HostName
+	if strings.Contains(host, ":") {
+		host = "[" + strings.Trim(host, "[]") + "]"
+	}
+	return host + ":" + strconv.Itoa(port)
+}
+
+type option struct {
+	key   string
+	value string
+}
+
+// block is a Host section, options before the first Host line belong to
+// a block that matches every host.
+type block struct {
+	patterns []string
+	// match 为 true 表示这是 Match 段，暂不支持，按不匹配处理
+	match bool
+	// parent 是 Include 所在的段，被包含文件里的段只有在它匹配时才生效
+	parent  *block
+	options []option
+}
+
+func (b *block) matches(host string) bool {
+	if b.match || (b.parent != nil && !b.parent.matches(host)) {
+		return false
+	}
+	matched := false
+	for _, p := range b.patterns {
+		negated := strings.HasPrefix(p, "!")
+		ok, _ := path.Match(strings.ToLower(strings.TrimPrefix(p, "!")), strings.ToLower(host))
+		if ok && negated {
+			return false
+		}
+		if ok {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// Config is a parsed ssh_config file including everything it includes.
+type Config struct {
+	blocks []*block
+}
+
+// DefaultPath returns ~/.ssh/config.
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "config")
+}
+
+// Load parses the config file at path, a missing file is an empty config.
+func Load(path string) (*Config, error) {
+	c := &Config{blocks: []*block{{patterns: []string{"*"}}}}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("open ssh config failed, %v", err)
+	}
+	defer f.Close()
+	if err := c.parse(f, path, nil, 0); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// Parse reads a config from r, Include directives are resolved relative to
+// ~/.ssh.
+func Parse(r io.Reader) (*Config, error) {
+	c := &Config{blocks: []*block{{patterns: []string{"*"}}}}
+	return c, c.parse(r, "<input>", nil, 0)
+}
+
+func (c *Config) parse(r io.Reader, name string, parent *block, depth int) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		key, args, err := splitLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %v", name, lineNo, err)
+		}
+		if len(key) == 0 {
+			continue
+		}
+		switch key {
+		case "host":
+			c.blocks = append(c.blocks, &block{patterns: args, parent: parent})
+		case "match":
+			c.blocks = append(c.blocks, &block{match: true, parent: parent})
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("%s:%d: include nested too deeply", name, lineNo)
+			}
+			cur := c.blocks[len(c.blocks)-1]
+			for _, pattern := range args {
+				if err := c.include(pattern, cur, depth+1); err != nil {
+					return fmt.Errorf("%s:%d: %v", name, lineNo, err)
+				}
+			}
+			// 被包含文件结束后恢复到 Include 所在的段
+			if c.blocks[len(c.blocks)-1] != cur {
+				c.blocks = append(c.blocks, &block{patterns: cur.patterns, match: cur.match, parent: cur.parent})
+			}
+		default:
+			if len(args) == 0 {
+				return fmt.Errorf("%s:%d: missing argument for %s", name, lineNo, key)
+			}
+			cur := c.blocks[len(c.blocks)-1]
+			cur.options = append(cur.options, option{key: key, value: strings.Join(args, " ")})
+		}
+	}
+	return scanner.Err()
+}
+
+// include parses every file matching pattern in place. Included files
+// continue the current Host section until they start their own.
+func (c *Config) include(pattern string, parent *block, depth int) error {
+	pattern = expandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(DefaultPath()), pattern)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("bad include pattern %q, %v", pattern, err)
+	}
+	slices.Sort(files)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("open included file failed, %v", err)
+		}
+		err = c.parse(f, file, parent, depth)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitLine returns the lower cased keyword and its arguments, supporting
+// "key value", "key=value" and double quoted arguments.
+func splitLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	key := strings.ToLower(line[:i])
+	rest := strings.TrimLeft(line[i:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+
+	args := make([]string, 0)
+	for len(rest) != 0 {
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return "", nil, errors.New("unterminated quoted argument")
+			}
+			args = append(args, rest[1:end+1])
+			rest = strings.TrimLeft(rest[end+2:], " \t")
+			continue
+		}
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		if strings.HasPrefix(rest[:end], "#") {
+			break
+		}
+		args = append(args, rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+	return key, args, nil
+}
+
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, p[1:])
+}
+
+// Aliases returns the literal host names declared in Host lines, wildcard
+// and negated patterns and sections that can never match are skipped.
+func (c *Config) Aliases() []string {
+	aliases := make([]string, 0)
+	for _, b := range c.blocks {
+		if b.match {
+			continue
+		}
+		for _, p := range b.patterns {
+			if strings.ContainsAny(p, "*?!") || slices.Contains(aliases, p) || !b.matches(p) {
+				continue
+			}
+			aliases = append(aliases, p)
+		}
+	}
+	return aliases
+}
+
+// Has reports whether alias is declared literally in a Host line.
+func (c *Config) Has(alias string) bool {
+	return slices.Contains(c.Aliases(), alias)
+}
+
+// Resolve applies every matching section in file order, the first value
+// obtained for an option wins, IdentityFile values accumulate, as in ssh.
+func (c *Config) Resolve(alias string) Host {
+	h := Host{Alias: alias}
+	seen := make(map[string]bool)
+	for _, b := range c.blocks {
+		if !b.matches(alias) {
+			continue
+		}
+		for _, o := range b.options {
+			if o.key == "identityfile" {
+				h.IdentityFiles = append(h.IdentityFiles, expandHome(expandTokens(o.value, alias)))
+				continue
+			}
+			if seen[o.key] {
+				continue
+			}
+			seen[o.key] = true
+			switch o.key {
+			case "hostname":
+				h.HostName = expandTokens(o.value, alias)
+			case "port":
+				h.Port, _ = strconv.Atoi(o.value)
+			case "user":
+				h.User = o.value
+			case "proxyjump":
+				if !strings.EqualFold(o.value, "none") {
+					h.ProxyJump = o.value
+				}
+			}
+		}
+	}
+	if len(h.HostName) == 0 {
+		h.HostName = alias
+	}
+	return h
+}
+
+// expandTokens supports the %h and %% tokens used in HostName and
+// IdentityFile.
+func expandTokens(s, host string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'h':
+			b.WriteString(host)
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,163 @@
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		line string
+		key  string
+		args []string
+	}{
+		{"", "", nil},
+		{"  # comment", "", nil},
+		{"HostName example.com", "hostname", []string{"example.com"}},
+		{"Port=2222", "port", []string{"2222"}},
+		{"Port = 2222", "port", []string{"2222"}},
+		{"\tUser\troot", "user", []string{"root"}},
+		{`IdentityFile "~/my keys/id_ed25519"`, "identityfile", []string{"~/my keys/id_ed25519"}},
+		{`Host a "b c" d # trailing comment`, "host", []string{"a", "b c", "d"}},
+		{"Host=web-* !web-db", "host", []string{"web-*", "!web-db"}},
+	}
+	for _, tt := range tests {
+		key, args, err := splitLine(tt.line)
+		if err != nil {
+			t.Errorf("splitLine(%q) failed, %v", tt.line, err)
+			continue
+		}
+		if key != tt.key || len(args) != len(tt.args) || len(args) != 0 && !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("splitLine(%q) = %q, %q, want %q, %q", tt.line, key, args, tt.key, tt.args)
+		}
+	}
+	if _, _, err := splitLine(`IdentityFile "unterminated`); err == nil {
+		t.Error("splitLine accepted an unterminated quote")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	const config = `
+User default
+IdentityFile ~/.ssh/id_all
+
+Host web-* !web-db
+	User deploy
+	Port 2200
+
+Host web-1
+	HostName 10.0.0.1
+	User ignored
+	IdentityFile ~/.ssh/id_web
+
+Host *.lan
+	HostName %h.example.com
+	IdentityFile ~/.ssh/%h
+	ProxyJump none
+
+Host db
+	HostName=db.internal
+	ProxyJump "bastion"
+	Port 5522
+`
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	c, err := Parse(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(name string) string { return filepath.Join(home, ".ssh", name) }
+
+	tests := []struct {
+		alias string
+		want  Host
+	}{
+		// 全局段在前面，所以 User default 先生效
+		{"web-1", Host{HostName: "10.0.0.1", Port: 2200, User: "default",
+			IdentityFiles: []string{key("id_all"), key("id_web")}}},
+		// 被否定的模式排除整个段
+		{"web-db", Host{HostName: "web-db", User: "default",
+			IdentityFiles: []string{key("id_all")}}},
+		{"nas.lan", Host{HostName: "nas.lan.example.com", User: "default",
+			IdentityFiles: []string{key("id_all"), key("nas.lan")}}},
+		{"db", Host{HostName: "db.internal", Port: 5522, User: "default", ProxyJump: "bastion",
+			IdentityFiles: []string{key("id_all")}}},
+	}
+	for _, tt := range tests {
+		tt.want.Alias = tt.alias
+		if got := c.Resolve(tt.alias); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Resolve(%q) = %+v, want %+v", tt.alias, got, tt.want)
+		}
+	}
+
+	if got, want := c.Aliases(), []string{"web-1", "db"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Aliases() = %q, want %q", got, want)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".ssh")
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 相对路径从 ~/.ssh 开始，包含的文件继续 Include 所在的段
+	write("config", "Host a\n\tInclude conf.d/*.conf\n\tUser after\nHost b\n\tInclude ~/.ssh/b.conf\n")
+	write("conf.d/1.conf", "HostName a.example.com\n")
+	write("conf.d/2.conf", "Host c\n\tUser from-c\n")
+	write("b.conf", "Port 2022\n")
+
+	c, err := Load(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		alias string
+		want  Host
+	}{
+		{"a", Host{Alias: "a", HostName: "a.example.com", User: "after"}},
+		{"b", Host{Alias: "b", HostName: "b", Port: 2022}},
+		// 被包含文件里的段只有在 Include 所在的段也匹配时才生效
+		{"c", Host{Alias: "c", HostName: "c"}},
+	}
+	for _, tt := range tests {
+		if got := c.Resolve(tt.alias); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Resolve(%q) = %+v, want %+v", tt.alias, got, tt.want)
+		}
+	}
+	if got, want := c.Aliases(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Aliases() = %q, want %q", got, want)
+	}
+
+	// 包含自己的文件在达到深度限制后报错
+	write("loop", "Include loop\n")
+	if _, err := Load(filepath.Join(dir, "loop")); err == nil || !strings.Contains(err.Error(), "nested too deeply") {
+		t.Errorf("Load of a recursive include = %v, want a depth error", err)
+	}
+}
+
+func TestExpandTokens(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain", "plain"},
+		{"%h.example.com", "host.example.com"},
+		{"100%%", "100%"},
+		{"%r@%h", "%r@host"},
+		{"trailing%", "trailing%"},
+	}
+	for _, tt := range tests {
+		if got := expandTokens(tt.in, "host"); got != tt.want {
+			t.Errorf("expandTokens(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// socks5 与 RFC 1928 的常量，只支持无认证的 CONNECT
+const (
+	socksVersion   = 5
+	socksNoAuth    = 0
+	socksNoMethods = 0xff
+	socksConnect   = 1
+
+	socksIPv4   = 1
+	socksDomain = 3
+	socksIPv6   = 4
+
+	replySucceeded   = 0
+	replyFailure     = 1
+	replyUnreachable = 4
+	replyRefused     = 5
+	replyBadCommand  = 7
+	replyBadAddress  = 8
+
+	handshakeTimeout = 10 * time.Second
+)
+
+// socksHandshake reads the greeting and the CONNECT request of a SOCKS5
+// client and returns the requested address. Requests that cannot be served
+// are answered with an error reply.
+func socksHandshake(conn net.Conn) (string, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	var hdr [2]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+		return "", fmt.Errorf("read socks greeting failed, %v", err)
+	}
+	if hdr[0] != socksVersion {
+		return "", fmt.Errorf("unsupported socks version %d", hdr[0])
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", fmt.Errorf("read socks greeting failed, %v", err)
+	}
+	method := byte(socksNoMethods)
+	for _, m := range methods {
+		if m == socksNoAuth {
+			method = socksNoAuth
+		}
+	}
+	if _, err := conn.Write([]byte{socksVersion, method}); err != nil {
+		return "", err
+	}
+	if method == socksNoMethods {
+		return "", errors.New("socks client requires authentication")
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return "", fmt.Errorf("read socks request failed, %v", err)
+	}
+	if req[1] != socksConnect {
+		socksReply(conn, replyBadCommand)
+		return "", fmt.Errorf("unsupported socks command %d", req[1])
+	}
+	var host string
+	switch req[3] {
+	case socksIPv4, socksIPv6:
+		ip := make(net.IP, 4)
+		if req[3] == socksIPv6 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", fmt.Errorf("read socks request failed, %v", err)
+		}
+		host = ip.String()
+	case socksDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return "", fmt.Errorf("read socks request failed, %v", err)
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return "", fmt.Errorf("read socks request failed, %v", err)
+		}
+		host = string(name)
+	default:
+		socksReply(conn, replyBadAddress)
+		return "", fmt.Errorf("unsupported socks address type %d", req[3])
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(conn, port[:]); err != nil {
+		return "", fmt.Errorf("read socks request failed, %v", err)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))), nil
+}
+
+// socksReply answers the CONNECT request, the bound address is not
+// reported.
+func socksReply(conn net.Conn, code byte) error {
+	_, err := conn.Write([]byte{socksVersion, code, 0, socksIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// replyCode picks the reply for a failed dial.
+func replyCode(err error) byte {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "refused"):
+		return replyRefused
+	case strings.Contains(msg, "unreachable"), strings.Contains(msg, "no such host"):
+		return replyUnreachable
+	}
+	return replyFailure
+}
@@ -0,0 +1,179 @@
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"tools/jsonstore"
+)
+
+// Kind is the direction of a forward.
+type Kind string
+
+const (
+	// Local listens on this machine and connects from the remote host,
+	// like ssh -L.
+	Local Kind = "local"
+	// Remote listens on the remote host and connects from this machine,
+	// like ssh -R.
+	Remote Kind = "remote"
+	// Dynamic is a local SOCKS5 proxy connecting from the remote host,
+	// like ssh -D.
+	Dynamic Kind = "dynamic"
+)
+
+// Spec defines a forward.
+type Spec struct {
+	Kind Kind `json:"kind"`
+	// Listen is the address listened on, on the remote host for remote
+	// forwards.
+	Listen string `json:"listen"`
+	// Target is the address connections are forwarded to, dynamic
+	// forwards take it from the SOCKS request.
+	Target string `json:"target,omitempty"`
+}
+
+func (s Spec) String() string {
+	switch s.Kind {
+	case Local:
+		return fmt.Sprintf("-L %s → %s", s.Listen, s.Target)
+	case Remote:
+		return fmt.Sprintf("-R %s → %s", s.Listen, s.Target)
+	}
+	return fmt.Sprintf("-D %s (SOCKS5)", s.Listen)
+}
+
+// Normalize checks the spec and fills in defaults: a bare port listens on
+// the loopback address, e.g. "8080" becomes "127.0.0.1:8080".
+func (s Spec) Normalize() (Spec, error) {
+	switch s.Kind {
+	case Local, Remote, Dynamic:
+	default:
+		return s, fmt.Errorf("unknown forward kind %q", s.Kind)
+	}
+	listen := strings.TrimSpace(s.Listen)
+	if len(listen) == 0 {
+		return s, errors.New("listen address is required")
+	}
+	if !strings.Contains(listen, ":") {
+		listen = "127.0.0.1:" + listen
+	}
+	listen, err := checkAddr(listen)
+	if err != nil {
+		return s, err
+	}
+	s.Listen = listen
+	if s.Kind == Dynamic {
+		s.Target = ""
+		return s, nil
+	}
+	target := strings.TrimSpace(s.Target)
+	if len(target) == 0 {
+		return s, errors.New("target address is required")
+	}
+	if s.Target, err = checkAddr(target); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+func checkAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q, use host:port", addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return "", fmt.Errorf("invalid address %q, bad port %q", addr, port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Store holds the saved forwards of each host, backed by a JSON file. It is
+// safe for concurrent use.
+type Store struct {
+	store *jsonstore.File
+	mu    sync.Mutex
+	hosts map[string][]Spec
+}
+
+type file struct {
+	Hosts map[string][]Spec `json:"hosts"`
+}
+
+// DefaultPath returns tunnels.json in the user config directory.
+func DefaultPath() string {
+	return jsonstore.DefaultPath("tunnels.json")
+}
+
+// Load reads the store at path, a missing file is an empty store.
+// A file that cannot be parsed is moved to path.bak, saving is refused
+// when it cannot be moved or read.
+func Load(path string) (*Store, error) {
+	store, f, err := jsonstore.Open[file](path, "tunnels")
+	s := &Store{store: store, hosts: make(map[string][]Spec)}
+	if f.Hosts != nil {
+		s.hosts = f.Hosts
+	}
+	return s, err
+}
+
+// Specs returns the forwards saved for host.
+func (s *Store) Specs(host string) []Spec {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.hosts[host])
+}
+
+// Put saves spec for host, replacing the forward of the same kind that
+// listens on the same address.
+func (s *Store) Put(host string, spec Spec) error {
+	spec, err := spec.Normalize()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	specs := slices.Clone(s.hosts[host])
+	i := slices.IndexFunc(specs, func(o Spec) bool { return o.Kind == spec.Kind && o.Listen == spec.Listen })
+	if i >= 0 {
+		specs[i] = spec
+	} else {
+		specs = append(specs, spec)
+	}
+	return s.set(host, specs)
+}
+
+// Delete removes spec from the forwards of host.
+func (s *Store) Delete(host string, spec Spec) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	specs := slices.DeleteFunc(slices.Clone(s.hosts[host]), func(o Spec) bool { return o == spec })
+	return s.set(host, specs)
+}
+
+// set replaces the forwards of host and saves the store, s.mu is held.
+func (s *Store) set(host string, specs []Spec) error {
+	hosts := make(map[string][]Spec, len(s.hosts)+1)
+	for k, v := range s.hosts {
+		hosts[k] = v
+	}
+	if len(specs) == 0 {
+		delete(hosts, host)
+	} else {
+		hosts[host] = specs
+	}
+	if err := s.save(hosts); err != nil {
+		return err
+	}
+	s.hosts = hosts
+	return nil
+}
+
+// save writes hosts to the store file.
+func (s *Store) save(hosts map[string][]Spec) error {
+	return s.store.Save(file{Hosts: hosts})
+}
@@ -0,0 +1,314 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dialTimeout limits connecting to the target of a forwarded connection.
+const dialTimeout = 15 * time.Second
+
+// Dialer is the ssh connection a tunnel runs over, implemented by
+// sshclient.Client.
+type Dialer interface {
+	Dial(ctx context.Context, network, addr string) (net.Conn, error)
+	Listen(ctx context.Context, network, addr string) (net.Listener, error)
+}
+
+type State int
+
+const (
+	Stopped State = iota
+	Running
+	Failed
+)
+
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Failed:
+		return "failed"
+	}
+	return "stopped"
+}
+
+// Stats is a snapshot of a tunnel.
+type Stats struct {
+	State State
+	// Err is why the tunnel failed, or the last error of a forwarded
+	// connection while it is running.
+	Err error
+	// Active is the number of open connections, Total counts all
+	// connections since the tunnel started.
+	Active int
+	Total  int
+	// Sent is the number of bytes forwarded to the targets, Received the
+	// number of bytes coming back.
+	Sent     int64
+	Received int64
+}
+
+// Tunnel forwards connections for one Spec. It is safe for concurrent use.
+type Tunnel struct {
+	Spec Spec
+	// changed 在状态或连接数变化时调用，用于刷新界面
+	changed func()
+
+	mu      sync.Mutex
+	state   State
+	err     error
+	ln      net.Listener
+	release func()
+	// cancel 中止正在进行的连接
+	cancel context.CancelFunc
+	conns  map[net.Conn]struct{}
+	active int
+	total  int
+	wg     sync.WaitGroup
+
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+// New returns a stopped tunnel, changed is called from other goroutines
+// when its state or connections change.
+func New(spec Spec, changed func()) *Tunnel {
+	if changed == nil {
+		changed = func() {}
+	}
+	return &Tunnel{Spec: spec, changed: changed}
+}
+
+// Stats returns the current state and counters.
+func (t *Tunnel) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Stats{
+		State:    t.state,
+		Err:      t.err,
+		Active:   t.active,
+		Total:    t.total,
+		Sent:     t.sent.Load(),
+		Received: t.received.Load(),
+	}
+}
+
+// Start listens and forwards connections over d until Stop is called or
+// the listener fails. release is called once the tunnel is stopped, also
+// when Start fails.
+func (t *Tunnel) Start(ctx context.Context, d Dialer, release func()) error {
+	t.mu.Lock()
+	running := t.state == Running
+	t.mu.Unlock()
+	if running {
+		release()
+		return fmt.Errorf("tunnel %s is already running", t.Spec)
+	}
+
+	var ln net.Listener
+	var err error
+	if t.Spec.Kind == Remote {
+		ln, err = d.Listen(ctx, "tcp", t.Spec.Listen)
+	} else {
+		var lc net.ListenConfig
+		if ln, err = lc.Listen(ctx, "tcp", t.Spec.Listen); err != nil {
+			err = fmt.Errorf("listen on %s failed, %v", t.Spec.Listen, err)
+		}
+	}
+	if err == nil && ctx.Err() != nil {
+		// 启动过程中被取消
+		ln.Close()
+		release()
+		return ctx.Err()
+	}
+	t.mu.Lock()
+	if err != nil {
+		t.state, t.err = Failed, err
+		t.mu.Unlock()
+		release()
+		t.changed()
+		return err
+	}
+	runCtx, cancel := context.WithCancel(context.Background())
+	t.state, t.err = Running, nil
+	t.ln, t.release, t.cancel = ln, release, cancel
+	t.conns = make(map[net.Conn]struct{})
+	t.active, t.total = 0, 0
+	t.sent.Store(0)
+	t.received.Store(0)
+	t.wg.Add(1)
+	t.mu.Unlock()
+	t.changed()
+
+	go t.serve(runCtx, ln, d)
+	return nil
+}
+
+// Stop closes the listener and every forwarded connection and waits for
+// them to finish.
+func (t *Tunnel) Stop() {
+	t.shutdown(Stopped, nil)
+	t.wg.Wait()
+}
+
+// shutdown closes everything and moves to state, it does nothing when the
+// tunnel is not running.
+func (t *Tunnel) shutdown(state State, err error) {
+	t.mu.Lock()
+	if t.ln == nil {
+		t.mu.Unlock()
+		return
+	}
+	t.ln.Close()
+	t.ln = nil
+	t.cancel()
+	for c := range t.conns {
+		c.Close()
+	}
+	release := t.release
+	t.release = nil
+	t.state, t.err = state, err
+	t.mu.Unlock()
+	release()
+	t.changed()
+}
+
+func (t *Tunnel) serve(ctx context.Context, ln net.Listener, d Dialer) {
+	defer t.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			// Stop 关闭监听时不算失败
+			t.shutdown(Failed, fmt.Errorf("accept failed, %v", err))
+			return
+		}
+		if !t.track(conn) {
+			conn.Close()
+			return
+		}
+		t.wg.Add(1)
+		go t.forward(ctx, conn, d)
+	}
+}
+
+// track adds a connection so Stop can close it, it returns false once the
+// tunnel is stopped.
+func (t *Tunnel) track(conn net.Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ln == nil {
+		return false
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+func (t *Tunnel) untrack(conn net.Conn) {
+	conn.Close()
+	t.mu.Lock()
+	delete(t.conns, conn)
+	t.mu.Unlock()
+}
+
+// forward connects conn to its target and copies data both ways until
+// either side closes.
+func (t *Tunnel) forward(ctx context.Context, conn net.Conn, d Dialer) {
+	defer t.wg.Done()
+	defer t.untrack(conn)
+
+	target := t.Spec.Target
+	if t.Spec.Kind == Dynamic {
+		var err error
+		if target, err = socksHandshake(conn); err != nil {
+			t.setErr(err)
+			return
+		}
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	remote, err := t.dial(dialCtx, d, target)
+	cancel()
+	if t.Spec.Kind == Dynamic {
+		code := byte(replySucceeded)
+		if err != nil {
+			code = replyCode(err)
+		}
+		socksReply(conn, code)
+	}
+	if err != nil {
+		t.setErr(err)
+		return
+	}
+	if !t.track(remote) {
+		remote.Close()
+		return
+	}
+	defer t.untrack(remote)
+
+	t.mu.Lock()
+	t.active++
+	t.total++
+	t.mu.Unlock()
+	t.changed()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(&counter{w: remote, n: &t.sent}, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(&counter{w: conn, n: &t.received}, remote)
+		done <- struct{}{}
+	}()
+	// 一个方向结束后关闭两端，另一个方向随之结束
+	<-done
+	conn.Close()
+	remote.Close()
+	<-done
+
+	t.mu.Lock()
+	t.active--
+	t.mu.Unlock()
+	t.changed()
+}
+
+// dial connects to the target, from this machine for remote forwards and
+// from the remote host otherwise.
+func (t *Tunnel) dial(ctx context.Context, d Dialer, target string) (net.Conn, error) {
+	if t.Spec.Kind != Remote {
+		return d.Dial(ctx, "tcp", target)
+	}
+	var nd net.Dialer
+	conn, err := nd.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s failed, %v", target, err)
+	}
+	return conn, nil
+}
+
+// setErr records the error of a forwarded connection.
+func (t *Tunnel) setErr(err error) {
+	t.mu.Lock()
+	if t.state == Running {
+		t.err = err
+	}
+	t.mu.Unlock()
+	t.changed()
+}
+
+// counter counts the bytes written through it.
+type counter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
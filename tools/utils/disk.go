@@ -58,6 +58,37 @@ func ignoreDevice(devType string) bool {
 	return ok
 }
 
+// UnmarshalJSON accepts the filesystem sizes both as strings and as the
+// numbers newer lsblk versions print with --bytes, unmounted filesystems
+// have null sizes.
+func (dev *BlockDevice) UnmarshalJSON(data []byte) error {
+	type plain BlockDevice
+	raw := struct {
+		*plain
+		FSAvail json.RawMessage `json:"fsavail,omitempty"`
+		FSSize  json.RawMessage `json:"fssize,omitempty"`
+		FSUsed  json.RawMessage `json:"fsused,omitempty"`
+	}{plain: (*plain)(dev)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	dev.FSAvail = rawString(raw.FSAvail)
+	dev.FSSize = rawString(raw.FSSize)
+	dev.FSUsed = rawString(raw.FSUsed)
+	return nil
+}
+
+func rawString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
 // GetBlockDevices get block devices
 func GetBlockDevices(result []byte) ([]BlockDevice, error) {
 	rawOut := make(map[string][]BlockDevice, 1)
@@ -81,34 +112,36 @@ func GetBlockDevices(result []byte) ([]BlockDevice, error) {
 	return res, nil
 }
 
+// IsRootDisk reports whether the device itself or any device below it,
+// partitions and the LVM, RAID or crypt devices on them, is mounted on /.
+// A disk with / on an LV of one of its partitions is a root disk, and so
+// is that partition.
 func (dev *BlockDevice) IsRootDisk() bool {
-	hasRoot := false
+	if dev.MountPoint == "/" {
+		return true
+	}
 	for _, child := range dev.Children {
-		if child.MountPoint == "/" {
-			hasRoot = true
-		}
-		if len(child.Children) > 0 {
-			hasRoot = child.IsRootDisk()
+		if child.IsRootDisk() {
+			return true
 		}
 	}
-	return hasRoot
+	return false
 }
 
+// IsMounted reports whether the device itself or any device below it is
+// mounted, and returns all their mountpoints. Like IsRootDisk it includes
+// the device's own mountpoint, so it also covers a partition or a disk
+// formatted without a partition table.
 func (dev *BlockDevice) IsMounted() (bool, []string) {
-	isMounted := false
 	mps := make([]string, 0)
+	if len(dev.MountPoint) != 0 {
+		mps = append(mps, dev.MountPoint)
+	}
 	for _, child := range dev.Children {
-		if len(child.MountPoint) != 0 {
-			isMounted = true
-			mps = append(mps, child.MountPoint)
-		}
-		if len(child.Children) > 0 {
-			mounted, mountpoints := child.IsMounted()
-			isMounted = mounted
-			mps = append(mps, mountpoints...)
-		}
+		_, mountpoints := child.IsMounted()
+		mps = append(mps, mountpoints...)
 	}
-	return isMounted, mps
+	return len(mps) != 0, mps
 }
 
 // devMpMap：key: device；value: mountpoint
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIsRootDiskAndIsMounted(t *testing.T) {
+	// sda1 挂载在 /boot，sda2 上的 LVM 卷挂载在 /，sdb 没有分区表直接格式化
+	sda := BlockDevice{Name: "/dev/sda", Type: "disk", Children: []BlockDevice{
+		{Name: "/dev/sda1", Type: "part", MountPoint: "/boot"},
+		{Name: "/dev/sda2", Type: "part", Children: []BlockDevice{
+			{Name: "/dev/mapper/vg-root", Type: "lvm", MountPoint: "/"},
+		}},
+		{Name: "/dev/sda3", Type: "part"},
+	}}
+	sdb := BlockDevice{Name: "/dev/sdb", Type: "disk", MountPoint: "/data"}
+	sdc := BlockDevice{Name: "/dev/sdc", Type: "disk", Children: []BlockDevice{
+		{Name: "/dev/sdc1", Type: "part"},
+	}}
+
+	tests := []struct {
+		dev     *BlockDevice
+		root    bool
+		mounted []string
+	}{
+		{&sda, true, []string{"/boot", "/"}},
+		{&sda.Children[0], false, []string{"/boot"}},
+		{&sda.Children[1], true, []string{"/"}},
+		{&sda.Children[2], false, nil},
+		{&sdb, false, []string{"/data"}},
+		{&sdc, false, nil},
+	}
+	for _, tt := range tests {
+		if root := tt.dev.IsRootDisk(); root != tt.root {
+			t.Errorf("%s: IsRootDisk() = %v, want %v", tt.dev.Name, root, tt.root)
+		}
+		mounted, mps := tt.dev.IsMounted()
+		if mounted != (len(tt.mounted) != 0) || !slices.Equal(mps, tt.mounted) {
+			t.Errorf("%s: IsMounted() = %v, %q, want %q", tt.dev.Name, mounted, mps, tt.mounted)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"tools/sshclient"
+)
+
+// FSType is a filesystem the partition editor creates.
+type FSType struct {
+	Name string
+	// Mkfs creates the filesystem, Force overwrites an existing one.
+	Mkfs  string
+	Force string
+	// LabelMax is the longest label the filesystem takes.
+	LabelMax int
+	Options  []MkfsOption
+}
+
+// MkfsOption is an optional argument of mkfs.
+type MkfsOption struct {
+	Name string
+	Args string
+}
+
+var FSTypes = []FSType{
+	{
+		Name: "ext4", Mkfs: "mkfs.ext4", Force: "-F", LabelMax: 16,
+		Options: []MkfsOption{
+			{"no reserved blocks", "-m 0"},
+			{"initialize inode tables now", "-E lazy_itable_init=0,lazy_journal_init=0"},
+			{"large files", "-T largefile"},
+		},
+	},
+	{
+		Name: "xfs", Mkfs: "mkfs.xfs", Force: "-f", LabelMax: 12,
+		Options: []MkfsOption{
+			{"reflink", "-m reflink=1"},
+			{"big timestamps", "-m bigtime=1"},
+		},
+	},
+	{
+		Name: "btrfs", Mkfs: "mkfs.btrfs", Force: "-f", LabelMax: 255,
+		Options: []MkfsOption{
+			{"duplicate metadata", "-m dup"},
+			{"xxhash checksums", "--csum xxhash"},
+		},
+	},
+}
+
+// LookupFSType returns the filesystem named name.
+func LookupFSType(name string) (FSType, bool) {
+	i := slices.IndexFunc(FSTypes, func(t FSType) bool { return t.Name == name })
+	if i < 0 {
+		return FSType{}, false
+	}
+	return FSTypes[i], true
+}
+
+// MkfsCmd creates a filesystem on dev with the chosen options, extra holds
+// more arguments separated by spaces.
+func MkfsCmd(fstype FSType, dev, label string, options []MkfsOption, extra string) (string, error) {
+	if len(label) > fstype.LabelMax {
+		return "", fmt.Errorf("label %q is too long, %s takes at most %d characters", label, fstype.Name, fstype.LabelMax)
+	}
+	args := []string{fstype.Mkfs, fstype.Force}
+	if len(label) != 0 {
+		args = append(args, "-L", sshclient.Quote(label))
+	}
+	for _, o := range options {
+		args = append(args, o.Args)
+	}
+	for _, a := range strings.Fields(extra) {
+		args = append(args, sshclient.Quote(a))
+	}
+	return strings.Join(append(args, sshclient.Quote(dev)), " "), nil
+}
+
+// MountCmd creates dir when needed and mounts dev on it.
+func MountCmd(dev, dir, options string) (string, error) {
+	if err := checkMountPoint(dir); err != nil {
+		return "", err
+	}
+	mount := "mount"
+	if len(options) != 0 {
+		mount += " -o " + sshclient.Quote(options)
+	}
+	return fmt.Sprintf("mkdir -p %s && %s %s %s", sshclient.Quote(dir), mount, sshclient.Quote(dev), sshclient.Quote(dir)), nil
+}
+
+// UmountCmd unmounts dev from every mountpoint.
+func UmountCmd(dev string) string {
+	// 不加 -A 时只卸载一个挂载点
+	return "umount --all-targets " + sshclient.Quote(dev)
+}
+
+func checkMountPoint(dir string) error {
+	switch {
+	case !path.IsAbs(dir):
+		return fmt.Errorf("mountpoint %q must be an absolute path", dir)
+	case path.Clean(dir) == "/":
+		return errors.New("refusing to mount on /")
+	case strings.ContainsAny(dir, " \t\n"):
+		// fstab 以空白分隔字段
+		return fmt.Errorf("mountpoint %q must not contain spaces", dir)
+	}
+	return nil
+}
+
+// Fstab is where the filesystems mounted at boot are listed.
+const Fstab = "/etc/fstab"
+
+// ReadFstab prints the fstab.
+const ReadFstab = "cat " + Fstab
+
+// FstabEntry is a line of the fstab.
+type FstabEntry struct {
+	Spec    string
+	File    string
+	VFSType string
+	MntOps  string
+	Freq    int
+	PassNo  int
+	// Line 是文件中的原始行，删除时按整行匹配
+	Line string
+}
+
+// ParseFstab returns the entries of an fstab, comments and blank lines are
+// skipped.
+func ParseFstab(data []byte) []FstabEntry {
+	var entries []FstabEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		e := FstabEntry{Spec: fields[0], File: fields[1], Line: line}
+		if len(fields) > 2 {
+			e.VFSType = fields[2]
+		}
+		if len(fields) > 3 {
+			e.MntOps = fields[3]
+		}
+		if len(fields) > 4 {
+			e.Freq, _ = strconv.Atoi(fields[4])
+		}
+		if len(fields) > 5 {
+			e.PassNo, _ = strconv.Atoi(fields[5])
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// FstabStatus is the result of checking an fstab entry against lsblk.
+type FstabStatus int
+
+const (
+	// FstabUnchecked entries are not block devices, e.g. tmpfs, or name
+	// them by label.
+	FstabUnchecked FstabStatus = iota
+	FstabOK
+	FstabMissing
+	// FstabMismatch entries name a device that is mounted elsewhere or
+	// holds another filesystem type.
+	FstabMismatch
+)
+
+// Check looks up the device of the entry among devs by UUID, PARTUUID or
+// path and returns what does not match.
+func (e FstabEntry) Check(devs []BlockDevice) (FstabStatus, string) {
+	var match func(d *BlockDevice) bool
+	key, value, _ := strings.Cut(e.Spec, "=")
+	switch {
+	case key == "UUID":
+		match = func(d *BlockDevice) bool { return strings.EqualFold(d.UUID, value) }
+	case key == "PARTUUID":
+		match = func(d *BlockDevice) bool { return strings.EqualFold(d.PartUUID, value) }
+	case strings.HasPrefix(e.Spec, "/dev/"):
+		match = func(d *BlockDevice) bool { return d.Name == e.Spec }
+	default:
+		return FstabUnchecked, ""
+	}
+	dev := findDevice(devs, match)
+	if dev == nil {
+		return FstabMissing, fmt.Sprintf("no device with %s", e.Spec)
+	}
+	if len(e.VFSType) != 0 && e.VFSType != "auto" && len(dev.FSType) != 0 && e.VFSType != dev.FSType {
+		return FstabMismatch, fmt.Sprintf("%s holds %s, not %s", dev.Name, dev.FSType, e.VFSType)
+	}
+	// swap 没有挂载点
+	if len(dev.MountPoint) != 0 && e.VFSType != "swap" && dev.MountPoint != e.File {
+		return FstabMismatch, fmt.Sprintf("%s is mounted on %s", dev.Name, dev.MountPoint)
+	}
+	return FstabOK, dev.Name
+}
+
+// findDevice returns the first device below devs that matches.
+func findDevice(devs []BlockDevice, match func(d *BlockDevice) bool) *BlockDevice {
+	for i := range devs {
+		if match(&devs[i]) {
+			return &devs[i]
+		}
+		if d := findDevice(devs[i].Children, match); d != nil {
+			return d
+		}
+	}
+	return nil
+}
+
+// FindDevice returns the device named name, also among the children.
+func FindDevice(devs []BlockDevice, name string) (*BlockDevice, bool) {
+	d := findDevice(devs, func(d *BlockDevice) bool { return d.Name == name })
+	return d, d != nil
+}
+
+// checkFsID checks a filesystem UUID or a PARTUUID taken from lsblk before
+// it is written to the fstab. Depending on the filesystem and partition
+// table they are a full UUID, e.g. 0a3c6a4e-5d0e-4f4b-9d5c-2b1f6f0e7a11,
+// or shorter hex strings like ABCD-1234 (vfat), 0a1b2c3d-01 (msdos
+// PARTUUID) or 16 hex digits (ntfs).
+func checkFsID(kind, id string) error {
+	if len(id) == 0 || len(id) > 36 || id[0] == '-' || id[len(id)-1] == '-' ||
+		strings.Trim(id, "0123456789abcdefABCDEF-") != "" {
+		return fmt.Errorf("invalid %s %q", kind, id)
+	}
+	return nil
+}
+
+// FstabLine returns the fstab line mounting the filesystem of dev on dir by
+// UUID.
+func FstabLine(dev *BlockDevice, dir, options string) (string, error) {
+	if len(dev.UUID) == 0 || len(dev.FSType) == 0 {
+		return "", fmt.Errorf("%s has no filesystem, create one first", dev.Name)
+	}
+	if err := checkFsID("UUID", dev.UUID); err != nil {
+		return "", err
+	}
+	if err := checkMountPoint(dir); err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(options, " \t") {
+		return "", fmt.Errorf("mount options %q must not contain spaces", options)
+	}
+	if len(options) == 0 {
+		options = "defaults"
+	}
+	pass := 2
+	if dev.FSType == "xfs" || dev.FSType == "btrfs" {
+		// xfs 和 btrfs 不在启动时检查
+		pass = 0
+	}
+	return fmt.Sprintf("UUID=%s %s %s %s 0 %d", dev.UUID, path.Clean(dir), dev.FSType, options, pass), nil
+}
+
+// FstabAddCmd appends line to the fstab after backing it up to fstab.bak,
+// a missing newline at the end of the file is added first. It refuses a
+// second entry for the same device or mountpoint.
+func FstabAddCmd(entries []FstabEntry, line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+		return "", fmt.Errorf("invalid fstab line %q", line)
+	}
+	if key, value, ok := strings.Cut(fields[0], "="); ok && (key == "UUID" || key == "PARTUUID") {
+		if err := checkFsID(key, value); err != nil {
+			return "", err
+		}
+	}
+	for _, e := range entries {
+		if e.Spec == fields[0] {
+			return "", fmt.Errorf("%s already has an fstab entry: %s", e.Spec, e.Line)
+		}
+		if e.File == fields[1] {
+			return "", fmt.Errorf("%s already has an fstab entry: %s", e.File, e.Line)
+		}
+	}
+	// 文件末尾没有换行时先补上，否则新行会接在最后一行后面
+	return fmt.Sprintf("cp -p %s %s.bak && { [ -z \"$(tail -c1 %s)\" ] || echo >> %s; } && printf '%%s\\n' %s >> %s",
+		Fstab, Fstab, Fstab, Fstab, sshclient.Quote(line), Fstab), nil
+}
+
+// FstabRemoveCmd deletes the lines equal to the line of e from the fstab
+// after backing it up to fstab.bak. The result is written to fstab.new and
+// moved over the fstab, which is left as it was when anything fails.
+func FstabRemoveCmd(e FstabEntry) string {
+	line := sshclient.Quote(e.Line)
+	// grep 没有输出任何行时退出状态为 1，不是错误；先复制 fstab.new 以保留权限
+	return fmt.Sprintf("{ grep -qxF -e %s %s || { echo 'entry not found in %s' >&2; false; }; } && "+
+		"cp -p %s %s.bak && cp -p %s %s.new && { grep -vxF -e %s %s.bak || [ $? -eq 1 ]; } > %s.new && mv %s.new %s",
+		line, Fstab, Fstab, Fstab, Fstab, Fstab, Fstab, line, Fstab, Fstab, Fstab, Fstab)
+}
+
+// PartitionPath returns the device of partition n of disk, e.g. /dev/sda1
+// or /dev/nvme0n1p1 when the disk name ends in a digit.
+func PartitionPath(disk string, n int) string {
+	if len(disk) != 0 && disk[len(disk)-1] >= '0' && disk[len(disk)-1] <= '9' {
+		return fmt.Sprintf("%sp%d", disk, n)
+	}
+	return fmt.Sprintf("%s%d", disk, n)
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckFsID(t *testing.T) {
+	tests := []struct {
+		id string
+		ok bool
+	}{
+		{"0a3c6a4e-5d0e-4f4b-9d5c-2b1f6f0e7a11", true},
+		{"0A3C6A4E-5D0E-4F4B-9D5C-2B1F6F0E7A11", true},
+		{"ABCD-1234", true},
+		{"0a1b2c3d-01", true},
+		{"01D8A3C2F4B5E6A7", true},
+		{"", false},
+		{"-abcd", false},
+		{"abcd-", false},
+		{"0a3c6a4e-5d0e-4f4b-9d5c-2b1f6f0e7a11-00", false},
+		{"abcd 1234", false},
+		{"abcd;reboot", false},
+		{"xyz", false},
+	}
+	for _, tt := range tests {
+		if err := checkFsID("UUID", tt.id); (err == nil) != tt.ok {
+			t.Errorf("checkFsID(%q) = %v, want ok %v", tt.id, err, tt.ok)
+		}
+	}
+}
+
+func TestFstabLine(t *testing.T) {
+	ext4 := &BlockDevice{Name: "/dev/sdb1", UUID: "0a3c6a4e-5d0e-4f4b-9d5c-2b1f6f0e7a11", FSType: "ext4"}
+	xfs := &BlockDevice{Name: "/dev/sdb2", UUID: "ABCD-1234", FSType: "xfs"}
+	tests := []struct {
+		name    string
+		dev     *BlockDevice
+		dir     string
+		options string
+		want    string
+	}{
+		{"defaults", ext4, "/data/", "", "UUID=0a3c6a4e-5d0e-4f4b-9d5c-2b1f6f0e7a11 /data ext4 defaults 0 2"},
+		{"options", ext4, "/data", "noatime,nofail", "UUID=0a3c6a4e-5d0e-4f4b-9d5c-2b1f6f0e7a11 /data ext4 noatime,nofail 0 2"},
+		{"no fsck", xfs, "/srv", "", "UUID=ABCD-1234 /srv xfs defaults 0 0"},
+		{"no filesystem", &BlockDevice{Name: "/dev/sdb3"}, "/data", "", ""},
+		{"bad uuid", &BlockDevice{Name: "/dev/sdb3", UUID: "x y", FSType: "ext4"}, "/data", "", ""},
+		{"relative", ext4, "data", "", ""},
+		{"root", ext4, "/", "", ""},
+		{"space in dir", ext4, "/my data", "", ""},
+		{"space in options", ext4, "/data", "a, b", ""},
+	}
+	for _, tt := range tests {
+		got, err := FstabLine(tt.dev, tt.dir, tt.options)
+		if len(tt.want) == 0 {
+			if err == nil {
+				t.Errorf("%s: got %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+const fstab = `# /etc/fstab
+UUID=11111111-2222-3333-4444-555555555555 / ext4 defaults 0 1
+/dev/sda2 none swap sw 0 0
+tmpfs /tmp tmpfs defaults 0 0`
+
+func TestFstabAddCmd(t *testing.T) {
+	entries := ParseFstab([]byte(fstab))
+	line := "UUID=0a3c6a4e-5d0e-4f4b-9d5c-2b1f6f0e7a11 /data ext4 defaults 0 2"
+	cmd, err := FstabAddCmd(entries, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `cp -p /etc/fstab /etc/fstab.bak && { [ -z "$(tail -c1 /etc/fstab)" ] || echo >> /etc/fstab; } && ` +
+		`printf '%s\n' 'UUID=0a3c6a4e-5d0e-4f4b-9d5c-2b1f6f0e7a11 /data ext4 defaults 0 2' >> /etc/fstab`
+	if cmd != want {
+		t.Errorf("FstabAddCmd() =\n%s\nwant\n%s", cmd, want)
+	}
+
+	// 原文件末尾没有换行，新行要另起一行
+	got := runFstabCmd(t, fstab, cmd)
+	if got != fstab+"\n"+line+"\n" {
+		t.Errorf("fstab after adding =\n%q", got)
+	}
+
+	for _, bad := range []string{
+		"",
+		"# UUID=0a3c6a4e /data ext4 defaults 0 2",
+		"UUID=0a3c6a4e /data",
+		"UUID=bad$(reboot) /data ext4 defaults 0 2",
+		"PARTUUID=x /data ext4 defaults 0 2",
+		"/dev/sda2 /data ext4 defaults 0 2",
+		"UUID=0a3c6a4e /tmp ext4 defaults 0 2",
+	} {
+		if cmd, err := FstabAddCmd(entries, bad); err == nil {
+			t.Errorf("FstabAddCmd(%q) = %q, want an error", bad, cmd)
+		}
+	}
+}
+
+func TestFstabRemoveCmd(t *testing.T) {
+	entries := ParseFstab([]byte(fstab + "\n"))
+	if len(entries) != 3 {
+		t.Fatalf("ParseFstab() = %+v, want 3 entries", entries)
+	}
+	swap := entries[1]
+	cmd := FstabRemoveCmd(swap)
+	want := `{ grep -qxF -e '/dev/sda2 none swap sw 0 0' /etc/fstab || { echo 'entry not found in /etc/fstab' >&2; false; }; } && ` +
+		`cp -p /etc/fstab /etc/fstab.bak && cp -p /etc/fstab /etc/fstab.new && ` +
+		`{ grep -vxF -e '/dev/sda2 none swap sw 0 0' /etc/fstab.bak || [ $? -eq 1 ]; } > /etc/fstab.new && mv /etc/fstab.new /etc/fstab`
+	if cmd != want {
+		t.Errorf("FstabRemoveCmd() =\n%s\nwant\n%s", cmd, want)
+	}
+
+	got := runFstabCmd(t, fstab+"\n", cmd)
+	if want := strings.Replace(fstab+"\n", swap.Line+"\n", "", 1); got != want {
+		t.Errorf("fstab after removing =\n%q\nwant\n%q", got, want)
+	}
+
+	// 删除唯一的一行时 grep 没有输出，不能当作失败
+	only := "tmpfs /tmp tmpfs defaults 0 0\n"
+	if got := runFstabCmd(t, only, FstabRemoveCmd(ParseFstab([]byte(only))[0])); got != "" {
+		t.Errorf("fstab after removing the only entry = %q, want empty", got)
+	}
+}
+
+// runFstabCmd runs cmd with /etc/fstab replaced by a temporary file holding
+// content and returns the file afterwards.
+func runFstabCmd(t *testing.T, content, cmd string) string {
+	t.Helper()
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh to run the fstab commands")
+	}
+	path := filepath.Join(t.TempDir(), "fstab")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(sh, "-c", strings.ReplaceAll(cmd, Fstab, path)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s failed, %v: %s", cmd, err, out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
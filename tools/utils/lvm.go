@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"tools/sshclient"
+)
+
+// LVM reports in bytes, with the fields the LVM page shows.
+const (
+	Pvs = "pvs --reportformat json --units b --nosuffix --options pv_name,vg_name,pv_fmt,pv_size,pv_free,pv_pe_count,pv_pe_alloc_count"
+	Vgs = "vgs --reportformat json --units b --nosuffix --options vg_name,vg_size,vg_free,vg_extent_size,vg_extent_count,vg_free_count"
+	Lvs = "lvs --reportformat json --units b --nosuffix --options lv_name,vg_name,lv_path,lv_size,lv_attr"
+)
+
+// PhysicalVolume is a device LVM stores volume groups on.
+type PhysicalVolume struct {
+	Name string
+	// VG 为空表示还没有加入卷组
+	VG           string
+	Format       string
+	Size         int64
+	Free         int64
+	Extents      int64
+	AllocExtents int64
+}
+
+// LogicalVolume is a volume carved out of a volume group.
+type LogicalVolume struct {
+	Name string
+	VG   string
+	Path string
+	Size int64
+	Attr string
+}
+
+// VolumeGroup is a pool of extents on its physical volumes that logical
+// volumes are allocated from.
+type VolumeGroup struct {
+	Name        string
+	Size        int64
+	Free        int64
+	ExtentSize  int64
+	Extents     int64
+	FreeExtents int64
+	PVs         []PhysicalVolume
+	LVs         []LogicalVolume
+}
+
+// Lvm is the LVM setup of a host, Orphans are the physical volumes in no
+// volume group.
+type Lvm struct {
+	VGs     []VolumeGroup
+	Orphans []PhysicalVolume
+}
+
+// lvmReport is the JSON printed with --reportformat json, numbers are
+// strings.
+type lvmReport struct {
+	Report []struct {
+		PV []map[string]string `json:"pv"`
+		VG []map[string]string `json:"vg"`
+		LV []map[string]string `json:"lv"`
+	} `json:"report"`
+}
+
+// ParseLvm parses the output of Pvs, Vgs and Lvs and puts the physical and
+// logical volumes into their volume groups.
+func ParseLvm(pvs, vgs, lvs []byte) (*Lvm, error) {
+	var reports [3]lvmReport
+	for i, data := range [][]byte{pvs, vgs, lvs} {
+		if err := json.Unmarshal(data, &reports[i]); err != nil {
+			return nil, fmt.Errorf("parse lvm report failed, %v", err)
+		}
+	}
+	l := &Lvm{}
+	for _, r := range reports[1].Report {
+		for _, row := range r.VG {
+			vg := VolumeGroup{Name: row["vg_name"]}
+			if err := parseLvmNumbers(row, map[string]*int64{
+				"vg_size":         &vg.Size,
+				"vg_free":         &vg.Free,
+				"vg_extent_size":  &vg.ExtentSize,
+				"vg_extent_count": &vg.Extents,
+				"vg_free_count":   &vg.FreeExtents,
+			}); err != nil {
+				return nil, err
+			}
+			l.VGs = append(l.VGs, vg)
+		}
+	}
+	for _, r := range reports[0].Report {
+		for _, row := range r.PV {
+			pv := PhysicalVolume{Name: row["pv_name"], VG: row["vg_name"], Format: row["pv_fmt"]}
+			if err := parseLvmNumbers(row, map[string]*int64{
+				"pv_size":           &pv.Size,
+				"pv_free":           &pv.Free,
+				"pv_pe_count":       &pv.Extents,
+				"pv_pe_alloc_count": &pv.AllocExtents,
+			}); err != nil {
+				return nil, err
+			}
+			if vg, ok := l.VG(pv.VG); ok {
+				vg.PVs = append(vg.PVs, pv)
+			} else {
+				l.Orphans = append(l.Orphans, pv)
+			}
+		}
+	}
+	for _, r := range reports[2].Report {
+		for _, row := range r.LV {
+			lv := LogicalVolume{Name: row["lv_name"], VG: row["vg_name"], Path: row["lv_path"], Attr: row["lv_attr"]}
+			if err := parseLvmNumbers(row, map[string]*int64{"lv_size": &lv.Size}); err != nil {
+				return nil, err
+			}
+			vg, ok := l.VG(lv.VG)
+			if !ok {
+				return nil, fmt.Errorf("logical volume %s is in unknown volume group %s", lv.Name, lv.VG)
+			}
+			vg.LVs = append(vg.LVs, lv)
+		}
+	}
+	return l, nil
+}
+
+func parseLvmNumbers(row map[string]string, fields map[string]*int64) error {
+	for name, v := range fields {
+		s := strings.TrimSpace(row[name])
+		if len(s) == 0 {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSuffix(s, "B"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("unexpected lvm %s %q", name, s)
+		}
+		*v = n
+	}
+	return nil
+}
+
+// VG returns the volume group named name.
+func (l *Lvm) VG(name string) (*VolumeGroup, bool) {
+	i := slices.IndexFunc(l.VGs, func(vg VolumeGroup) bool { return vg.Name == name })
+	if i < 0 {
+		return nil, false
+	}
+	return &l.VGs[i], true
+}
+
+// LV returns the logical volume name of the volume group vg.
+func (l *Lvm) LV(vg, name string) (*LogicalVolume, bool) {
+	g, ok := l.VG(vg)
+	if !ok {
+		return nil, false
+	}
+	i := slices.IndexFunc(g.LVs, func(lv LogicalVolume) bool { return lv.Name == name })
+	if i < 0 {
+		return nil, false
+	}
+	return &g.LVs[i], true
+}
+
+// FullName is the vg/lv name LVM commands take.
+func (lv *LogicalVolume) FullName() string {
+	return lv.VG + "/" + lv.Name
+}
+
+// Open reports whether the volume is in use, e.g. mounted, by the lv_attr
+// flags.
+func (lv *LogicalVolume) Open() bool {
+	return len(lv.Attr) > 5 && lv.Attr[5] == 'o'
+}
+
+// checkLvName checks a new logical volume name, LVM allows letters,
+// digits and "+_.-" but no leading '-'.
+func checkLvName(name string) error {
+	switch {
+	case len(name) == 0:
+		return errors.New("the logical volume needs a name")
+	case strings.Trim(name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789+_.-") != "",
+		name[0] == '-', name == ".", name == "..":
+		return fmt.Errorf("invalid logical volume name %q, use letters, digits, '+', '_', '.' and '-'", name)
+	}
+	return nil
+}
+
+// lvSize returns the lvcreate/lvextend size argument for size MiB, or all
+// free extents of vg when size is 0.
+func lvSize(vg *VolumeGroup, size int64, plus string) (string, error) {
+	if vg.FreeExtents == 0 {
+		return "", fmt.Errorf("volume group %s has no free extents", vg.Name)
+	}
+	if size == 0 {
+		return fmt.Sprintf("--extents %s100%%FREE", plus), nil
+	}
+	if size < 0 || size*MiB > vg.Free {
+		return "", fmt.Errorf("invalid size %d MiB, volume group %s has %d MiB free", size, vg.Name, vg.Free/MiB)
+	}
+	return fmt.Sprintf("--size %s%dm", plus, size), nil
+}
+
+// LvcreateCmd creates the logical volume name of size MiB in vg, all free
+// space when size is 0.
+func LvcreateCmd(vg *VolumeGroup, name string, size int64) (string, error) {
+	if err := checkLvName(name); err != nil {
+		return "", err
+	}
+	if slices.ContainsFunc(vg.LVs, func(lv LogicalVolume) bool { return lv.Name == name }) {
+		return "", fmt.Errorf("volume group %s already has a logical volume %s", vg.Name, name)
+	}
+	arg, err := lvSize(vg, size, "")
+	if err != nil {
+		return "", err
+	}
+	// --yes 清除新卷上残留的文件系统签名，否则 lvcreate 会等待回答
+	return fmt.Sprintf("lvcreate --yes --name %s %s %s", sshclient.Quote(name), arg, sshclient.Quote(vg.Name)), nil
+}
+
+// LvextendCmd grows lv by size MiB, by all free space of vg when size is 0.
+// With resizefs the filesystem on it is grown too.
+func LvextendCmd(vg *VolumeGroup, lv *LogicalVolume, size int64, resizefs bool) (string, error) {
+	arg, err := lvSize(vg, size, "+")
+	if err != nil {
+		return "", err
+	}
+	cmd := "lvextend " + arg
+	if resizefs {
+		cmd += " --resizefs"
+	}
+	return cmd + " " + sshclient.Quote(lv.FullName()), nil
+}
+
+// LvremoveCmd removes lv, it refuses a volume that is in use.
+func LvremoveCmd(lv *LogicalVolume) (string, error) {
+	if lv.Open() {
+		return "", fmt.Errorf("%s is in use, unmount it first", lv.FullName())
+	}
+	return "lvremove --yes " + sshclient.Quote(lv.FullName()), nil
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+// pvs, vgs and lvs output for one volume group on two disks and an unused
+// physical volume.
+const (
+	pvsReport = `  {
+      "report": [
+          {
+              "pv": [
+                  {"pv_name":"/dev/sdb", "vg_name":"data", "pv_fmt":"lvm2", "pv_size":"10733223936", "pv_free":"0", "pv_pe_count":"2559", "pv_pe_alloc_count":"2559"},
+                  {"pv_name":"/dev/sdc", "vg_name":"data", "pv_fmt":"lvm2", "pv_size":"10733223936", "pv_free":"6438256640", "pv_pe_count":"2559", "pv_pe_alloc_count":"1024"},
+                  {"pv_name":"/dev/sdd1", "vg_name":"", "pv_fmt":"lvm2", "pv_size":"1073741824", "pv_free":"1073741824", "pv_pe_count":"0", "pv_pe_alloc_count":"0"}
+              ]
+          }
+      ]
+  }
+`
+	vgsReport = `  {
+      "report": [
+          {
+              "vg": [
+                  {"vg_name":"data", "vg_size":"21466447872", "vg_free":"6438256640", "vg_extent_size":"4194304", "vg_extent_count":"5118", "vg_free_count":"1535"}
+              ]
+          }
+      ]
+  }
+`
+	lvsReport = `  {
+      "report": [
+          {
+              "lv": [
+                  {"lv_name":"home", "vg_name":"data", "lv_path":"/dev/data/home", "lv_size":"10737418240", "lv_attr":"-wi-ao----"},
+                  {"lv_name":"scratch", "vg_name":"data", "lv_path":"/dev/data/scratch", "lv_size":"4290772992", "lv_attr":"-wi-a-----"}
+              ]
+          }
+      ]
+  }
+`
+)
+
+func TestParseLvm(t *testing.T) {
+	l, err := ParseLvm([]byte(pvsReport), []byte(vgsReport), []byte(lvsReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Lvm{
+		VGs: []VolumeGroup{{
+			Name: "data", Size: 21466447872, Free: 6438256640,
+			ExtentSize: 4194304, Extents: 5118, FreeExtents: 1535,
+			PVs: []PhysicalVolume{
+				{Name: "/dev/sdb", VG: "data", Format: "lvm2", Size: 10733223936, Free: 0, Extents: 2559, AllocExtents: 2559},
+				{Name: "/dev/sdc", VG: "data", Format: "lvm2", Size: 10733223936, Free: 6438256640, Extents: 2559, AllocExtents: 1024},
+			},
+			LVs: []LogicalVolume{
+				{Name: "home", VG: "data", Path: "/dev/data/home", Size: 10737418240, Attr: "-wi-ao----"},
+				{Name: "scratch", VG: "data", Path: "/dev/data/scratch", Size: 4290772992, Attr: "-wi-a-----"},
+			},
+		}},
+		Orphans: []PhysicalVolume{
+			{Name: "/dev/sdd1", Format: "lvm2", Size: 1073741824, Free: 1073741824},
+		},
+	}
+	if !reflect.DeepEqual(l, want) {
+		t.Errorf("ParseLvm() =\n%+v\nwant\n%+v", l, want)
+	}
+	if home, ok := l.LV("data", "home"); !ok || !home.Open() {
+		t.Errorf("LV(data, home) = %+v, %v, want an open volume", home, ok)
+	}
+	if scratch, ok := l.LV("data", "scratch"); !ok || scratch.Open() {
+		t.Errorf("LV(data, scratch) = %+v, %v, want a closed volume", scratch, ok)
+	}
+
+	empty := []byte(`{"report": [{"pv": [], "vg": [], "lv": []}]}`)
+	if l, err := ParseLvm(empty, empty, empty); err != nil || len(l.VGs) != 0 || len(l.Orphans) != 0 {
+		t.Errorf("ParseLvm() of a host without LVM = %+v, %v", l, err)
+	}
+	badLv := []byte(`{"report": [{"lv": [{"lv_name":"x", "vg_name":"gone", "lv_size":"1"}]}]}`)
+	badSize := []byte(`{"report": [{"vg": [{"vg_name":"data", "vg_size":"1.5g"}]}]}`)
+	for _, bad := range [][3][]byte{
+		{[]byte("not json"), empty, empty},
+		{empty, badSize, empty},
+		{empty, empty, badLv},
+	} {
+		if _, err := ParseLvm(bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("ParseLvm(%q, %q, %q) succeeded", bad[0], bad[1], bad[2])
+		}
+	}
+}
+
+func TestLvCmds(t *testing.T) {
+	l, err := ParseLvm([]byte(pvsReport), []byte(vgsReport), []byte(lvsReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vg, _ := l.VG("data")
+	home, _ := l.LV("data", "home")
+	scratch, _ := l.LV("data", "scratch")
+	full := VolumeGroup{Name: "full", Size: vg.Size}
+
+	tests := []struct {
+		name string
+		cmd  func() (string, error)
+		want string
+	}{
+		{"create", func() (string, error) { return LvcreateCmd(vg, "logs", 1024) },
+			"lvcreate --yes --name logs --size 1024m data"},
+		{"create all free", func() (string, error) { return LvcreateCmd(vg, "logs", 0) },
+			"lvcreate --yes --name logs --extents 100%FREE data"},
+		{"create all of the free space", func() (string, error) { return LvcreateCmd(vg, "a+b_c.d-e", 6140) },
+			"lvcreate --yes --name a+b_c.d-e --size 6140m data"},
+		{"create too big", func() (string, error) { return LvcreateCmd(vg, "logs", 6141) }, ""},
+		{"create negative", func() (string, error) { return LvcreateCmd(vg, "logs", -1) }, ""},
+		{"create existing", func() (string, error) { return LvcreateCmd(vg, "home", 1024) }, ""},
+		{"create no name", func() (string, error) { return LvcreateCmd(vg, "", 1024) }, ""},
+		{"create leading dash", func() (string, error) { return LvcreateCmd(vg, "-x", 1024) }, ""},
+		{"create dot dot", func() (string, error) { return LvcreateCmd(vg, "..", 1024) }, ""},
+		{"create bad name", func() (string, error) { return LvcreateCmd(vg, "a b", 1024) }, ""},
+		{"create no free extents", func() (string, error) { return LvcreateCmd(&full, "logs", 0) }, ""},
+		{"extend", func() (string, error) { return LvextendCmd(vg, home, 512, false) },
+			"lvextend --size +512m data/home"},
+		{"extend resizefs", func() (string, error) { return LvextendCmd(vg, home, 512, true) },
+			"lvextend --size +512m --resizefs data/home"},
+		{"extend all free", func() (string, error) { return LvextendCmd(vg, scratch, 0, true) },
+			"lvextend --extents +100%FREE --resizefs data/scratch"},
+		{"extend too big", func() (string, error) { return LvextendCmd(vg, home, 6141, false) }, ""},
+		{"extend no free extents", func() (string, error) { return LvextendCmd(&full, home, 0, false) }, ""},
+		{"remove", func() (string, error) { return LvremoveCmd(scratch) }, "lvremove --yes data/scratch"},
+		{"remove open", func() (string, error) { return LvremoveCmd(home) }, ""},
+	}
+	for _, tt := range tests {
+		got, err := tt.cmd()
+		if len(tt.want) == 0 {
+			if err == nil {
+				t.Errorf("%s: got %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"tools/sshclient"
+)
+
+// DiskLabels are the partition table types the partition editor creates.
+var DiskLabels = []string{DiskLabelGpt, DiskLabelMsdos}
+
+// MiB is the unit partitions are created and resized in, which keeps them
+// aligned for any sector size.
+const MiB = 1 << 20
+
+// PartTable is a partition table read with parted, Parts lists the
+// partitions and the free space between them in disk order.
+type PartTable struct {
+	Device     string
+	Size       int64
+	SectorSize int
+	// Label is the partition table type, "unknown" when the disk has none.
+	Label string
+	Model string
+	Parts []Partition
+}
+
+// Partition is a partition or, when Free is set, unallocated space. Start
+// and End are byte offsets, End is inclusive.
+type Partition struct {
+	Number int
+	Start  int64
+	End    int64
+	Size   int64
+	FSType string
+	Name   string
+	Flags  string
+	Free   bool
+}
+
+// PartedPrint prints the partition table of dev with the free space in
+// bytes, in parted's machine readable format.
+func PartedPrint(dev string) string {
+	return fmt.Sprintf("parted --script --machine %s unit B print free", sshclient.Quote(dev))
+}
+
+// ParsePartTable parses the output of PartedPrint. A disk without a
+// partition table is reported with the label "unknown" and one free
+// region spanning the disk.
+func ParsePartTable(result []byte) (*PartTable, error) {
+	var t *PartTable
+	for _, line := range strings.Split(string(result), "\n") {
+		line = strings.TrimSuffix(strings.TrimSpace(line), ";")
+		if len(line) == 0 || line == "BYT" {
+			continue
+		}
+		fields := splitMachine(line)
+		if t == nil {
+			// 第一行是磁盘信息：path:size:transport:logical:physical:label:model:flags
+			if len(fields) < 7 {
+				return nil, fmt.Errorf("unexpected parted output %q", line)
+			}
+			size, err := parseBytes(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			sector, _ := strconv.Atoi(fields[3])
+			t = &PartTable{Device: fields[0], Size: size, SectorSize: sector, Label: fields[5], Model: fields[6]}
+			continue
+		}
+		// 分区：number:start:end:size:fs:name:flags，空闲空间：number:start:end:size:free
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("unexpected parted output %q", line)
+		}
+		var p Partition
+		var err error
+		if p.Start, err = parseBytes(fields[1]); err != nil {
+			return nil, err
+		}
+		if p.End, err = parseBytes(fields[2]); err != nil {
+			return nil, err
+		}
+		if p.Size, err = parseBytes(fields[3]); err != nil {
+			return nil, err
+		}
+		if fields[4] == "free" {
+			p.Free = true
+		} else {
+			if p.Number, err = strconv.Atoi(fields[0]); err != nil {
+				return nil, fmt.Errorf("unexpected parted output %q", line)
+			}
+			p.FSType = fields[4]
+			if len(fields) > 5 {
+				p.Name = fields[5]
+			}
+			if len(fields) > 6 {
+				p.Flags = fields[6]
+			}
+		}
+		t.Parts = append(t.Parts, p)
+	}
+	if t == nil {
+		return nil, errors.New("unexpected parted output, missing the disk line")
+	}
+	if t.Label == "unknown" && len(t.Parts) == 0 {
+		t.Parts = []Partition{{Start: 0, End: t.Size - 1, Size: t.Size, Free: true}}
+	}
+	return t, nil
+}
+
+// splitMachine splits a line of parted's machine output, a backslash
+// escapes the separator.
+func splitMachine(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			cur.WriteByte(line[i])
+		case c == ':':
+			fields = append(fields, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	return append(fields, cur.String())
+}
+
+func parseBytes(s string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSuffix(s, "B"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected parted size %q", s)
+	}
+	return n, nil
+}
+
+// Partition returns the partition with the number n.
+func (t *PartTable) Partition(n int) (Partition, bool) {
+	i := slices.IndexFunc(t.Parts, func(p Partition) bool { return !p.Free && p.Number == n })
+	if i < 0 {
+		return Partition{}, false
+	}
+	return t.Parts[i], true
+}
+
+// MklabelCmd creates an empty partition table, destroying all partitions.
+func MklabelCmd(dev, label string) (string, error) {
+	if !slices.Contains(DiskLabels, label) {
+		return "", fmt.Errorf("unsupported disk label %q", label)
+	}
+	return fmt.Sprintf("parted --script %s mklabel %s", sshclient.Quote(dev), label), nil
+}
+
+// MkpartCmd adds a partition from start to end MiB. GPT partitions get
+// name, msdos tables only get primary partitions.
+func MkpartCmd(t *PartTable, name string, start, end int64) (string, error) {
+	// 第一个 MiB 留给分区表
+	if start < 1 {
+		return "", errors.New("partitions start at 1 MiB or later")
+	}
+	if err := t.checkRange(start, end, -1); err != nil {
+		return "", err
+	}
+	var kind string
+	switch t.Label {
+	case DiskLabelGpt:
+		if len(name) == 0 {
+			name = "primary"
+		}
+		// parted 会再次拆分参数，名称中不能有空格和引号
+		if strings.Trim(name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.") != "" {
+			return "", fmt.Errorf("invalid partition name %q, use letters, digits, '-', '_' and '.'", name)
+		}
+		kind = name
+	case DiskLabelMsdos:
+		kind = "primary"
+	default:
+		return "", fmt.Errorf("%s has no partition table, create one first", t.Device)
+	}
+	return fmt.Sprintf("parted --script --align optimal %s unit MiB mkpart %s %d %d",
+		sshclient.Quote(t.Device), kind, start, end), nil
+}
+
+// RmpartCmd deletes partition n.
+func RmpartCmd(t *PartTable, n int) (string, error) {
+	if _, ok := t.Partition(n); !ok {
+		return "", fmt.Errorf("%s has no partition %d", t.Device, n)
+	}
+	return fmt.Sprintf("parted --script %s rm %d", sshclient.Quote(t.Device), n), nil
+}
+
+// ResizepartCmd moves the end of partition n to end MiB. Only the partition
+// changes, its filesystem has to be grown or shrunk separately.
+func ResizepartCmd(t *PartTable, n int, end int64) (string, error) {
+	p, ok := t.Partition(n)
+	if !ok {
+		return "", fmt.Errorf("%s has no partition %d", t.Device, n)
+	}
+	if err := t.checkRange(p.Start/MiB, end, n); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("parted --script %s unit MiB resizepart %d %d", sshclient.Quote(t.Device), n, end), nil
+}
+
+// PartprobeCmd asks the kernel to reread the partition table.
+func PartprobeCmd(dev string) string {
+	return "partprobe " + sshclient.Quote(dev)
+}
+
+// checkRange checks that start to end MiB lies in free space, or for
+// resizing partition skip, in the partition and the free space after it.
+// The free regions come from parted and leave out the areas it reserves,
+// e.g. the backup GPT at the end of the disk.
+func (t *PartTable) checkRange(start, end int64, skip int) error {
+	if start < 0 || end <= start {
+		return fmt.Errorf("invalid range %d-%d MiB, the end must be after the start", start, end)
+	}
+	// last 是可用空间的最后一个字节
+	last := int64(-1)
+	for i, p := range t.Parts {
+		if skip < 0 && p.Free && p.Start <= start*MiB && start*MiB <= p.End {
+			last = p.End
+			break
+		}
+		if skip >= 0 && !p.Free && p.Number == skip {
+			last = p.End
+			if i+1 < len(t.Parts) && t.Parts[i+1].Free {
+				last = t.Parts[i+1].End
+			}
+			break
+		}
+	}
+	switch {
+	case last < 0 && skip < 0:
+		return fmt.Errorf("start %d MiB is not in free space", start)
+	case last < 0:
+		return fmt.Errorf("%s has no partition %d", t.Device, skip)
+	case end*MiB-1 > last:
+		return fmt.Errorf("end %d MiB is beyond the free space, which ends at %d MiB", end, (last+1)/MiB)
+	}
+	return nil
+}
+
+// Busy returns why the partitions of the disk must not be changed: it
+// holds the root filesystem, something on it is mounted, or it is used by
+// LVM, RAID or encryption. It returns "" when the disk is free.
+func (dev *BlockDevice) Busy() string {
+	if dev.IsRootDisk() {
+		return fmt.Sprintf("%s holds the root filesystem", dev.Name)
+	}
+	if mounted, mps := dev.IsMounted(); mounted {
+		return fmt.Sprintf("%s is mounted on %s", dev.Name, strings.Join(mps, ", "))
+	}
+	var holder func(d *BlockDevice) string
+	holder = func(d *BlockDevice) string {
+		for i := range d.Children {
+			child := &d.Children[i]
+			if child.Type != "part" {
+				return child.Name
+			}
+			if h := holder(child); len(h) != 0 {
+				return h
+			}
+		}
+		return ""
+	}
+	if h := holder(dev); len(h) != 0 {
+		return fmt.Sprintf("%s is in use by %s", dev.Name, h)
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+// partedGpt is the output of PartedPrint for a 10 GiB GPT disk with two
+// partitions and free space between and after them.
+const partedGpt = `BYT;
+/dev/sdb:10737418240B:scsi:512:512:gpt:QEMU QEMU HARDDISK:;
+1:17408B:1048575B:1031168B:free;
+1:1048576B:537919487B:536870912B:fat32:EFI\:boot:boot, esp;
+1:537919488B:1074790399B:536870912B:free;
+2:1074790400B:5369757695B:4294967296B:ext4:data:;
+1:5369757696B:10737401343B:5367643648B:free;
+`
+
+func TestParsePartTable(t *testing.T) {
+	table, err := ParsePartTable([]byte(partedGpt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PartTable{
+		Device:     "/dev/sdb",
+		Size:       10737418240,
+		SectorSize: 512,
+		Label:      "gpt",
+		Model:      "QEMU QEMU HARDDISK",
+		Parts: []Partition{
+			{Start: 17408, End: 1048575, Size: 1031168, Free: true},
+			{Number: 1, Start: 1048576, End: 537919487, Size: 536870912, FSType: "fat32", Name: "EFI:boot", Flags: "boot, esp"},
+			{Start: 537919488, End: 1074790399, Size: 536870912, Free: true},
+			{Number: 2, Start: 1074790400, End: 5369757695, Size: 4294967296, FSType: "ext4", Name: "data"},
+			{Start: 5369757696, End: 10737401343, Size: 5367643648, Free: true},
+		},
+	}
+	if !reflect.DeepEqual(table, want) {
+		t.Errorf("ParsePartTable() =\n%+v\nwant\n%+v", table, want)
+	}
+
+	// 没有分区表时整个磁盘是一块空闲空间
+	table, err = ParsePartTable([]byte("BYT;\n/dev/sdc:1073741824B:scsi:512:512:unknown:QEMU QEMU HARDDISK:;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantParts := []Partition{{Start: 0, End: 1073741823, Size: 1073741824, Free: true}}
+	if table.Label != "unknown" || !reflect.DeepEqual(table.Parts, wantParts) {
+		t.Errorf("ParsePartTable() of an empty disk = %+v", table)
+	}
+
+	for _, bad := range []string{"", "BYT;\n", "BYT;\n/dev/sdb:10B:scsi;\n", "BYT;\n/dev/sdb:xB:scsi:512:512:gpt:m:;\n"} {
+		if _, err := ParsePartTable([]byte(bad)); err == nil {
+			t.Errorf("ParsePartTable(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPartedCmds(t *testing.T) {
+	gpt, err := ParsePartTable([]byte(partedGpt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msdos := *gpt
+	msdos.Label = DiskLabelMsdos
+	blank := *gpt
+	blank.Label = "unknown"
+
+	tests := []struct {
+		name string
+		cmd  func() (string, error)
+		want string
+	}{
+		{"print", func() (string, error) { return PartedPrint("/dev/sdb"), nil },
+			"parted --script --machine /dev/sdb unit B print free"},
+		{"print quoted", func() (string, error) { return PartedPrint("/dev/disk by id"), nil },
+			"parted --script --machine '/dev/disk by id' unit B print free"},
+		{"mklabel", func() (string, error) { return MklabelCmd("/dev/sdb", DiskLabelGpt) },
+			"parted --script /dev/sdb mklabel gpt"},
+		{"mklabel unsupported", func() (string, error) { return MklabelCmd("/dev/sdb", DiskLabelSun) }, ""},
+		{"mkpart gpt", func() (string, error) { return MkpartCmd(gpt, "", 5121, 10239) },
+			"parted --script --align optimal /dev/sdb unit MiB mkpart primary 5121 10239"},
+		{"mkpart gpt named", func() (string, error) { return MkpartCmd(gpt, "swap-1", 513, 1025) },
+			"parted --script --align optimal /dev/sdb unit MiB mkpart swap-1 513 1025"},
+		{"mkpart msdos", func() (string, error) { return MkpartCmd(&msdos, "ignored", 513, 1024) },
+			"parted --script --align optimal /dev/sdb unit MiB mkpart primary 513 1024"},
+		{"mkpart bad name", func() (string, error) { return MkpartCmd(gpt, "a b", 513, 1024) }, ""},
+		{"mkpart no table", func() (string, error) { return MkpartCmd(&blank, "", 513, 1024) }, ""},
+		{"mkpart first MiB", func() (string, error) { return MkpartCmd(gpt, "", 0, 1) }, ""},
+		{"mkpart in a partition", func() (string, error) { return MkpartCmd(gpt, "", 2, 100) }, ""},
+		{"mkpart past the free space", func() (string, error) { return MkpartCmd(gpt, "", 513, 1026) }, ""},
+		{"mkpart over the backup GPT", func() (string, error) { return MkpartCmd(gpt, "", 5121, 10240) }, ""},
+		{"mkpart empty range", func() (string, error) { return MkpartCmd(gpt, "", 600, 600) }, ""},
+		{"rm", func() (string, error) { return RmpartCmd(gpt, 2) },
+			"parted --script /dev/sdb rm 2"},
+		{"rm missing", func() (string, error) { return RmpartCmd(gpt, 3) }, ""},
+		{"resize into free space", func() (string, error) { return ResizepartCmd(gpt, 1, 1025) },
+			"parted --script /dev/sdb unit MiB resizepart 1 1025"},
+		{"resize shrink", func() (string, error) { return ResizepartCmd(gpt, 2, 2048) },
+			"parted --script /dev/sdb unit MiB resizepart 2 2048"},
+		{"resize past the free space", func() (string, error) { return ResizepartCmd(gpt, 1, 1026) }, ""},
+		{"resize missing", func() (string, error) { return ResizepartCmd(gpt, 3, 2048) }, ""},
+		{"partprobe", func() (string, error) { return PartprobeCmd("/dev/sdb"), nil }, "partprobe /dev/sdb"},
+	}
+	for _, tt := range tests {
+		got, err := tt.cmd()
+		if len(tt.want) == 0 {
+			if err == nil {
+				t.Errorf("%s: got %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
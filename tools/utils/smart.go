@@ -0,0 +1,284 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Smartctl reads the SMART data of a device, the device path is appended.
+// It needs root and exits non-zero for many harmless reasons, its exit
+// status is a bit mask, so the output is parsed whatever the status.
+const Smartctl = "smartctl --json -a"
+
+// Thresholds above which a disk is reported as a warning.
+const (
+	WarnTemperature    = 60
+	WarnPercentageUsed = 80
+)
+
+// ATA attribute ids that count bad sectors.
+const (
+	AttrReallocatedSectors   = 5
+	AttrPendingSectors       = 197
+	AttrOfflineUncorrectable = 198
+)
+
+type Health int
+
+const (
+	HealthUnknown Health = iota
+	HealthPass
+	HealthWarn
+	HealthFail
+)
+
+func (h Health) String() string {
+	switch h {
+	case HealthPass:
+		return "PASS"
+	case HealthWarn:
+		return "WARN"
+	case HealthFail:
+		return "FAIL"
+	}
+	return "UNKNOWN"
+}
+
+// SmartInfo is the SMART data of a disk reported by smartctl. ATA or NVMe
+// is set depending on the protocol of the disk.
+type SmartInfo struct {
+	Device       string
+	Protocol     string
+	ModelName    string
+	SerialNumber string
+	Firmware     string
+	// Passed is the overall self-assessment, nil when the disk did not
+	// report one.
+	Passed *bool
+	// Temperature is in degrees Celsius, zero when unknown.
+	Temperature  int
+	PowerOnHours int64
+	ATA          *ATAHealth
+	NVMe         *NVMeHealth
+	// ExitStatus is the bit mask smartctl exited with, Messages are its
+	// warnings and errors.
+	ExitStatus int
+	Messages   []string
+}
+
+// ATAHealth holds the attributes of an ATA disk.
+type ATAHealth struct {
+	ReallocatedSectors   int64
+	PendingSectors       int64
+	OfflineUncorrectable int64
+	Attributes           []ATAAttribute
+}
+
+// ATAAttribute is a row of the ATA SMART attribute table.
+type ATAAttribute struct {
+	ID     int
+	Name   string
+	Value  int
+	Worst  int
+	Thresh int
+	Raw    int64
+	// RawString is the raw value as smartctl prints it, e.g. "35 (Min/Max 20/45)".
+	RawString string
+	// WhenFailed is "now" or "past" when the value dropped to the threshold.
+	WhenFailed string
+}
+
+// NVMeHealth is the SMART / health information log of an NVMe disk.
+type NVMeHealth struct {
+	CriticalWarning         int
+	AvailableSpare          int
+	AvailableSpareThreshold int
+	PercentageUsed          int
+	MediaErrors             int64
+	ErrorLogEntries         int64
+	UnsafeShutdowns         int64
+	// DataUnitsRead and DataUnitsWritten count units of 512,000 bytes.
+	DataUnitsRead    int64
+	DataUnitsWritten int64
+}
+
+// smartOutput is the part of smartctl's JSON output that is used.
+type smartOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	Device struct {
+		Name     string `json:"name"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName       string `json:"model_name"`
+	SerialNumber    string `json:"serial_number"`
+	FirmwareVersion string `json:"firmware_version"`
+	SmartStatus     *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATASmartAttributes *struct {
+		Table []struct {
+			ID         int    `json:"id"`
+			Name       string `json:"name"`
+			Value      int    `json:"value"`
+			Worst      int    `json:"worst"`
+			Thresh     int    `json:"thresh"`
+			WhenFailed string `json:"when_failed"`
+			Raw        struct {
+				Value  int64  `json:"value"`
+				String string `json:"string"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeLog *struct {
+		CriticalWarning         int   `json:"critical_warning"`
+		Temperature             int   `json:"temperature"`
+		AvailableSpare          int   `json:"available_spare"`
+		AvailableSpareThreshold int   `json:"available_spare_threshold"`
+		PercentageUsed          int   `json:"percentage_used"`
+		DataUnitsRead           int64 `json:"data_units_read"`
+		DataUnitsWritten        int64 `json:"data_units_written"`
+		PowerOnHours            int64 `json:"power_on_hours"`
+		UnsafeShutdowns         int64 `json:"unsafe_shutdowns"`
+		MediaErrors             int64 `json:"media_errors"`
+		NumErrLogEntries        int64 `json:"num_err_log_entries"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// ParseSmart parses the output of Smartctl.
+func ParseSmart(result []byte) (*SmartInfo, error) {
+	var out smartOutput
+	if err := json.Unmarshal(result, &out); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal smartctl output, error: %v", err)
+	}
+	info := &SmartInfo{
+		Device:       out.Device.Name,
+		Protocol:     out.Device.Protocol,
+		ModelName:    out.ModelName,
+		SerialNumber: out.SerialNumber,
+		Firmware:     out.FirmwareVersion,
+		Temperature:  out.Temperature.Current,
+		PowerOnHours: out.PowerOnTime.Hours,
+		ExitStatus:   out.Smartctl.ExitStatus,
+	}
+	for _, m := range out.Smartctl.Messages {
+		info.Messages = append(info.Messages, m.String)
+	}
+	if out.SmartStatus != nil {
+		passed := out.SmartStatus.Passed
+		info.Passed = &passed
+	}
+	if out.ATASmartAttributes != nil {
+		ata := &ATAHealth{}
+		for _, a := range out.ATASmartAttributes.Table {
+			ata.Attributes = append(ata.Attributes, ATAAttribute{
+				ID:         a.ID,
+				Name:       a.Name,
+				Value:      a.Value,
+				Worst:      a.Worst,
+				Thresh:     a.Thresh,
+				Raw:        a.Raw.Value,
+				RawString:  a.Raw.String,
+				WhenFailed: a.WhenFailed,
+			})
+			switch a.ID {
+			case AttrReallocatedSectors:
+				ata.ReallocatedSectors = a.Raw.Value
+			case AttrPendingSectors:
+				ata.PendingSectors = a.Raw.Value
+			case AttrOfflineUncorrectable:
+				ata.OfflineUncorrectable = a.Raw.Value
+			}
+		}
+		info.ATA = ata
+	}
+	if log := out.NVMeLog; log != nil {
+		info.NVMe = &NVMeHealth{
+			CriticalWarning:         log.CriticalWarning,
+			AvailableSpare:          log.AvailableSpare,
+			AvailableSpareThreshold: log.AvailableSpareThreshold,
+			PercentageUsed:          log.PercentageUsed,
+			MediaErrors:             log.MediaErrors,
+			ErrorLogEntries:         log.NumErrLogEntries,
+			UnsafeShutdowns:         log.UnsafeShutdowns,
+			DataUnitsRead:           log.DataUnitsRead,
+			DataUnitsWritten:        log.DataUnitsWritten,
+		}
+		// 旧版本 smartctl 只在 NVMe 日志中给出温度和通电时间
+		if info.Temperature == 0 {
+			info.Temperature = log.Temperature
+		}
+		if info.PowerOnHours == 0 {
+			info.PowerOnHours = log.PowerOnHours
+		}
+	}
+	return info, nil
+}
+
+// Health rates the disk and returns the reasons for a warning or failure.
+// A disk without a SMART self-assessment, e.g. a virtual disk, is unknown.
+func (s *SmartInfo) Health() (Health, []string) {
+	var fail, warn []string
+	if s.Passed != nil && !*s.Passed {
+		fail = append(fail, "SMART overall-health self-assessment failed")
+	}
+	if ata := s.ATA; ata != nil {
+		for _, a := range ata.Attributes {
+			if a.WhenFailed == "now" {
+				fail = append(fail, fmt.Sprintf("attribute %d %s is failing", a.ID, a.Name))
+			}
+		}
+		if ata.ReallocatedSectors > 0 {
+			warn = append(warn, fmt.Sprintf("%d reallocated sectors", ata.ReallocatedSectors))
+		}
+		if ata.PendingSectors > 0 {
+			warn = append(warn, fmt.Sprintf("%d pending sectors", ata.PendingSectors))
+		}
+		if ata.OfflineUncorrectable > 0 {
+			warn = append(warn, fmt.Sprintf("%d offline uncorrectable sectors", ata.OfflineUncorrectable))
+		}
+	}
+	if nvme := s.NVMe; nvme != nil {
+		if nvme.CriticalWarning != 0 {
+			fail = append(fail, fmt.Sprintf("critical warning 0x%02x", nvme.CriticalWarning))
+		}
+		if nvme.AvailableSpare < nvme.AvailableSpareThreshold {
+			fail = append(fail, fmt.Sprintf("available spare %d%% below threshold %d%%", nvme.AvailableSpare, nvme.AvailableSpareThreshold))
+		}
+		if nvme.MediaErrors > 0 {
+			warn = append(warn, fmt.Sprintf("%d media errors", nvme.MediaErrors))
+		}
+		if nvme.PercentageUsed >= WarnPercentageUsed {
+			warn = append(warn, fmt.Sprintf("%d%% of rated endurance used", nvme.PercentageUsed))
+		}
+	}
+	if s.Temperature >= WarnTemperature {
+		warn = append(warn, fmt.Sprintf("temperature %d°C", s.Temperature))
+	}
+
+	switch {
+	case len(fail) != 0:
+		return HealthFail, append(fail, warn...)
+	case len(warn) != 0:
+		return HealthWarn, warn
+	case s.Passed == nil:
+		reason := "no SMART data"
+		if len(s.Messages) != 0 {
+			reason = strings.Join(s.Messages, "; ")
+		}
+		return HealthUnknown, []string{reason}
+	}
+	return HealthPass, nil
+}
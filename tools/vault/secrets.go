@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"context"
+	"errors"
+)
+
+// maxUnlockAttempts limits how often a connection asks for the master
+// passphrase before giving up on the saved credentials.
+const maxUnlockAttempts = 3
+
+// Prompt asks the user a question, it has the same contract as
+// sshclient.Prompt.
+type Prompt func(ctx context.Context, question string, echo bool) (answer string, ok bool)
+
+// UnlockWith asks for the master passphrase until the vault is unlocked.
+// Concurrent callers wait for the first one, so the user is asked once.
+func (v *Vault) UnlockWith(ctx context.Context, prompt Prompt) error {
+	v.unlockMu.Lock()
+	defer v.unlockMu.Unlock()
+	question := "Enter the vault passphrase to use saved credentials:"
+	for range maxUnlockAttempts {
+		if !v.Locked() {
+			return nil
+		}
+		passphrase, ok := prompt(ctx, question, false)
+		if !ok {
+			return ErrLocked
+		}
+		err := v.Unlock(passphrase)
+		if err == nil || !errors.Is(err, ErrWrongPassphrase) {
+			return err
+		}
+		question = "Wrong passphrase, enter the vault passphrase again:"
+	}
+	return ErrWrongPassphrase
+}
+
+// Secrets serves saved credentials to ssh connections, it implements
+// sshclient.Secrets. A locked vault is unlocked through Prompt.
+type Secrets struct {
+	Vault  *Vault
+	Prompt Prompt
+}
+
+func (s Secrets) Password(ctx context.Context, user, addr string) (string, bool) {
+	return s.lookup(ctx, PasswordKey(user, addr))
+}
+
+func (s Secrets) Passphrase(ctx context.Context, keyFile string) (string, bool) {
+	return s.lookup(ctx, PassphraseKey(keyFile))
+}
+
+func (s Secrets) lookup(ctx context.Context, name string) (string, bool) {
+	if s.Vault == nil || !s.Vault.Exists() {
+		return "", false
+	}
+	if s.Vault.Locked() {
+		if s.Prompt == nil || s.Vault.UnlockWith(ctx, s.Prompt) != nil {
+			return "", false
+		}
+	}
+	c, err := s.Vault.Get(name)
+	if err != nil || len(c.Password) == 0 {
+		return "", false
+	}
+	return c.Password, true
+}
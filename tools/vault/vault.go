@@ -0,0 +1,340 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	DefaultLockTimeout = 5 * time.Minute
+
+	version = 1
+	// scrypt 参数，按 2^15 的推荐值，解锁大约需要几十毫秒
+	scryptN   = 1 << 15
+	scryptR   = 8
+	scryptP   = 1
+	keyLength = chacha20poly1305.KeySize
+	saltSize  = 16
+)
+
+// additionalData binds the ciphertext to the file format version.
+var additionalData = []byte("my-ui-tools vault v1")
+
+var (
+	ErrLocked          = errors.New("credential vault is locked")
+	ErrWrongPassphrase = errors.New("wrong vault passphrase")
+)
+
+// Credential is a stored secret, either a login password or the passphrase
+// of a private key file.
+type Credential struct {
+	Password string `json:"password"`
+}
+
+// file is the on-disk format, only the salt and the kdf parameters are in
+// plain text.
+type file struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Vault keeps credentials in a file encrypted with a key derived from a
+// master passphrase. It locks itself, dropping the key and the decrypted
+// entries, after a period without use.
+type Vault struct {
+	path    string
+	timeout time.Duration
+	onLock  func()
+
+	// unlockMu 保证多个连接同时需要解锁时只询问一次
+	unlockMu sync.Mutex
+
+	mu      sync.Mutex
+	key     []byte
+	salt    []byte
+	entries map[string]Credential
+	timer   *time.Timer
+	// gen 在每次使用时增加，计时器只在期间没有再使用时锁定
+	gen uint64
+}
+
+// DefaultPath returns vault.json in the user config directory.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "my-ui-tools", "vault.json")
+}
+
+// New returns a locked vault stored at path. onLock is called from a timer
+// goroutine when the vault locks itself.
+func New(path string, timeout time.Duration, onLock func()) *Vault {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	return &Vault{path: path, timeout: timeout, onLock: onLock}
+}
+
+// PasswordKey is the entry name of the login password for user at addr.
+func PasswordKey(user, addr string) string {
+	return user + "@" + addr
+}
+
+// PassphraseKey is the entry name of the passphrase of a private key file.
+func PassphraseKey(keyFile string) string {
+	return "key:" + keyFile
+}
+
+// Exists reports whether the vault file has been created.
+func (v *Vault) Exists() bool {
+	_, err := os.Stat(v.path)
+	return err == nil
+}
+
+// Locked reports whether the key has to be derived again before use.
+func (v *Vault) Locked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.key == nil
+}
+
+// Unlock derives the key from passphrase and decrypts the vault. A vault
+// that does not exist yet is created with this passphrase.
+func (v *Vault) Unlock(passphrase string) error {
+	if len(passphrase) == 0 {
+		return errors.New("vault passphrase is required")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	data, err := os.ReadFile(v.path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLength)
+		if err != nil {
+			return err
+		}
+		v.key, v.salt, v.entries = key, salt, make(map[string]Credential)
+		if err := v.save(); err != nil {
+			v.wipe()
+			return err
+		}
+		v.touch()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read credential vault failed, %v", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("unable to unmarshal credential vault, error: %v", err)
+	}
+	if f.Version != version || f.KDF != "scrypt" {
+		return fmt.Errorf("unsupported credential vault format %d/%s", f.Version, f.KDF)
+	}
+	// 只接受 save 写入的参数，被改过的文件可能让 scrypt 占用大量内存或者很久不返回
+	if f.N != scryptN || f.R != scryptR || f.P != scryptP {
+		return fmt.Errorf("unsupported credential vault kdf parameters N=%d r=%d p=%d", f.N, f.R, f.P)
+	}
+	if len(f.Salt) != saltSize || len(f.Nonce) != chacha20poly1305.NonceSizeX {
+		return errors.New("credential vault is damaged, bad salt or nonce size")
+	}
+	key, err := scrypt.Key([]byte(passphrase), f.Salt, f.N, f.R, f.P, keyLength)
+	if err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+	plain, err := aead.Open(nil, f.Nonce, f.Data, additionalData)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	entries := make(map[string]Credential)
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return fmt.Errorf("unable to unmarshal credential vault entries, error: %v", err)
+	}
+	v.key, v.salt, v.entries = key, f.Salt, entries
+	v.touch()
+	return nil
+}
+
+// Lock forgets the key and the decrypted entries.
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.wipe()
+}
+
+func (v *Vault) wipe() {
+	clear(v.key)
+	v.key = nil
+	v.entries = nil
+	if v.timer != nil {
+		v.timer.Stop()
+		v.timer = nil
+	}
+}
+
+// touch restarts the inactivity timer, it must be called with mu held.
+func (v *Vault) touch() {
+	v.gen++
+	gen := v.gen
+	if v.timer != nil {
+		v.timer.Stop()
+	}
+	v.timer = time.AfterFunc(v.timeout, func() { v.expire(gen) })
+}
+
+// expire locks the vault unless it was used again after the timer of gen
+// was started.
+func (v *Vault) expire(gen uint64) {
+	v.mu.Lock()
+	// Stop 不能取消已经触发、正在等待锁的回调，这里再检查一次
+	if gen != v.gen || v.key == nil {
+		v.mu.Unlock()
+		return
+	}
+	v.wipe()
+	v.mu.Unlock()
+	if v.onLock != nil {
+		v.onLock()
+	}
+}
+
+// Get returns the credential saved under name.
+func (v *Vault) Get(name string) (Credential, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return Credential{}, ErrLocked
+	}
+	v.touch()
+	c, ok := v.entries[name]
+	if !ok {
+		return Credential{}, fmt.Errorf("no credential saved for %s", name)
+	}
+	return c, nil
+}
+
+// Names returns the sorted entry names.
+func (v *Vault) Names() ([]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return nil, ErrLocked
+	}
+	v.touch()
+	names := make([]string, 0, len(v.entries))
+	for name := range v.entries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Put stores c under name and saves the vault.
+func (v *Vault) Put(name string, c Credential) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	v.touch()
+	old, existed := v.entries[name]
+	v.entries[name] = c
+	if err := v.save(); err != nil {
+		if existed {
+			v.entries[name] = old
+		} else {
+			delete(v.entries, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes name and saves the vault.
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrLocked
+	}
+	v.touch()
+	old, existed := v.entries[name]
+	if !existed {
+		return nil
+	}
+	delete(v.entries, name)
+	if err := v.save(); err != nil {
+		v.entries[name] = old
+		return err
+	}
+	return nil
+}
+
+// save encrypts the entries with a fresh nonce and replaces the file
+// atomically, it must be called with mu held.
+func (v *Vault) save() error {
+	plain, err := json.Marshal(v.entries)
+	if err != nil {
+		return err
+	}
+	defer clear(plain)
+	aead, err := chacha20poly1305.NewX(v.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(file{
+		Version: version,
+		KDF:     "scrypt",
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    v.salt,
+		Nonce:   nonce,
+		Data:    aead.Seal(nil, nonce, plain, additionalData),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return fmt.Errorf("save credential vault failed, %v", err)
+	}
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("save credential vault failed, %v", err)
+	}
+	if err := os.Rename(tmp, v.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("save credential vault failed, %v", err)
+	}
+	return nil
+}
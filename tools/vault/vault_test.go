@@ -0,0 +1,130 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newVault returns an unlocked vault holding one credential, saved in a
+// temporary directory.
+func newVault(t *testing.T, timeout time.Duration, onLock func()) *Vault {
+	t.Helper()
+	v := New(filepath.Join(t.TempDir(), "vault.json"), timeout, onLock)
+	if err := v.Unlock("master"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Put(PasswordKey("root", "host:22"), Credential{Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestRoundTrip(t *testing.T) {
+	v := newVault(t, time.Minute, nil)
+	v.Lock()
+	if _, err := v.Get(PasswordKey("root", "host:22")); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Get of a locked vault = %v, want ErrLocked", err)
+	}
+
+	// 用新的实例读文件，确认内容确实写到了磁盘上
+	v = New(v.path, time.Minute, nil)
+	if err := v.Unlock("master"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := v.Get(PasswordKey("root", "host:22"))
+	if err != nil || c.Password != "secret" {
+		t.Errorf("Get = %v, %v, want secret", c, err)
+	}
+	if err := v.Delete(PasswordKey("root", "host:22")); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := v.Names(); err != nil || len(names) != 0 {
+		t.Errorf("Names after Delete = %v, %v, want none", names, err)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	v := newVault(t, time.Minute, nil)
+	v.Lock()
+	if err := v.Unlock("wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Unlock with a wrong passphrase = %v, want ErrWrongPassphrase", err)
+	}
+	if !v.Locked() {
+		t.Error("vault unlocked by a wrong passphrase")
+	}
+}
+
+func TestTampered(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(f *file)
+		want   error
+	}{
+		{"data", func(f *file) { f.Data[0] ^= 1 }, ErrWrongPassphrase},
+		{"salt", func(f *file) { f.Salt[0] ^= 1 }, ErrWrongPassphrase},
+		{"nonce size", func(f *file) { f.Nonce = f.Nonce[:12] }, nil},
+		{"huge N", func(f *file) { f.N = 1 << 30 }, nil},
+		{"r", func(f *file) { f.R = 1024 }, nil},
+		{"version", func(f *file) { f.Version = 2 }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVault(t, time.Minute, nil)
+			v.Lock()
+			data, err := os.ReadFile(v.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f file
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(&f)
+			if data, err = json.Marshal(f); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(v.path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			err = v.Unlock("master")
+			if err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Unlock of a tampered file = %v, want %v", err, tt.want)
+			}
+			if !v.Locked() {
+				t.Error("vault unlocked from a tampered file")
+			}
+		})
+	}
+}
+
+func TestAutoLock(t *testing.T) {
+	locked := make(chan struct{}, 1)
+	v := newVault(t, 50*time.Millisecond, func() { locked <- struct{}{} })
+
+	// 一直在使用时不应该锁定
+	for range 5 {
+		time.Sleep(20 * time.Millisecond)
+		if _, err := v.Get(PasswordKey("root", "host:22")); err != nil {
+			t.Fatalf("Get while in use = %v", err)
+		}
+	}
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("vault did not lock itself")
+	}
+	if !v.Locked() {
+		t.Error("onLock called but the vault is unlocked")
+	}
+	select {
+	case <-locked:
+		t.Error("onLock called more than once")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,127 @@
+package vt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Span is a run of text sharing one style, the Rune of Style is unused.
+type Span struct {
+	Text  string
+	Style Cell
+}
+
+// Styler splits the output of a program that is not a terminal into styled
+// spans. SGR sequences change the style of the text after them, other
+// escape sequences and control characters except newline and tab are
+// dropped. The style carries over between calls so streamed output can be
+// parsed line by line.
+type Styler struct {
+	pen Cell
+}
+
+// Spans parses text and returns its spans.
+func (s *Styler) Spans(text string) []Span {
+	spans := make([]Span, 0)
+	var run strings.Builder
+	flush := func() {
+		if run.Len() != 0 {
+			spans = append(spans, Span{Text: run.String(), Style: s.pen})
+			run.Reset()
+		}
+	}
+	for i := 0; i < len(text); {
+		c := text[i]
+		if c != 0x1b {
+			if c >= 0x20 && c != 0x7f || c == '\n' || c == '\t' {
+				run.WriteByte(c)
+			}
+			i++
+			continue
+		}
+		n, params, sgr := escape(text[i:])
+		if sgr {
+			flush()
+			s.pen.applySGR(params)
+		}
+		i += n
+	}
+	flush()
+	return spans
+}
+
+// StripANSI removes escape sequences and control characters from text,
+// keeping newlines and tabs.
+func StripANSI(text string) string {
+	if !strings.ContainsFunc(text, func(r rune) bool {
+		return r < 0x20 && r != '\n' && r != '\t' || r == 0x7f
+	}) {
+		return text
+	}
+	var b strings.Builder
+	var s Styler
+	for _, span := range s.Spans(text) {
+		b.WriteString(span.Text)
+	}
+	return b.String()
+}
+
+// escape measures the escape sequence at the start of text and returns the
+// parameters when it is SGR. An unterminated sequence runs to the end.
+func escape(text string) (n int, params []int, sgr bool) {
+	if len(text) < 2 {
+		return len(text), nil, false
+	}
+	switch text[1] {
+	case '[':
+		// CSI：参数字节 0x30-0x3f，中间字节 0x20-0x2f，结束字节 0x40-0x7e
+		i := 2
+		for i < len(text) && text[i] >= 0x30 && text[i] <= 0x3f {
+			i++
+		}
+		paramEnd := i
+		for i < len(text) && text[i] >= 0x20 && text[i] <= 0x2f {
+			i++
+		}
+		if i >= len(text) {
+			return len(text), nil, false
+		}
+		if text[i] < 0x40 || text[i] > 0x7e {
+			// 非法序列，只跳过 ESC [
+			return 2, nil, false
+		}
+		raw := text[2:paramEnd]
+		if text[i] != 'm' || paramEnd != i || strings.ContainsAny(raw, "<=>?") {
+			return i + 1, nil, false
+		}
+		return i + 1, sgrParams(raw), true
+	case ']', 'P', 'X', '^', '_':
+		// OSC 以 BEL 或 ST 结束，其它字符串以 ST 结束
+		for i := 2; i < len(text); i++ {
+			if text[i] == 0x07 && text[1] == ']' {
+				return i + 1, nil, false
+			}
+			if text[i] == 0x1b && i+1 < len(text) && text[i+1] == '\\' {
+				return i + 2, nil, false
+			}
+		}
+		return len(text), nil, false
+	case '(', ')', '*', '+', '#', '%', ' ':
+		return min(3, len(text)), nil, false
+	}
+	return 2, nil, false
+}
+
+// sgrParams parses "1;38;5;208", empty parameters are 0 and colon
+// separated sub parameters are treated like semicolons.
+func sgrParams(raw string) []int {
+	params := make([]int, 0)
+	if len(raw) == 0 {
+		return params
+	}
+	for _, f := range strings.Split(strings.ReplaceAll(raw, ":", ";"), ";") {
+		v, _ := strconv.Atoi(f)
+		params = append(params, v)
+	}
+	return params
+}
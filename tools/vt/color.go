@@ -0,0 +1,103 @@
+package vt
+
+import "image/color"
+
+// Color is a cell colour: the default colour, one of the 256 xterm palette
+// entries or a 24 bit RGB value.
+type Color uint32
+
+const (
+	DefaultColor Color = 0
+
+	paletteFlag Color = 1 << 24
+	rgbFlag     Color = 1 << 25
+)
+
+// IndexColor returns palette entry i, 0-7 are the ANSI colours and 8-15
+// their bright variants.
+func IndexColor(i uint8) Color {
+	return paletteFlag | Color(i)
+}
+
+func RGBColor(r, g, b uint8) Color {
+	return rgbFlag | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// NRGBA resolves c, def is used for the default colour.
+func (c Color) NRGBA(def color.NRGBA) color.NRGBA {
+	switch {
+	case c&rgbFlag != 0:
+		return color.NRGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 255}
+	case c&paletteFlag != 0:
+		return paletteColor(uint8(c))
+	}
+	return def
+}
+
+// bright returns the bright variant of an ANSI colour, used for bold text.
+func (c Color) bright() Color {
+	if c&paletteFlag != 0 && uint8(c) < 8 {
+		return c + 8
+	}
+	return c
+}
+
+// ansi 是 xterm 默认的 16 色
+var ansi = [16]color.NRGBA{
+	{R: 0, G: 0, B: 0, A: 255},
+	{R: 205, G: 49, B: 49, A: 255},
+	{R: 13, G: 188, B: 121, A: 255},
+	{R: 229, G: 229, B: 16, A: 255},
+	{R: 36, G: 114, B: 200, A: 255},
+	{R: 188, G: 63, B: 188, A: 255},
+	{R: 17, G: 168, B: 205, A: 255},
+	{R: 229, G: 229, B: 229, A: 255},
+	{R: 102, G: 102, B: 102, A: 255},
+	{R: 241, G: 76, B: 76, A: 255},
+	{R: 35, G: 209, B: 139, A: 255},
+	{R: 245, G: 245, B: 67, A: 255},
+	{R: 59, G: 142, B: 234, A: 255},
+	{R: 214, G: 112, B: 214, A: 255},
+	{R: 41, G: 184, B: 219, A: 255},
+	{R: 255, G: 255, B: 255, A: 255},
+}
+
+func paletteColor(i uint8) color.NRGBA {
+	switch {
+	case i < 16:
+		return ansi[i]
+	case i < 232:
+		// 6x6x6 颜色立方体
+		i -= 16
+		level := func(v uint8) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return 55 + v*40
+		}
+		return color.NRGBA{R: level(i / 36), G: level(i / 6 % 6), B: level(i % 6), A: 255}
+	}
+	// 24 级灰度
+	v := 8 + (i-232)*10
+	return color.NRGBA{R: v, G: v, B: v, A: 255}
+}
+
+// Colors resolves the foreground and background of c with the attributes
+// applied, defFG and defBG are the default colours.
+func (c Cell) Colors(defFG, defBG color.NRGBA) (fg, bg color.NRGBA) {
+	fgColor := c.FG
+	if c.Attr&Bold != 0 {
+		fgColor = fgColor.bright()
+	}
+	fg, bg = fgColor.NRGBA(defFG), c.BG.NRGBA(defBG)
+	if c.Attr&Reverse != 0 {
+		fg, bg = bg, fg
+	}
+	if c.Attr&Faint != 0 {
+		fg.A = 160
+	}
+	if c.Attr&Hidden != 0 {
+		fg = bg
+	}
+	return fg, bg
+}
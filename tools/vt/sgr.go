@@ -0,0 +1,81 @@
+package vt
+
+// applySGR sets the colours and attributes selected by the parameters of
+// an SGR sequence, no parameters reset them.
+func (pen *Cell) applySGR(params []int) {
+	if len(params) == 0 {
+		params = []int{0}
+	}
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			pen.FG, pen.BG, pen.Attr = DefaultColor, DefaultColor, 0
+		case p == 1:
+			pen.Attr |= Bold
+		case p == 2:
+			pen.Attr |= Faint
+		case p == 3:
+			pen.Attr |= Italic
+		case p == 4:
+			pen.Attr |= Underline
+		case p == 7:
+			pen.Attr |= Reverse
+		case p == 8:
+			pen.Attr |= Hidden
+		case p == 9:
+			pen.Attr |= Strike
+		case p == 21 || p == 22:
+			pen.Attr &^= Bold | Faint
+		case p == 23:
+			pen.Attr &^= Italic
+		case p == 24:
+			pen.Attr &^= Underline
+		case p == 27:
+			pen.Attr &^= Reverse
+		case p == 28:
+			pen.Attr &^= Hidden
+		case p == 29:
+			pen.Attr &^= Strike
+		case p >= 30 && p <= 37:
+			pen.FG = IndexColor(uint8(p - 30))
+		case p == 38 || p == 48:
+			c, used := extendedColor(params, i+1)
+			i += used
+			if p == 38 {
+				pen.FG = c
+			} else {
+				pen.BG = c
+			}
+		case p == 39:
+			pen.FG = DefaultColor
+		case p >= 40 && p <= 47:
+			pen.BG = IndexColor(uint8(p - 40))
+		case p == 49:
+			pen.BG = DefaultColor
+		case p >= 90 && p <= 97:
+			pen.FG = IndexColor(uint8(p - 90 + 8))
+		case p >= 100 && p <= 107:
+			pen.BG = IndexColor(uint8(p - 100 + 8))
+		}
+	}
+}
+
+// extendedColor parses "5;n" or "2;r;g;b" starting at parameter i and
+// returns how many parameters it used.
+func extendedColor(params []int, i int) (Color, int) {
+	if i >= len(params) {
+		return DefaultColor, 0
+	}
+	switch params[i] {
+	case 5:
+		if i+1 < len(params) {
+			return IndexColor(uint8(params[i+1])), 2
+		}
+	case 2:
+		if i+3 < len(params) {
+			return RGBColor(uint8(params[i+1]), uint8(params[i+2]), uint8(params[i+3])), 4
+		}
+	}
+	return DefaultColor, len(params) - i
+}
@@ -0,0 +1,708 @@
+package vt
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+// Attr is a set of text attributes selected with SGR.
+type Attr uint8
+
+const (
+	Bold Attr = 1 << iota
+	Faint
+	Italic
+	Underline
+	Reverse
+	Hidden
+	Strike
+)
+
+// Cell is one character cell. The second cell of a wide character has
+// Rune 0.
+type Cell struct {
+	Rune rune
+	FG   Color
+	BG   Color
+	Attr Attr
+}
+
+// Screen is a copy of the visible cells for drawing.
+type Screen struct {
+	Cells         [][]Cell
+	CursorX       int
+	CursorY       int
+	CursorVisible bool
+	Title         string
+}
+
+type cursor struct {
+	x, y int
+	pen  Cell
+	// wrapNext 表示最后一列已写满，下一个字符写到下一行
+	wrapNext bool
+	charsets [2]byte
+	charset  int
+}
+
+// parser states
+const (
+	stateGround = iota
+	stateEscape
+	stateCharset
+	stateCSI
+	stateOSC
+	stateString
+	stateStringEscape
+	stateIgnore
+)
+
+// Terminal emulates the subset of xterm used by common shells and full
+// screen programs: cursor movement, erasing, scroll regions, the
+// alternate screen and 256/RGB colours. It is safe for concurrent use.
+type Terminal struct {
+	mu    sync.Mutex
+	cols  int
+	rows  int
+	lines [][]Cell
+	// main 在使用备用屏幕时保存主屏幕
+	main    [][]Cell
+	cur     cursor
+	saved   cursor
+	top     int
+	bottom  int
+	title   string
+	visible bool
+	wrap    bool
+	// appCursor 是 DECCKM，方向键发送 ESC O 序列
+	appCursor bool
+	// replies 是发送给远端的应答，例如光标位置报告
+	replies []byte
+	reply   func([]byte)
+
+	state   int
+	params  []int
+	private byte
+	inter   byte
+	osc     []byte
+	// isOSC 区分 OSC 和其它以 ST 结束的字符串
+	isOSC    bool
+	utf8     []byte
+	charsetG int
+}
+
+// New returns a terminal of cols x rows cells.
+func New(cols, rows int) *Terminal {
+	t := &Terminal{}
+	t.cols, t.rows = max(cols, 1), max(rows, 1)
+	t.reset()
+	return t
+}
+
+// SetReply sets the function receiving answers to status queries, they
+// must be written to the remote program.
+func (t *Terminal) SetReply(reply func([]byte)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reply = reply
+}
+
+func (t *Terminal) reset() {
+	t.lines = t.blankLines(t.rows)
+	t.main = nil
+	t.cur = cursor{charsets: [2]byte{'B', 'B'}}
+	t.saved = t.cur
+	t.top, t.bottom = 0, t.rows-1
+	t.visible = true
+	t.wrap = true
+	t.appCursor = false
+	t.state = stateGround
+}
+
+func (t *Terminal) blankLines(n int) [][]Cell {
+	lines := make([][]Cell, n)
+	for i := range lines {
+		lines[i] = t.blankLine()
+	}
+	return lines
+}
+
+// blankLine uses the current background colour, as xterm does when
+// erasing.
+func (t *Terminal) blankLine() []Cell {
+	line := make([]Cell, t.cols)
+	for i := range line {
+		line[i] = t.blank()
+	}
+	return line
+}
+
+func (t *Terminal) blank() Cell {
+	return Cell{Rune: ' ', BG: t.cur.pen.BG}
+}
+
+// Size returns the number of columns and rows.
+func (t *Terminal) Size() (cols, rows int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cols, t.rows
+}
+
+// AppCursorKeys reports whether arrow keys should be sent in application
+// mode.
+func (t *Terminal) AppCursorKeys() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.appCursor
+}
+
+// Resize changes the screen size, keeping the lines around the cursor.
+func (t *Terminal) Resize(cols, rows int) {
+	cols, rows = max(cols, 1), max(rows, 1)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cols == t.cols && rows == t.rows {
+		return
+	}
+	// 行数减少时丢弃顶部的行，保证光标仍然可见
+	shift := max(0, t.cur.y-(rows-1))
+	resize := func(old [][]Cell) [][]Cell {
+		if old == nil {
+			return nil
+		}
+		lines := make([][]Cell, rows)
+		for y := range lines {
+			lines[y] = make([]Cell, cols)
+			for x := range lines[y] {
+				lines[y][x] = Cell{Rune: ' '}
+				if y+shift < len(old) && x < len(old[y+shift]) {
+					lines[y][x] = old[y+shift][x]
+				}
+			}
+		}
+		return lines
+	}
+	t.lines = resize(t.lines)
+	t.main = resize(t.main)
+	t.cols, t.rows = cols, rows
+	t.top, t.bottom = 0, rows-1
+	t.cur.y -= shift
+	t.cur.x = min(t.cur.x, cols-1)
+	t.cur.wrapNext = false
+	t.saved.x = min(t.saved.x, cols-1)
+	t.saved.y = min(t.saved.y, rows-1)
+}
+
+// Title returns the window title set by the remote program.
+func (t *Terminal) Title() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.title
+}
+
+// Snapshot copies the screen for drawing.
+func (t *Terminal) Snapshot() Screen {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cells := make([][]Cell, len(t.lines))
+	for i, line := range t.lines {
+		cells[i] = append([]Cell(nil), line...)
+	}
+	return Screen{
+		Cells:         cells,
+		CursorX:       t.cur.x,
+		CursorY:       t.cur.y,
+		CursorVisible: t.visible,
+		Title:         t.title,
+	}
+}
+
+// Write feeds output of the remote program to the terminal.
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	for _, b := range p {
+		t.feed(b)
+	}
+	replies, reply := t.replies, t.reply
+	t.replies = nil
+	t.mu.Unlock()
+	if len(replies) != 0 && reply != nil {
+		reply(replies)
+	}
+	return len(p), nil
+}
+
+func (t *Terminal) feed(b byte) {
+	// C0 控制字符在任何状态下都立即执行（字符串中除外）
+	if b < 0x20 && t.state != stateOSC && t.state != stateString && t.state != stateStringEscape {
+		if b == 0x1b {
+			t.state = stateEscape
+			t.inter = 0
+			return
+		}
+		if b == 0x18 || b == 0x1a {
+			t.state = stateGround
+			return
+		}
+		t.control(b)
+		return
+	}
+
+	switch t.state {
+	case stateGround:
+		t.ground(b)
+	case stateEscape:
+		t.escape(b)
+	case stateCharset:
+		t.cur.charsets[t.charsetG] = b
+		t.state = stateGround
+	case stateIgnore:
+		t.state = stateGround
+	case stateCSI:
+		t.csiByte(b)
+	case stateOSC:
+		switch b {
+		case 0x07:
+			t.oscEnd()
+		case 0x1b:
+			t.state = stateStringEscape
+		default:
+			if len(t.osc) < 4096 {
+				t.osc = append(t.osc, b)
+			}
+		}
+	case stateString:
+		if b == 0x1b {
+			t.state = stateStringEscape
+		}
+	case stateStringEscape:
+		// ESC \ 结束字符串
+		t.state = stateGround
+		if t.isOSC {
+			t.oscEnd()
+		}
+		if b != '\\' {
+			t.feed(b)
+		}
+	}
+}
+
+func (t *Terminal) ground(b byte) {
+	if b == 0x7f {
+		return
+	}
+	if b < 0x80 {
+		// 不完整的 UTF-8 序列显示为替换字符
+		if len(t.utf8) != 0 {
+			t.utf8 = t.utf8[:0]
+			t.print(utf8.RuneError)
+		}
+		t.print(rune(b))
+		return
+	}
+	t.utf8 = append(t.utf8, b)
+	if !utf8.FullRune(t.utf8) {
+		return
+	}
+	r, _ := utf8.DecodeRune(t.utf8)
+	t.utf8 = t.utf8[:0]
+	t.print(r)
+}
+
+func (t *Terminal) control(b byte) {
+	switch b {
+	case '\b':
+		if t.cur.x > 0 {
+			t.cur.x--
+		}
+		t.cur.wrapNext = false
+	case '\t':
+		t.cur.x = min(t.cols-1, (t.cur.x/8+1)*8)
+		t.cur.wrapNext = false
+	case '\n', '\v', '\f':
+		t.lineFeed()
+	case '\r':
+		t.cur.x = 0
+		t.cur.wrapNext = false
+	case 0x0e:
+		t.cur.charset = 1
+	case 0x0f:
+		t.cur.charset = 0
+	}
+}
+
+func (t *Terminal) escape(b byte) {
+	t.state = stateGround
+	switch b {
+	case '[':
+		t.state = stateCSI
+		t.params = t.params[:0]
+		t.private = 0
+		t.inter = 0
+	case ']':
+		t.state = stateOSC
+		t.osc = t.osc[:0]
+		t.isOSC = true
+	case 'P', 'X', '^', '_':
+		t.state = stateString
+		t.isOSC = false
+	case '#', '%', ' ', '*', '+':
+		t.state = stateIgnore
+	case '(', ')':
+		t.state = stateCharset
+		t.charsetG = 0
+		if b == ')' {
+			t.charsetG = 1
+		}
+	case '7':
+		t.saved = t.cur
+	case '8':
+		t.cur = t.saved
+	case 'D':
+		t.lineFeed()
+	case 'E':
+		t.cur.x = 0
+		t.lineFeed()
+	case 'M':
+		t.reverseIndex()
+	case 'c':
+		t.reset()
+	}
+}
+
+func (t *Terminal) oscEnd() {
+	t.state = stateGround
+	// OSC 0 和 2 设置窗口标题
+	s := string(t.osc)
+	if len(s) > 2 && (s[:2] == "0;" || s[:2] == "2;") {
+		t.title = s[2:]
+	}
+	t.osc = t.osc[:0]
+}
+
+func (t *Terminal) csiByte(b byte) {
+	switch {
+	case b >= '0' && b <= '9':
+		if len(t.params) == 0 {
+			t.params = append(t.params, 0)
+		}
+		n := &t.params[len(t.params)-1]
+		if *n < 10000 {
+			*n = *n*10 + int(b-'0')
+		}
+	case b == ';' || b == ':':
+		if len(t.params) == 0 {
+			t.params = append(t.params, 0)
+		}
+		t.params = append(t.params, 0)
+	case b >= '<' && b <= '?':
+		t.private = b
+	case b >= 0x20 && b <= 0x2f:
+		t.inter = b
+	case b >= 0x40 && b <= 0x7e:
+		t.state = stateGround
+		t.csi(b)
+	default:
+		t.state = stateGround
+	}
+}
+
+// param returns parameter i, or def when it is missing or zero.
+func (t *Terminal) param(i, def int) int {
+	if i >= len(t.params) || t.params[i] == 0 {
+		return def
+	}
+	return t.params[i]
+}
+
+func (t *Terminal) csi(final byte) {
+	if t.inter != 0 {
+		return
+	}
+	if t.private == '?' {
+		if final == 'h' || final == 'l' {
+			for _, mode := range t.params {
+				t.setPrivateMode(mode, final == 'h')
+			}
+		}
+		return
+	}
+	if t.private == '>' {
+		if final == 'c' {
+			t.replies = append(t.replies, "\x1b[>0;0;0c"...)
+		}
+		return
+	}
+	if t.private != 0 {
+		return
+	}
+
+	n := t.param(0, 1)
+	switch final {
+	case 'A':
+		t.moveTo(t.cur.x, max(t.cur.y-n, t.scrollTop()))
+	case 'B', 'e':
+		t.moveTo(t.cur.x, min(t.cur.y+n, t.scrollBottom()))
+	case 'C', 'a':
+		t.moveTo(t.cur.x+n, t.cur.y)
+	case 'D':
+		t.moveTo(t.cur.x-n, t.cur.y)
+	case 'E':
+		t.moveTo(0, min(t.cur.y+n, t.scrollBottom()))
+	case 'F':
+		t.moveTo(0, max(t.cur.y-n, t.scrollTop()))
+	case 'G', '`':
+		t.moveTo(n-1, t.cur.y)
+	case 'd':
+		t.moveTo(t.cur.x, n-1)
+	case 'H', 'f':
+		t.moveTo(t.param(1, 1)-1, t.param(0, 1)-1)
+	case 'J':
+		t.eraseDisplay(t.param(0, 0))
+	case 'K':
+		t.eraseLine(t.param(0, 0))
+	case 'L':
+		t.insertLines(n)
+	case 'M':
+		t.deleteLines(n)
+	case '@':
+		t.insertChars(n)
+	case 'P':
+		t.deleteChars(n)
+	case 'X':
+		line := t.lines[t.cur.y]
+		for x := t.cur.x; x < min(t.cur.x+n, t.cols); x++ {
+			line[x] = t.blank()
+		}
+	case 'S':
+		t.scrollUp(t.top, t.bottom, n)
+	case 'T':
+		t.scrollDown(t.top, t.bottom, n)
+	case 'm':
+		t.sgr()
+	case 'r':
+		top, bottom := t.param(0, 1)-1, t.param(1, t.rows)-1
+		if top < bottom && bottom < t.rows {
+			t.top, t.bottom = top, bottom
+			t.moveTo(0, 0)
+		}
+	case 's':
+		t.saved = t.cur
+	case 'u':
+		t.cur = t.saved
+	case 'n':
+		switch t.param(0, 0) {
+		case 5:
+			t.replies = append(t.replies, "\x1b[0n"...)
+		case 6:
+			t.replies = fmt.Appendf(t.replies, "\x1b[%d;%dR", t.cur.y+1, t.cur.x+1)
+		}
+	case 'c':
+		t.replies = append(t.replies, "\x1b[?1;2c"...)
+	}
+}
+
+func (t *Terminal) setPrivateMode(mode int, on bool) {
+	switch mode {
+	case 1:
+		t.appCursor = on
+	case 7:
+		t.wrap = on
+	case 25:
+		t.visible = on
+	case 1048:
+		if on {
+			t.saved = t.cur
+		} else {
+			t.cur = t.saved
+		}
+	case 47, 1047, 1049:
+		if on == (t.main != nil) {
+			return
+		}
+		if mode == 1049 && on {
+			t.saved = t.cur
+		}
+		if on {
+			t.main = t.lines
+			t.lines = t.blankLines(t.rows)
+		} else {
+			t.lines = t.main
+			t.main = nil
+		}
+		if mode == 1049 && !on {
+			t.cur = t.saved
+		}
+	}
+}
+
+func (t *Terminal) sgr() {
+	t.cur.pen.applySGR(t.params)
+}
+
+func (t *Terminal) print(r rune) {
+	if t.cur.charsets[t.cur.charset] == '0' {
+		if g, ok := decGraphics[r]; ok {
+			r = g
+		}
+	}
+	w := runeWidth(r)
+	if w == 0 {
+		return
+	}
+	if t.cur.wrapNext && t.wrap {
+		t.cur.x = 0
+		t.lineFeed()
+	}
+	t.cur.wrapNext = false
+	if w == 2 && t.cur.x == t.cols-1 {
+		if !t.wrap {
+			return
+		}
+		t.lines[t.cur.y][t.cur.x] = t.blank()
+		t.cur.x = 0
+		t.lineFeed()
+	}
+	line := t.lines[t.cur.y]
+	cell := t.cur.pen
+	cell.Rune = r
+	line[t.cur.x] = cell
+	if w == 2 && t.cur.x+1 < t.cols {
+		cell.Rune = 0
+		line[t.cur.x+1] = cell
+	}
+	t.cur.x += w
+	if t.cur.x >= t.cols {
+		t.cur.x = t.cols - 1
+		t.cur.wrapNext = true
+	}
+}
+
+func (t *Terminal) moveTo(x, y int) {
+	t.cur.x = min(max(x, 0), t.cols-1)
+	t.cur.y = min(max(y, 0), t.rows-1)
+	t.cur.wrapNext = false
+}
+
+// scrollTop and scrollBottom limit relative movement to the scroll region
+// when the cursor is inside it.
+func (t *Terminal) scrollTop() int {
+	if t.cur.y >= t.top {
+		return t.top
+	}
+	return 0
+}
+
+func (t *Terminal) scrollBottom() int {
+	if t.cur.y <= t.bottom {
+		return t.bottom
+	}
+	return t.rows - 1
+}
+
+func (t *Terminal) lineFeed() {
+	t.cur.wrapNext = false
+	switch {
+	case t.cur.y == t.bottom:
+		t.scrollUp(t.top, t.bottom, 1)
+	case t.cur.y < t.rows-1:
+		t.cur.y++
+	}
+}
+
+func (t *Terminal) reverseIndex() {
+	t.cur.wrapNext = false
+	switch {
+	case t.cur.y == t.top:
+		t.scrollDown(t.top, t.bottom, 1)
+	case t.cur.y > 0:
+		t.cur.y--
+	}
+}
+
+// scrollUp moves lines top..bottom up by n, blank lines appear at the
+// bottom.
+func (t *Terminal) scrollUp(top, bottom, n int) {
+	n = min(n, bottom-top+1)
+	copy(t.lines[top:bottom+1], t.lines[top+n:bottom+1])
+	for y := bottom - n + 1; y <= bottom; y++ {
+		t.lines[y] = t.blankLine()
+	}
+}
+
+func (t *Terminal) scrollDown(top, bottom, n int) {
+	n = min(n, bottom-top+1)
+	copy(t.lines[top+n:bottom+1], t.lines[top:bottom+1-n])
+	for y := top; y < top+n; y++ {
+		t.lines[y] = t.blankLine()
+	}
+}
+
+func (t *Terminal) insertLines(n int) {
+	if t.cur.y < t.top || t.cur.y > t.bottom {
+		return
+	}
+	t.scrollDown(t.cur.y, t.bottom, n)
+	t.cur.x = 0
+}
+
+func (t *Terminal) deleteLines(n int) {
+	if t.cur.y < t.top || t.cur.y > t.bottom {
+		return
+	}
+	t.scrollUp(t.cur.y, t.bottom, n)
+	t.cur.x = 0
+}
+
+func (t *Terminal) insertChars(n int) {
+	line := t.lines[t.cur.y]
+	n = min(n, t.cols-t.cur.x)
+	copy(line[t.cur.x+n:], line[t.cur.x:])
+	for x := t.cur.x; x < t.cur.x+n; x++ {
+		line[x] = t.blank()
+	}
+}
+
+func (t *Terminal) deleteChars(n int) {
+	line := t.lines[t.cur.y]
+	n = min(n, t.cols-t.cur.x)
+	copy(line[t.cur.x:], line[t.cur.x+n:])
+	for x := t.cols - n; x < t.cols; x++ {
+		line[x] = t.blank()
+	}
+}
+
+func (t *Terminal) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		t.eraseLine(0)
+		for y := t.cur.y + 1; y < t.rows; y++ {
+			t.lines[y] = t.blankLine()
+		}
+	case 1:
+		t.eraseLine(1)
+		for y := 0; y < t.cur.y; y++ {
+			t.lines[y] = t.blankLine()
+		}
+	case 2, 3:
+		for y := range t.lines {
+			t.lines[y] = t.blankLine()
+		}
+	}
+}
+
+func (t *Terminal) eraseLine(mode int) {
+	line := t.lines[t.cur.y]
+	from, to := t.cur.x, t.cols
+	switch mode {
+	case 1:
+		from, to = 0, t.cur.x+1
+	case 2:
+		from = 0
+	}
+	for x := from; x < to; x++ {
+		line[x] = t.blank()
+	}
+	t.cur.wrapNext = false
+}